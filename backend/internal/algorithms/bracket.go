@@ -0,0 +1,250 @@
+package algorithms
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// Bracket stages
+const (
+	StagePool    = "pool"
+	StageWinners = "winners"
+	StageLosers  = "losers"
+	StageFinal   = "final"
+	// StageFinalReset is the second grand final, played in double elimination
+	// when the losers' bracket champion wins the first one.
+	StageFinalReset = "final_reset"
+)
+
+// BracketGame is one game in a generated elimination bracket. First-round
+// games have seeds; later games are fed by the winner or loser of earlier
+// games, referenced by Key.
+type BracketGame struct {
+	Key      string
+	Stage    string
+	Round    int // 1-based within the stage
+	Position int // 0-based within the round
+	HomeSeed int // 0 when the slot is fed by another game
+	AwaySeed int // 0 when the slot is fed by another game; > team count means a bye
+
+	WinnerTo   string // Key of the game the winner advances to, "" for the final
+	WinnerSlot string // "home" or "away"
+	LoserTo    string // Key of the game the loser drops to (double elimination only)
+	LoserSlot  string
+}
+
+// BracketSize returns the smallest power of two that fits n teams.
+func BracketSize(n int) int {
+	size := 1
+	for size < n {
+		size *= 2
+	}
+	return size
+}
+
+// SeedOrder returns the first-round seed order for a bracket of the given size
+// so that the top seeds can only meet in the late rounds, e.g. for 8:
+// 1, 8, 4, 5, 2, 7, 3, 6 (games 1v8, 4v5, 2v7, 3v6).
+func SeedOrder(size int) []int {
+	order := []int{1}
+	for len(order) < size {
+		next := make([]int, 0, len(order)*2)
+		total := len(order)*2 + 1
+		for _, seed := range order {
+			next = append(next, seed, total-seed)
+		}
+		order = next
+	}
+	return order
+}
+
+func bracketKey(stage string, round, position int) string {
+	return fmt.Sprintf("%s-%d-%d", stage, round, position)
+}
+
+func slotFor(position int) string {
+	if position%2 == 0 {
+		return "home"
+	}
+	return "away"
+}
+
+// winnersBracket builds the main elimination tree for numTeams teams. Seeds
+// larger than numTeams are byes.
+func winnersBracket(numTeams int) ([]BracketGame, int) {
+	size := BracketSize(numTeams)
+	order := SeedOrder(size)
+
+	rounds := 0
+	for s := size; s > 1; s /= 2 {
+		rounds++
+	}
+
+	var games []BracketGame
+	for round := 1; round <= rounds; round++ {
+		count := size >> round
+		for pos := 0; pos < count; pos++ {
+			game := BracketGame{
+				Key:      bracketKey(StageWinners, round, pos),
+				Stage:    StageWinners,
+				Round:    round,
+				Position: pos,
+			}
+			if round == 1 {
+				game.HomeSeed = order[pos*2]
+				game.AwaySeed = order[pos*2+1]
+			}
+			if round < rounds {
+				game.WinnerTo = bracketKey(StageWinners, round+1, pos/2)
+				game.WinnerSlot = slotFor(pos)
+			}
+			games = append(games, game)
+		}
+	}
+	return games, rounds
+}
+
+// SingleEliminationBracket generates a single elimination bracket. The last
+// winners' round is the final.
+func SingleEliminationBracket(numTeams int) []BracketGame {
+	if numTeams < 2 {
+		return nil
+	}
+	games, rounds := winnersBracket(numTeams)
+	for i := range games {
+		if games[i].Round == rounds {
+			games[i].Stage = StageFinal
+			games[i].Key = bracketKey(StageFinal, 1, 0)
+			games[i].Round = 1
+		}
+	}
+	// Re-point the semifinal winners at the renamed final
+	for i := range games {
+		if games[i].WinnerTo == bracketKey(StageWinners, rounds, 0) {
+			games[i].WinnerTo = bracketKey(StageFinal, 1, 0)
+		}
+	}
+	return games
+}
+
+// DoubleEliminationBracket generates a double elimination bracket: a winners'
+// bracket, a losers' bracket fed by winners' bracket losers, and a grand final
+// between the two bracket champions. The winners' champion is the home team in
+// the grand final; if the losers' champion wins, a reset game is played (the
+// reset is created when needed, not here).
+//
+// For a bracket of size 2^k the losers' bracket has 2(k-1) rounds. Odd rounds
+// pair up survivors; even rounds bring in the losers of the next winners'
+// round, in reverse order to avoid immediate rematches.
+func DoubleEliminationBracket(numTeams int) []BracketGame {
+	if numTeams < 2 {
+		return nil
+	}
+	games, wbRounds := winnersBracket(numTeams)
+	size := BracketSize(numTeams)
+	final := bracketKey(StageFinal, 1, 0)
+	lbRounds := 2 * (wbRounds - 1)
+
+	byKey := make(map[string]*BracketGame, len(games))
+	for i := range games {
+		byKey[games[i].Key] = &games[i]
+	}
+
+	// Winners' champion goes to the grand final
+	byKey[bracketKey(StageWinners, wbRounds, 0)].WinnerTo = final
+	byKey[bracketKey(StageWinners, wbRounds, 0)].WinnerSlot = "home"
+
+	if lbRounds == 0 {
+		// Two teams: the loser of the only game goes straight to the final
+		byKey[bracketKey(StageWinners, 1, 0)].LoserTo = final
+		byKey[bracketKey(StageWinners, 1, 0)].LoserSlot = "away"
+	}
+
+	var losers []BracketGame
+	for round := 1; round <= lbRounds; round++ {
+		// Rounds 1-2 have size/4 games, 3-4 have size/8, ...
+		count := size >> ((round+1)/2 + 1)
+		for pos := 0; pos < count; pos++ {
+			game := BracketGame{
+				Key:      bracketKey(StageLosers, round, pos),
+				Stage:    StageLosers,
+				Round:    round,
+				Position: pos,
+			}
+			switch {
+			case round == lbRounds:
+				game.WinnerTo = final
+				game.WinnerSlot = "away"
+			case round%2 == 1:
+				// Survivors meet a winners' bracket dropout in the same position
+				game.WinnerTo = bracketKey(StageLosers, round+1, pos)
+				game.WinnerSlot = "home"
+			default:
+				game.WinnerTo = bracketKey(StageLosers, round+1, pos/2)
+				game.WinnerSlot = slotFor(pos)
+			}
+			losers = append(losers, game)
+		}
+	}
+
+	// Drop winners' bracket losers into the losers' bracket
+	for i := range games {
+		wb := &games[i]
+		if wb.Stage != StageWinners || lbRounds == 0 {
+			continue
+		}
+		if wb.Round == 1 {
+			wb.LoserTo = bracketKey(StageLosers, 1, wb.Position/2)
+			wb.LoserSlot = slotFor(wb.Position)
+			continue
+		}
+		lbRound := 2 * (wb.Round - 1)
+		count := size >> (wb.Round)
+		wb.LoserTo = bracketKey(StageLosers, lbRound, count-1-wb.Position)
+		wb.LoserSlot = "away"
+	}
+
+	games = append(games, losers...)
+	games = append(games, BracketGame{
+		Key:   final,
+		Stage: StageFinal,
+		Round: 1,
+	})
+	return games
+}
+
+// SeedFromPools orders the teams advancing from pool play into bracket seeds.
+// Pool winners are seeded first, then second-place teams, and so on; teams
+// with the same finishing place are ordered by their records. advancePerPool
+// limits how many teams leave each pool (0 means all of them).
+func SeedFromPools(pools map[string][]StandingsRow, advancePerPool int) []uuid.UUID {
+	names := make([]string, 0, len(pools))
+	deepest := 0
+	for name, rows := range pools {
+		names = append(names, name)
+		if len(rows) > deepest {
+			deepest = len(rows)
+		}
+	}
+	sort.Strings(names)
+	if advancePerPool > 0 && advancePerPool < deepest {
+		deepest = advancePerPool
+	}
+
+	var seeds []uuid.UUID
+	for place := 0; place < deepest; place++ {
+		var tier []StandingsRow
+		for _, name := range names {
+			if place < len(pools[name]) {
+				tier = append(tier, pools[name][place])
+			}
+		}
+		sort.SliceStable(tier, func(i, j int) bool { return rankedAhead(tier[i], tier[j]) })
+		for _, row := range tier {
+			seeds = append(seeds, row.TeamID)
+		}
+	}
+	return seeds
+}
@@ -0,0 +1,88 @@
+package algorithms
+
+import "fmt"
+
+// Lineup rules enforced by the generators: nine fielders in a 5-4 gender
+// split, either way round.
+const (
+	FieldPlayers      = 9
+	MajorityGenderMin = 5
+	MinorityGenderMin = 4
+)
+
+// ForfeitRisk describes whether a game can field a legal lineup from the
+// players who have confirmed, and who is missing if not.
+type ForfeitRisk struct {
+	AtRisk       bool     `json:"atRisk"`
+	Going        int      `json:"going"`
+	GoingMales   int      `json:"goingMales"`
+	GoingFemales int      `json:"goingFemales"`
+	Maybe        int      `json:"maybe"`
+	MaybeMales   int      `json:"maybeMales"`
+	MaybeFemales int      `json:"maybeFemales"`
+	NeedMales    int      `json:"needMales"`   // More men needed for the closest legal split
+	NeedFemales  int      `json:"needFemales"` // More women needed for the closest legal split
+	Reasons      []string `json:"reasons"`
+}
+
+// ShortGenders returns the genders ("M", "F") the game still needs players of.
+func (f ForfeitRisk) ShortGenders() []string {
+	var genders []string
+	if f.NeedMales > 0 {
+		genders = append(genders, "M")
+	}
+	if f.NeedFemales > 0 {
+		genders = append(genders, "F")
+	}
+	return genders
+}
+
+// AssessForfeitRisk checks confirmed attendance against the lineup rules.
+// Counts are of "going" and "maybe" players by gender. The shortfall is
+// measured against whichever split (5M-4F or 4M-5F) needs fewer extra players.
+func AssessForfeitRisk(goingMales, goingFemales, maybeMales, maybeFemales int) ForfeitRisk {
+	risk := ForfeitRisk{
+		Going:        goingMales + goingFemales,
+		GoingMales:   goingMales,
+		GoingFemales: goingFemales,
+		Maybe:        maybeMales + maybeFemales,
+		MaybeMales:   maybeMales,
+		MaybeFemales: maybeFemales,
+		Reasons:      []string{},
+	}
+
+	shortfall := func(have, want int) int {
+		if have >= want {
+			return 0
+		}
+		return want - have
+	}
+
+	// 5 men and 4 women
+	needM, needF := shortfall(goingMales, MajorityGenderMin), shortfall(goingFemales, MinorityGenderMin)
+	// 4 men and 5 women
+	altM, altF := shortfall(goingMales, MinorityGenderMin), shortfall(goingFemales, MajorityGenderMin)
+	if altM+altF < needM+needF {
+		needM, needF = altM, altF
+	}
+	risk.NeedMales, risk.NeedFemales = needM, needF
+	risk.AtRisk = needM+needF > 0
+
+	if risk.Going < FieldPlayers {
+		risk.Reasons = append(risk.Reasons, fmt.Sprintf("only %d of %d players confirmed", risk.Going, FieldPlayers))
+	}
+	if needM > 0 {
+		risk.Reasons = append(risk.Reasons, fmt.Sprintf("%d more %s needed for a 5-4 field", needM, plural(needM, "man", "men")))
+	}
+	if needF > 0 {
+		risk.Reasons = append(risk.Reasons, fmt.Sprintf("%d more %s needed for a 5-4 field", needF, plural(needF, "woman", "women")))
+	}
+	return risk
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
@@ -0,0 +1,191 @@
+package algorithms
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Matchup is a single game between two teams within a round.
+type Matchup struct {
+	Round      int       `json:"round"` // 1-based
+	HomeTeamID uuid.UUID `json:"homeTeamId"`
+	AwayTeamID uuid.UUID `json:"awayTeamId"`
+}
+
+// WeeklyTimeSlot is a recurring start time on a day of the week.
+type WeeklyTimeSlot struct {
+	Weekday time.Weekday
+	Time    string // HH:MM
+}
+
+// ScheduleSlot is a concrete date, start time and venue a game can be played in.
+type ScheduleSlot struct {
+	Date    time.Time `json:"date"`
+	Time    string    `json:"time"`
+	VenueID uuid.UUID `json:"venueId"`
+}
+
+// ScheduledGame is a matchup assigned to a slot.
+type ScheduledGame struct {
+	Matchup
+	ScheduleSlot
+}
+
+// ParseWeekday accepts full or three-letter English day names, case-insensitively.
+func ParseWeekday(name string) (time.Weekday, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		full := strings.ToLower(d.String())
+		if name == full || name == full[:3] {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid weekday %q", name)
+}
+
+// RoundRobin pairs every team with every other team using the circle method.
+// With an odd number of teams one team sits out each round. A double round
+// robin repeats the schedule with home and away swapped.
+//
+// Home and away follow the distance between the paired teams in the circle,
+// which balances them exactly: with an odd number of teams everyone has as
+// many home games as away games, and with an even number the counts differ by
+// one. A double round robin is always even.
+func RoundRobin(teamIDs []uuid.UUID, double bool) [][]Matchup {
+	if len(teamIDs) < 2 {
+		return nil
+	}
+
+	// uuid.Nil is the bye placeholder for odd team counts
+	teams := append([]uuid.UUID{}, teamIDs...)
+	if len(teams)%2 == 1 {
+		teams = append(teams, uuid.Nil)
+	}
+	// The last team stays fixed while the other m circle round it
+	m := len(teams) - 1
+	fixed := teams[m]
+
+	var rounds [][]Matchup
+	for round := 0; round < m; round++ {
+		var matchups []Matchup
+		add := func(home, away uuid.UUID) {
+			if home == uuid.Nil || away == uuid.Nil {
+				return
+			}
+			matchups = append(matchups, Matchup{Round: round + 1, HomeTeamID: home, AwayTeamID: away})
+		}
+
+		// The fixed team alternates, and plays each of the others once
+		if round%2 == 0 {
+			add(fixed, teams[round])
+		} else {
+			add(teams[round], fixed)
+		}
+		// The rest pair up either side of the team facing the fixed one; an
+		// odd distance gives home to the team ahead, an even one to the team
+		// behind, so each team is home for half of its games
+		for j := 1; j <= (m-1)/2; j++ {
+			ahead, behind := teams[(round+j)%m], teams[(round-j+m)%m]
+			if j%2 == 1 {
+				add(ahead, behind)
+			} else {
+				add(behind, ahead)
+			}
+		}
+		rounds = append(rounds, matchups)
+	}
+
+	if double {
+		single := len(rounds)
+		for i := 0; i < single; i++ {
+			mirrored := make([]Matchup, len(rounds[i]))
+			for j, m := range rounds[i] {
+				mirrored[j] = Matchup{Round: single + i + 1, HomeTeamID: m.AwayTeamID, AwayTeamID: m.HomeTeamID}
+			}
+			rounds = append(rounds, mirrored)
+		}
+	}
+
+	return rounds
+}
+
+// BuildScheduleSlots expands weekly time slots into concrete slots for every
+// venue on every matching date between start and end (inclusive), skipping
+// excluded dates such as holidays.
+func BuildScheduleSlots(start, end time.Time, times []WeeklyTimeSlot, venueIDs []uuid.UUID, exclude []time.Time) []ScheduleSlot {
+	excluded := make(map[string]bool, len(exclude))
+	for _, d := range exclude {
+		excluded[d.Format("2006-01-02")] = true
+	}
+
+	var slots []ScheduleSlot
+	for date := start; !date.After(end); date = date.AddDate(0, 0, 1) {
+		if excluded[date.Format("2006-01-02")] {
+			continue
+		}
+		for _, t := range times {
+			if t.Weekday != date.Weekday() {
+				continue
+			}
+			for _, venueID := range venueIDs {
+				slots = append(slots, ScheduleSlot{Date: date, Time: t.Time, VenueID: venueID})
+			}
+		}
+	}
+	return slots
+}
+
+// AssignSlots places each round on its own date, using the earliest remaining
+// date with enough slots for the whole round. Keeping a round to a single date
+// guarantees no team is double-booked. Within a date, slots are filled in
+// time order.
+func AssignSlots(rounds [][]Matchup, slots []ScheduleSlot) ([]ScheduledGame, error) {
+	byDate := make(map[string][]ScheduleSlot)
+	var dates []string
+	for _, slot := range slots {
+		key := slot.Date.Format("2006-01-02")
+		if _, ok := byDate[key]; !ok {
+			dates = append(dates, key)
+		}
+		byDate[key] = append(byDate[key], slot)
+	}
+	sort.Strings(dates)
+	for _, key := range dates {
+		daySlots := byDate[key]
+		sort.SliceStable(daySlots, func(i, j int) bool { return daySlots[i].Time < daySlots[j].Time })
+	}
+
+	var scheduled []ScheduledGame
+	next := 0
+	for _, round := range rounds {
+		if len(round) == 0 {
+			continue
+		}
+		for next < len(dates) && len(byDate[dates[next]]) < len(round) {
+			next++
+		}
+		if next >= len(dates) {
+			return nil, fmt.Errorf("not enough dates: scheduled %d of %d rounds, each round needs %d slots on one date", countRounds(scheduled), len(rounds), len(round))
+		}
+
+		daySlots := byDate[dates[next]]
+		for i, m := range round {
+			scheduled = append(scheduled, ScheduledGame{Matchup: m, ScheduleSlot: daySlots[i]})
+		}
+		next++
+	}
+
+	return scheduled, nil
+}
+
+func countRounds(games []ScheduledGame) int {
+	seen := make(map[int]bool)
+	for _, g := range games {
+		seen[g.Round] = true
+	}
+	return len(seen)
+}
@@ -0,0 +1,92 @@
+package algorithms
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestRoundRobin(t *testing.T) {
+	tests := []struct {
+		teams   int
+		double  bool
+		maxDiff int // Largest home-minus-away gap allowed for any team
+	}{
+		{teams: 2, maxDiff: 1},
+		{teams: 3, maxDiff: 0},
+		{teams: 4, maxDiff: 1},
+		{teams: 5, maxDiff: 0},
+		{teams: 6, maxDiff: 1},
+		{teams: 7, maxDiff: 0},
+		{teams: 8, maxDiff: 1},
+		{teams: 9, maxDiff: 0},
+		{teams: 10, maxDiff: 1},
+		{teams: 11, maxDiff: 0},
+		{teams: 12, maxDiff: 1},
+		{teams: 13, maxDiff: 0},
+		{teams: 14, maxDiff: 1},
+		{teams: 15, maxDiff: 0},
+		{teams: 16, maxDiff: 1},
+		{teams: 4, double: true, maxDiff: 0},
+		{teams: 9, double: true, maxDiff: 0},
+		{teams: 14, double: true, maxDiff: 0},
+	}
+
+	for _, tt := range tests {
+		teamIDs := make([]uuid.UUID, tt.teams)
+		for i := range teamIDs {
+			teamIDs[i] = uuid.New()
+		}
+
+		rounds := RoundRobin(teamIDs, tt.double)
+
+		meetings := 1
+		if tt.double {
+			meetings = 2
+		}
+		wantRounds := tt.teams - 1
+		if tt.teams%2 == 1 {
+			wantRounds = tt.teams
+		}
+		if len(rounds) != wantRounds*meetings {
+			t.Errorf("%d teams, double %v: got %d rounds, want %d", tt.teams, tt.double, len(rounds), wantRounds*meetings)
+		}
+
+		games := make(map[[2]uuid.UUID]int)
+		home := make(map[uuid.UUID]int)
+		away := make(map[uuid.UUID]int)
+		for r, round := range rounds {
+			playing := make(map[uuid.UUID]bool)
+			for _, m := range round {
+				if m.Round != r+1 {
+					t.Errorf("%d teams, double %v: matchup in round %d says round %d", tt.teams, tt.double, r+1, m.Round)
+				}
+				if playing[m.HomeTeamID] || playing[m.AwayTeamID] {
+					t.Errorf("%d teams, double %v: a team plays twice in round %d", tt.teams, tt.double, r+1)
+				}
+				playing[m.HomeTeamID] = true
+				playing[m.AwayTeamID] = true
+				games[[2]uuid.UUID{m.HomeTeamID, m.AwayTeamID}]++
+				home[m.HomeTeamID]++
+				away[m.AwayTeamID]++
+			}
+		}
+
+		for i, a := range teamIDs {
+			for _, b := range teamIDs[i+1:] {
+				ab, ba := games[[2]uuid.UUID{a, b}], games[[2]uuid.UUID{b, a}]
+				if ab+ba != meetings || (tt.double && ab != 1) {
+					t.Errorf("%d teams, double %v: a pair meets %d times at one's home and %d at the other's", tt.teams, tt.double, ab, ba)
+				}
+			}
+			diff := home[a] - away[a]
+			if diff < 0 {
+				diff = -diff
+			}
+			if diff > tt.maxDiff {
+				t.Errorf("%d teams, double %v: a team has %d home and %d away games, want a gap of at most %d",
+					tt.teams, tt.double, home[a], away[a], tt.maxDiff)
+			}
+		}
+	}
+}
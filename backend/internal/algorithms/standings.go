@@ -0,0 +1,131 @@
+package algorithms
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/liam/screaming-toller/backend/internal/models"
+)
+
+// StandingsRow is one team's line in a league standings table.
+type StandingsRow struct {
+	Rank            int       `json:"rank"`
+	TeamID          uuid.UUID `json:"teamId"`
+	TeamName        string    `json:"teamName"`
+	LogoURL         string    `json:"logoUrl"`
+	GamesPlayed     int       `json:"gamesPlayed"`
+	Wins            int       `json:"wins"`
+	Losses          int       `json:"losses"`
+	Ties            int       `json:"ties"`
+	WinPct          float64   `json:"winPct"`
+	RunsFor         int       `json:"runsFor"`
+	RunsAgainst     int       `json:"runsAgainst"`
+	RunDifferential int       `json:"runDifferential"`
+	Streak          string    `json:"streak"` // e.g. "W3", "L1"; empty if no games played
+}
+
+// IsScored reports whether a game has a final result that counts towards standings.
+func IsScored(game models.Game) bool {
+	return game.FinalScore != nil && game.OpponentScore != nil &&
+		game.Status != "cancelled" && game.Status != "postponed"
+}
+
+// ComputeStandings builds a standings table from each team's own game records.
+// Every team is scored from the perspective of its own Game rows, so a game
+// between two platform teams counts once for each side. Games without a final
+// score, or that were cancelled or postponed, are ignored.
+//
+// Teams are ranked by win percentage (ties count as half a win), then run
+// differential, then fewest runs allowed.
+func ComputeStandings(teams []models.Team, games []models.Game) []StandingsRow {
+	rows := make(map[uuid.UUID]*StandingsRow, len(teams))
+	for _, team := range teams {
+		rows[team.ID] = &StandingsRow{
+			TeamID:   team.ID,
+			TeamName: team.Name,
+			LogoURL:  team.LogoURL,
+		}
+	}
+
+	// Process games in date order so the streak reflects the most recent results
+	sorted := make([]models.Game, 0, len(games))
+	for _, game := range games {
+		if _, ok := rows[game.TeamID]; ok && IsScored(game) {
+			sorted = append(sorted, game)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].Date.Equal(sorted[j].Date) {
+			return sorted[i].Date.Before(sorted[j].Date)
+		}
+		return sorted[i].Time < sorted[j].Time
+	})
+
+	streakResult := make(map[uuid.UUID]string)
+	streakLength := make(map[uuid.UUID]int)
+
+	for _, game := range sorted {
+		row := rows[game.TeamID]
+		runsFor, runsAgainst := *game.FinalScore, *game.OpponentScore
+
+		row.GamesPlayed++
+		row.RunsFor += runsFor
+		row.RunsAgainst += runsAgainst
+
+		var result string
+		switch {
+		case runsFor > runsAgainst:
+			row.Wins++
+			result = "W"
+		case runsFor < runsAgainst:
+			row.Losses++
+			result = "L"
+		default:
+			row.Ties++
+			result = "T"
+		}
+
+		if streakResult[game.TeamID] == result {
+			streakLength[game.TeamID]++
+		} else {
+			streakResult[game.TeamID] = result
+			streakLength[game.TeamID] = 1
+		}
+	}
+
+	standings := make([]StandingsRow, 0, len(rows))
+	for _, team := range teams {
+		row := rows[team.ID]
+		row.RunDifferential = row.RunsFor - row.RunsAgainst
+		if row.GamesPlayed > 0 {
+			row.WinPct = (float64(row.Wins) + 0.5*float64(row.Ties)) / float64(row.GamesPlayed)
+			row.Streak = fmt.Sprintf("%s%d", streakResult[team.ID], streakLength[team.ID])
+		}
+		standings = append(standings, *row)
+	}
+
+	sort.SliceStable(standings, func(i, j int) bool {
+		return rankedAhead(standings[i], standings[j])
+	})
+
+	for i := range standings {
+		standings[i].Rank = i + 1
+	}
+
+	return standings
+}
+
+// rankedAhead reports whether a ranks above b in the standings.
+func rankedAhead(a, b StandingsRow) bool {
+	if a.WinPct != b.WinPct {
+		return a.WinPct > b.WinPct
+	}
+	if a.RunDifferential != b.RunDifferential {
+		return a.RunDifferential > b.RunDifferential
+	}
+	if a.RunsAgainst != b.RunsAgainst {
+		return a.RunsAgainst < b.RunsAgainst
+	}
+	return a.TeamName < b.TeamName
+}
@@ -27,19 +27,44 @@ func InitDB() {
 
 	log.Println("Connected to database")
 
+	// Verified phone numbers must be unique before their index is
+	migrateUniquePhones(DB)
+
 	// Auto-migrate models
 	err = DB.AutoMigrate(
 		&models.User{},
 		&models.Team{},
 		&models.TeamMember{},
 		&models.TeamMemberPreference{},
+		&models.League{},
+		&models.LeagueTeam{},
+		&models.LeagueAdmin{},
+		&models.Tournament{},
+		&models.TournamentTeam{},
+		&models.TournamentGame{},
+		&models.Venue{},
 		&models.Game{},
 		&models.Attendance{},
+		&models.Blackout{},
+		&models.RSVPToken{},
+		&models.PhoneVerification{},
+		&models.ReminderRule{},
+		&models.ReminderSend{},
+		&models.MessageTemplate{},
+		&models.ScheduledJob{},
 		&models.BattingOrder{},
 		&models.BattingOrderPool{},
 		&models.FieldingLineup{},
 		&models.InningScore{},
 		&models.Invitation{},
+		&models.NotificationPreference{},
+		&models.PushSubscription{},
+		&models.TeamIntegration{},
+		&models.DigestSubscription{},
+		&models.OutboxMessage{},
+		&models.Spare{},
+		&models.SpareRequest{},
+		&models.SpareInvite{},
 	)
 	if err != nil {
 		log.Fatalf("Failed to migrate database: %v", err)
@@ -61,6 +86,23 @@ func InitDB() {
 
 	// Make batting_orders.team_member_id nullable
 	migrateBattingOrderNullableTeamMember(DB)
+
+	// Give jobs scheduled before fire_at existed their fire time
+	migrateScheduledJobFireAt(DB)
+}
+
+// migrateScheduledJobFireAt sets fire_at on jobs created before the column
+// existed. Their run_at is the closest there is; without it every such job
+// would be rescheduled, and run again, by the next reconcile.
+func migrateScheduledJobFireAt(db *gorm.DB) {
+	result := db.Exec("UPDATE scheduled_jobs SET fire_at = run_at WHERE fire_at IS NULL")
+	if result.Error != nil {
+		log.Printf("Failed to backfill scheduled_jobs.fire_at: %v", result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Backfilled fire_at on %d scheduled jobs", result.RowsAffected)
+	}
 }
 
 func migrateRoles(db *gorm.DB) {
@@ -109,6 +151,37 @@ func migrateAuth0(db *gorm.DB) {
 	}
 }
 
+// migrateUniquePhones readies users.phone for its unique index, which covers
+// verified numbers only. A number verified on several accounts stays verified
+// only on the one that verified it first, and an older index is dropped so
+// AutoMigrate recreates it.
+func migrateUniquePhones(db *gorm.DB) {
+	var indexDef string
+	if err := db.Raw("SELECT indexdef FROM pg_indexes WHERE tablename = 'users' AND indexname = 'idx_users_phone'").Scan(&indexDef).Error; err != nil {
+		log.Printf("Failed to check users phone index: %v", err)
+		return
+	}
+	if indexDef == "" || strings.Contains(indexDef, "phone_verified_at") {
+		return
+	}
+
+	// Before phone_verified_at existed nothing was verified, so nothing clashes
+	if db.Migrator().HasColumn(&models.User{}, "phone_verified_at") {
+		result := db.Exec(`UPDATE users SET phone_verified_at = NULL WHERE phone_verified_at IS NOT NULL AND id NOT IN (
+			SELECT DISTINCT ON (phone) id FROM users WHERE phone <> '' AND phone_verified_at IS NOT NULL ORDER BY phone, phone_verified_at)`)
+		if result.Error != nil {
+			log.Printf("Failed to unverify duplicate phone numbers: %v", result.Error)
+			return
+		}
+		if result.RowsAffected > 0 {
+			log.Printf("Unverified %d duplicate phone numbers", result.RowsAffected)
+		}
+	}
+	if err := db.Migrator().DropIndex(&models.User{}, "idx_users_phone"); err != nil {
+		log.Printf("Failed to drop users phone index: %v", err)
+	}
+}
+
 func fixGameConstraints(db *gorm.DB) {
 	log.Println("Checking for game foreign key constraints to ensure CASCADE...")
 
@@ -2,12 +2,14 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/liam/screaming-toller/backend/internal/database"
 	"github.com/liam/screaming-toller/backend/internal/models"
+	"github.com/liam/screaming-toller/backend/internal/services"
 	"github.com/liam/screaming-toller/backend/internal/utils"
 	"gorm.io/gorm"
 )
@@ -25,6 +27,8 @@ type UpdateUserRequest struct {
 	Name            string `json:"name"`
 	OptOutReminders bool   `json:"optOutReminders"`
 	WhapiToken      string `json:"whapiToken"`
+	Phone           *string `json:"phone,omitempty"` // Used to match WhatsApp replies; omit to keep, "" to clear
+	Locale          *string `json:"locale,omitempty"` // Language for messages; omit to keep, "" to use the team's
 }
 
 // maskToken returns a masked version of the token (e.g. "********") if it exists.
@@ -192,6 +196,39 @@ func UpdateMe(w http.ResponseWriter, r *http.Request) {
 
 	user.Name = req.Name
 	user.OptOutReminders = req.OptOutReminders
+
+	if req.Phone != nil {
+		phone := services.NormalizePhone(*req.Phone)
+		if *req.Phone != "" && len(phone) < 10 {
+			http.Error(w, "Invalid phone number", http.StatusBadRequest)
+			return
+		}
+		if phone != "" && phone != user.Phone {
+			var taken int64
+			if err := database.DB.Model(&models.User{}).Where("phone = ? AND id <> ? AND phone_verified_at IS NOT NULL", phone, user.ID).Count(&taken).Error; err != nil {
+				http.Error(w, "Failed to update user", http.StatusInternalServerError)
+				return
+			}
+			if taken > 0 {
+				http.Error(w, "This phone number is already used by another account", http.StatusConflict)
+				return
+			}
+		}
+		if phone != user.Phone {
+			// A new number must be verified again before it is texted. The
+			// verification row is kept, so its sends still count.
+			user.PhoneVerifiedAt = nil
+		}
+		user.Phone = phone
+	}
+
+	if req.Locale != nil {
+		if *req.Locale != "" && !services.SupportedLocale(*req.Locale) {
+			http.Error(w, "Unsupported language", http.StatusBadRequest)
+			return
+		}
+		user.Locale = *req.Locale
+	}
 	
 	if req.WhapiToken != "" && req.WhapiToken != "********" {
 		encrypted, err := utils.Encrypt(req.WhapiToken)
@@ -210,3 +247,83 @@ func UpdateMe(w http.ResponseWriter, r *http.Request) {
 	user.WhapiToken = maskToken(user.WhapiToken)
 	json.NewEncoder(w).Encode(user)
 }
+
+type ConfirmPhoneRequest struct {
+	Code string `json:"code"`
+}
+
+// SendPhoneVerification texts a code to the current user's phone number.
+// Text messages are only sent to a number once its code is confirmed.
+func SendPhoneVerification(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uuid.UUID)
+
+	var user models.User
+	if result := database.DB.First(&user, userID); result.Error != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if user.PhoneVerifiedAt != nil {
+		http.Error(w, "Phone number is already verified", http.StatusConflict)
+		return
+	}
+
+	err := services.SendPhoneVerification(notifications, user)
+	switch {
+	case errors.Is(err, services.ErrNoPhone):
+		http.Error(w, "Add a phone number first", http.StatusBadRequest)
+		return
+	case errors.Is(err, services.ErrPhoneTaken):
+		http.Error(w, "This phone number is already used by another account", http.StatusConflict)
+		return
+	case errors.Is(err, services.ErrSMSNotConfigured):
+		http.Error(w, "Text messages are not configured", http.StatusServiceUnavailable)
+		return
+	case errors.Is(err, services.ErrPhoneCodeTooSoon):
+		http.Error(w, "Please wait a minute before requesting another code", http.StatusTooManyRequests)
+		return
+	case errors.Is(err, services.ErrPhoneCodeLimit):
+		http.Error(w, "Too many codes have been sent today; try again tomorrow", http.StatusTooManyRequests)
+		return
+	case err != nil:
+		http.Error(w, "Failed to send verification code", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ConfirmPhoneVerification checks the code texted to the current user and
+// marks their phone number verified.
+func ConfirmPhoneVerification(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uuid.UUID)
+
+	var req ConfirmPhoneRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	err := services.ConfirmPhoneVerification(userID, req.Code)
+	switch {
+	case errors.Is(err, services.ErrPhoneCodeInvalid):
+		http.Error(w, "The code is incorrect", http.StatusBadRequest)
+		return
+	case errors.Is(err, services.ErrPhoneCodeExpired), errors.Is(err, services.ErrPhoneCodeNotFound):
+		http.Error(w, "The code has expired; request a new one", http.StatusGone)
+		return
+	case errors.Is(err, services.ErrPhoneTaken):
+		http.Error(w, "This phone number is already used by another account", http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, "Failed to verify phone number", http.StatusInternalServerError)
+		return
+	}
+
+	var user models.User
+	if result := database.DB.First(&user, userID); result.Error != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	user.WhapiToken = maskToken(user.WhapiToken)
+	json.NewEncoder(w).Encode(user)
+}
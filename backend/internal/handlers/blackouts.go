@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/liam/screaming-toller/backend/internal/database"
+	"github.com/liam/screaming-toller/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type CreateBlackoutRequest struct {
+	StartDate string `json:"startDate"` // YYYY-MM-DD
+	EndDate   string `json:"endDate"`   // YYYY-MM-DD, inclusive
+	Reason    string `json:"reason"`
+}
+
+type BlackoutResponse struct {
+	Blackout     models.Blackout `json:"blackout"`
+	GamesUpdated int             `json:"gamesUpdated"`
+}
+
+// GetMyBlackouts lists the current user's blackout ranges on the team.
+func GetMyBlackouts(w http.ResponseWriter, r *http.Request) {
+	teamMember, ok := myTeamMember(w, r)
+	if !ok {
+		return
+	}
+
+	var blackouts []models.Blackout
+	if result := database.DB.Where("team_member_id = ?", teamMember.ID).Order("start_date asc").Find(&blackouts); result.Error != nil {
+		http.Error(w, "Failed to fetch blackout dates", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(blackouts)
+}
+
+// CreateMyBlackout registers a date range the current user is away for and
+// marks them not_going for every upcoming game inside it.
+func CreateMyBlackout(w http.ResponseWriter, r *http.Request) {
+	teamMember, ok := myTeamMember(w, r)
+	if !ok {
+		return
+	}
+
+	var req CreateBlackoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	startDate, err := time.Parse("2006-01-02", req.StartDate)
+	if err != nil {
+		http.Error(w, "Invalid start date format. Use YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	endDate, err := time.Parse("2006-01-02", req.EndDate)
+	if err != nil {
+		http.Error(w, "Invalid end date format. Use YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	if endDate.Before(startDate) {
+		http.Error(w, "End date must be on or after the start date", http.StatusBadRequest)
+		return
+	}
+
+	blackout := models.Blackout{
+		TeamMemberID: teamMember.ID,
+		StartDate:    startDate,
+		EndDate:      endDate,
+		Reason:       strings.TrimSpace(req.Reason),
+	}
+
+	var updated int
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&blackout).Error; err != nil {
+			return err
+		}
+		var err error
+		updated, err = applyBlackout(tx, teamMember, blackout)
+		return err
+	})
+	if err != nil {
+		http.Error(w, "Failed to save blackout dates", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(BlackoutResponse{Blackout: blackout, GamesUpdated: updated})
+}
+
+// DeleteMyBlackout removes a blackout range. Attendance already set to
+// not_going is left as is, since the member may have set it themselves.
+func DeleteMyBlackout(w http.ResponseWriter, r *http.Request) {
+	teamMember, ok := myTeamMember(w, r)
+	if !ok {
+		return
+	}
+
+	blackoutID, err := uuid.Parse(chi.URLParam(r, "blackoutID"))
+	if err != nil {
+		http.Error(w, "Invalid blackout ID", http.StatusBadRequest)
+		return
+	}
+
+	result := database.DB.Where("id = ? AND team_member_id = ?", blackoutID, teamMember.ID).Delete(&models.Blackout{})
+	if result.Error != nil {
+		http.Error(w, "Failed to delete blackout dates", http.StatusInternalServerError)
+		return
+	}
+	if result.RowsAffected == 0 {
+		http.Error(w, "Blackout not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetTeamBlackouts lists every active member's current and upcoming blackout
+// ranges so admins can plan around them.
+func GetTeamBlackouts(w http.ResponseWriter, r *http.Request) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+		return
+	}
+
+	var blackouts []models.Blackout
+	if result := database.DB.Preload("TeamMember.User").
+		Joins("JOIN team_members ON team_members.id = blackouts.team_member_id").
+		Where("team_members.team_id = ? AND team_members.is_active = ? AND blackouts.end_date >= ?", teamID, true, today()).
+		Order("blackouts.start_date asc").
+		Find(&blackouts); result.Error != nil {
+		http.Error(w, "Failed to fetch blackout dates", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(blackouts)
+}
+
+// myTeamMember loads the current user's active membership on the team in the
+// URL, writing the error response if there isn't one.
+func myTeamMember(w http.ResponseWriter, r *http.Request) (*models.TeamMember, bool) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	userID := r.Context().Value("userID").(uuid.UUID)
+
+	var teamMember models.TeamMember
+	if result := database.DB.Where("team_id = ? AND user_id = ? AND is_active = ?", teamID, userID, true).First(&teamMember); result.Error != nil {
+		http.Error(w, "Team member not found", http.StatusNotFound)
+		return nil, false
+	}
+	return &teamMember, true
+}
+
+// applyBlackout marks the member not_going for the team's upcoming games that
+// fall inside the blackout, returning how many games were affected.
+func applyBlackout(tx *gorm.DB, teamMember *models.TeamMember, blackout models.Blackout) (int, error) {
+	var games []models.Game
+	if err := tx.Where("team_id = ? AND date >= ? AND date BETWEEN ? AND ? AND status NOT IN ?",
+		teamMember.TeamID, today(), blackout.StartDate, blackout.EndDate, []string{"cancelled", "completed"}).
+		Find(&games).Error; err != nil {
+		return 0, err
+	}
+
+	for _, game := range games {
+		if err := setNotGoing(tx, teamMember.ID, game.ID); err != nil {
+			return 0, err
+		}
+	}
+	return len(games), nil
+}
+
+// applyBlackoutsToGame marks members not_going for a game that now falls in
+// one of their blackout ranges, e.g. after the game is moved.
+func applyBlackoutsToGame(tx *gorm.DB, game models.Game) error {
+	blackedOut, err := blackedOutMembers(tx, game.TeamID, game.Date)
+	if err != nil {
+		return err
+	}
+	for memberID := range blackedOut {
+		if err := setNotGoing(tx, memberID, game.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// blackedOutMembers returns the team's active members with a blackout
+// covering date.
+func blackedOutMembers(tx *gorm.DB, teamID uuid.UUID, date time.Time) (map[uuid.UUID]bool, error) {
+	var memberIDs []uuid.UUID
+	if err := tx.Model(&models.Blackout{}).
+		Joins("JOIN team_members ON team_members.id = blackouts.team_member_id").
+		Where("team_members.team_id = ? AND team_members.is_active = ? AND blackouts.start_date <= ? AND blackouts.end_date >= ?", teamID, true, date, date).
+		Pluck("blackouts.team_member_id", &memberIDs).Error; err != nil {
+		return nil, err
+	}
+
+	blackedOut := make(map[uuid.UUID]bool, len(memberIDs))
+	for _, id := range memberIDs {
+		blackedOut[id] = true
+	}
+	return blackedOut, nil
+}
+
+func setNotGoing(tx *gorm.DB, teamMemberID, gameID uuid.UUID) error {
+	var attendance models.Attendance
+	err := tx.Where("team_member_id = ? AND game_id = ?", teamMemberID, gameID).First(&attendance).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return tx.Create(&models.Attendance{
+			TeamMemberID: teamMemberID,
+			GameID:       gameID,
+			Status:       "not_going",
+			UpdatedAt:    time.Now(),
+		}).Error
+	}
+	if err != nil {
+		return err
+	}
+	if attendance.Status == "not_going" {
+		return nil
+	}
+	return tx.Model(&attendance).Updates(map[string]interface{}{
+		"status":     "not_going",
+		"updated_at": time.Now(),
+	}).Error
+}
+
+// today is the current date at midnight UTC, matching how game dates are stored.
+func today() time.Time {
+	return time.Now().UTC().Truncate(24 * time.Hour)
+}
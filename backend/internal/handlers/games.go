@@ -2,24 +2,34 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/liam/screaming-toller/backend/internal/database"
 	"github.com/liam/screaming-toller/backend/internal/models"
 	"github.com/liam/screaming-toller/backend/internal/algorithms"
+	"github.com/liam/screaming-toller/backend/internal/services"
 	"gorm.io/gorm"
 )
 
+var errVenueNotFound = errors.New("venue not found")
+
 type CreateGameRequest struct {
 	Date         string `json:"date"` // YYYY-MM-DD
 	Time         string `json:"time"` // HH:MM
 	Location     string `json:"location"`
 	OpposingTeam string `json:"opposingTeam"`
 	IsHome       bool   `json:"isHome"`
+	VenueID      *uuid.UUID `json:"venueId,omitempty"`
+	OpponentTeamID *uuid.UUID `json:"opponentTeamId,omitempty"` // Links the opponent when they are also on the platform
+	RSVPDeadline *time.Time `json:"rsvpDeadline,omitempty"` // Overrides the team's default RSVP deadline
 }
 
 func CreateGame(w http.ResponseWriter, r *http.Request) {
@@ -50,6 +60,31 @@ func CreateGame(w http.ResponseWriter, r *http.Request) {
 		OpposingTeam: req.OpposingTeam,
 		IsHome:       req.IsHome,
 		Status:       "scheduled",
+		RSVPDeadline: req.RSVPDeadline,
+	}
+
+	if req.VenueID != nil {
+		venue, err := findTeamVenue(database.DB, teamID, *req.VenueID)
+		if err != nil {
+			http.Error(w, "Venue not found", http.StatusBadRequest)
+			return
+		}
+		game.VenueID = &venue.ID
+		if game.Location == "" {
+			game.Location = venue.Name
+		}
+	}
+
+	if req.OpponentTeamID != nil {
+		opponent, err := findOpponentTeam(teamID, *req.OpponentTeamID)
+		if err != nil {
+			http.Error(w, "Opponent team not found", http.StatusBadRequest)
+			return
+		}
+		game.OpponentTeamID = &opponent.ID
+		if game.OpposingTeam == "" {
+			game.OpposingTeam = opponent.Name
+		}
 	}
 
 	if result := database.DB.Create(&game); result.Error != nil {
@@ -58,26 +93,11 @@ func CreateGame(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Initialize attendance for all active team members with "maybe" status
-	var teamMembers []models.TeamMember
-	if result := database.DB.Where("team_id = ? AND is_active = ?", teamID, true).Find(&teamMembers); result.Error != nil {
-		http.Error(w, "Failed to fetch team members", http.StatusInternalServerError)
+	if err := initializeAttendance(database.DB, teamID, game.ID); err != nil {
+		http.Error(w, "Failed to initialize attendance", http.StatusInternalServerError)
 		return
 	}
-
-	// Create attendance records for all team members
-	for _, teamMember := range teamMembers {
-		attendance := models.Attendance{
-			TeamMemberID: teamMember.ID,
-			GameID:       game.ID,
-			Status:       "maybe",
-			UpdatedAt:    time.Now(),
-		}
-		if result := database.DB.Create(&attendance); result.Error != nil {
-			// Log error but don't fail the whole game creation
-			http.Error(w, "Failed to initialize attendance", http.StatusInternalServerError)
-			return
-		}
-	}
+	scheduleGameJobs(game.ID)
 
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(game)
@@ -91,10 +111,11 @@ func GetTeamGames(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var games []models.Game
-	if result := database.DB.Where("team_id = ?", teamID).Order("date asc").Find(&games); result.Error != nil {
+	if result := database.DB.Preload("Venue").Where("team_id = ?", teamID).Order("date asc").Find(&games); result.Error != nil {
 		http.Error(w, "Failed to fetch games", http.StatusInternalServerError)
 		return
 	}
+	setRSVPDeadlines(teamID, games)
 
 	json.NewEncoder(w).Encode(games)
 }
@@ -115,12 +136,14 @@ func GetGame(w http.ResponseWriter, r *http.Request) {
 	var game models.Game
 	if result := database.DB.Preload("InningScores", func(db *gorm.DB) *gorm.DB {
 		return db.Order("inning ASC")
-	}).Where("id = ? AND team_id = ?", gameID, teamID).First(&game); result.Error != nil {
+	}).Preload("Venue").Where("id = ? AND team_id = ?", gameID, teamID).First(&game); result.Error != nil {
 		http.Error(w, "Game not found", http.StatusNotFound)
 		return
 	}
+	games := []models.Game{game}
+	setRSVPDeadlines(teamID, games)
 
-	json.NewEncoder(w).Encode(game)
+	json.NewEncoder(w).Encode(games[0])
 }
 
 func GetAttendance(w http.ResponseWriter, r *http.Request) {
@@ -139,8 +162,51 @@ func GetAttendance(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(attendance)
 }
 
+// GetForfeitRisk reports whether the players confirmed so far can field a
+// legal 5-4 lineup, and how many more of each gender are needed if not.
+func GetForfeitRisk(w http.ResponseWriter, r *http.Request) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+		return
+	}
+	gameID, err := uuid.Parse(chi.URLParam(r, "gameID"))
+	if err != nil {
+		http.Error(w, "Invalid game ID", http.StatusBadRequest)
+		return
+	}
+
+	var game models.Game
+	if result := database.DB.Where("id = ? AND team_id = ?", gameID, teamID).First(&game); result.Error != nil {
+		http.Error(w, "Game not found", http.StatusNotFound)
+		return
+	}
+
+	risk, err := services.GameForfeitRisk(game.ID)
+	if err != nil {
+		http.Error(w, "Failed to assess attendance", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(risk)
+}
+
 type UpdateAttendanceRequest struct {
-	Status string `json:"status"` // "going", "not_going", "maybe"
+	Status string  `json:"status"`         // "going", "not_going", "maybe"
+	Note   *string `json:"note,omitempty"` // Omit to keep the current note, "" clears it
+}
+
+// maxAttendanceNoteLength matches the size of the attendances.note column.
+const maxAttendanceNoteLength = 280
+
+// attendanceNoteUpdate trims the note and checks its length. ok is false when
+// the note is too long.
+func attendanceNoteUpdate(note *string) (string, bool) {
+	if note == nil {
+		return "", true
+	}
+	trimmed := strings.TrimSpace(*note)
+	return trimmed, utf8.RuneCountInString(trimmed) <= maxAttendanceNoteLength
 }
 
 func UpdateAttendance(w http.ResponseWriter, r *http.Request) {
@@ -178,14 +244,36 @@ func UpdateAttendance(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	note, ok := attendanceNoteUpdate(req.Note)
+	if !ok {
+		http.Error(w, fmt.Sprintf("Note must be %d characters or fewer", maxAttendanceNoteLength), http.StatusBadRequest)
+		return
+	}
+
+	var game models.Game
+	if result := database.DB.Preload("Team").Where("id = ? AND team_id = ?", gameID, teamID).First(&game); result.Error != nil {
+		http.Error(w, "Game not found", http.StatusNotFound)
+		return
+	}
+
 	// Upsert attendance
 	var attendance models.Attendance
-	if result := database.DB.Where("team_member_id = ? AND game_id = ?", teamMember.ID, gameID).First(&attendance); result.Error != nil {
+	found := database.DB.Where("team_member_id = ? AND game_id = ?", teamMember.ID, gameID).First(&attendance).Error == nil
+
+	// Players can't change their answer once a locking RSVP deadline has passed,
+	// but can still leave a note ("running late")
+	if (!found || attendance.Status != req.Status) && services.RSVPLocked(game, game.Team, time.Now()) {
+		http.Error(w, "The RSVP deadline for this game has passed. Ask a team admin to update your attendance.", http.StatusForbidden)
+		return
+	}
+
+	if !found {
 		// Create new attendance record
 		attendance = models.Attendance{
 			TeamMemberID: teamMember.ID,
 			GameID:       gameID,
 			Status:       req.Status,
+			Note:         note,
 			UpdatedAt:    time.Now(),
 		}
 		if result := database.DB.Create(&attendance); result.Error != nil {
@@ -194,10 +282,14 @@ func UpdateAttendance(w http.ResponseWriter, r *http.Request) {
 		}
 	} else {
 		// Update existing attendance
-		if result := database.DB.Model(&attendance).Updates(map[string]interface{}{
+		updates := map[string]interface{}{
 			"status":     req.Status,
 			"updated_at": time.Now(),
-		}); result.Error != nil {
+		}
+		if req.Note != nil {
+			updates["note"] = note
+		}
+		if result := database.DB.Model(&attendance).Updates(updates); result.Error != nil {
 			http.Error(w, result.Error.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -225,6 +317,7 @@ func GetBattingOrder(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, result.Error.Error(), http.StatusInternalServerError)
 		return
 	}
+	setBattingOrderNotes(gameID, battingOrder, minorityPool)
 
 	json.NewEncoder(w).Encode(BattingOrderResponse{
 		BattingOrder: battingOrder,
@@ -244,6 +337,7 @@ func GetFieldingLineup(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, result.Error.Error(), http.StatusInternalServerError)
 		return
 	}
+	setFieldingNotes(gameID, fieldingLineup)
 
 	json.NewEncoder(w).Encode(fieldingLineup)
 }
@@ -254,6 +348,9 @@ type UpdateGameRequest struct {
 	Location     string `json:"location,omitempty"`
 	OpposingTeam string `json:"opposingTeam,omitempty"`
 	IsHome       *bool  `json:"isHome,omitempty"`
+	VenueID      *string `json:"venueId,omitempty"` // "" clears the venue
+	OpponentTeamID *string `json:"opponentTeamId,omitempty"` // "" unlinks the opponent
+	RSVPDeadline   *string `json:"rsvpDeadline,omitempty"`   // RFC 3339; "" falls back to the team default
 }
 
 func UpdateGame(w http.ResponseWriter, r *http.Request) {
@@ -301,15 +398,88 @@ func UpdateGame(w http.ResponseWriter, r *http.Request) {
 	if req.IsHome != nil {
 		updates["is_home"] = *req.IsHome
 	}
+	if req.VenueID != nil {
+		if *req.VenueID == "" {
+			updates["venue_id"] = nil
+		} else {
+			venueID, err := uuid.Parse(*req.VenueID)
+			if err != nil {
+				http.Error(w, "Invalid venue ID", http.StatusBadRequest)
+				return
+			}
+			venue, err := findTeamVenue(database.DB, teamID, venueID)
+			if err != nil {
+				http.Error(w, "Venue not found", http.StatusBadRequest)
+				return
+			}
+			updates["venue_id"] = venue.ID
+			if req.Location == "" {
+				updates["location"] = venue.Name
+			}
+		}
+	}
+	if req.OpponentTeamID != nil {
+		if *req.OpponentTeamID == "" {
+			updates["opponent_team_id"] = nil
+		} else {
+			opponentID, err := uuid.Parse(*req.OpponentTeamID)
+			if err != nil {
+				http.Error(w, "Invalid opponent team ID", http.StatusBadRequest)
+				return
+			}
+			opponent, err := findOpponentTeam(teamID, opponentID)
+			if err != nil {
+				http.Error(w, "Opponent team not found", http.StatusBadRequest)
+				return
+			}
+			updates["opponent_team_id"] = opponent.ID
+			if req.OpposingTeam == "" {
+				updates["opposing_team"] = opponent.Name
+			}
+		}
+	}
+	if req.RSVPDeadline != nil {
+		if *req.RSVPDeadline == "" {
+			updates["rsvp_deadline"] = nil
+		} else {
+			deadline, err := time.Parse(time.RFC3339, *req.RSVPDeadline)
+			if err != nil {
+				http.Error(w, "Invalid RSVP deadline. Use RFC 3339, e.g. 2025-06-01T18:00:00-07:00", http.StatusBadRequest)
+				return
+			}
+			updates["rsvp_deadline"] = deadline
+		}
+	}
+	reopenRSVPs(updates)
 
 	if result := database.DB.Model(&game).Updates(updates); result.Error != nil {
 		http.Error(w, "Failed to update game", http.StatusInternalServerError)
 		return
 	}
 
+	// A game moved into someone's blackout marks them not_going
+	if date, ok := updates["date"].(time.Time); ok {
+		game.Date = date
+		if err := applyBlackoutsToGame(database.DB, game); err != nil {
+			log.Printf("Failed to apply blackout dates to game %s: %v", game.ID, err)
+		}
+	}
+	scheduleGameJobs(game.ID)
+
 	json.NewEncoder(w).Encode(game)
 }
 
+// reopenRSVPs clears a game's RSVP resolution when the updates move it or
+// its deadline, so it is resolved again when the new deadline passes.
+func reopenRSVPs(updates map[string]interface{}) {
+	for _, column := range []string{"date", "time", "rsvp_deadline"} {
+		if _, ok := updates[column]; ok {
+			updates["rsvp_resolved_at"] = nil
+			return
+		}
+	}
+}
+
 func DeleteGame(w http.ResponseWriter, r *http.Request) {
 	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
 	if err != nil {
@@ -336,6 +506,365 @@ func DeleteGame(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// findOpponentTeam loads an active team that can be linked as an opponent.
+func findOpponentTeam(teamID, opponentID uuid.UUID) (*models.Team, error) {
+	if opponentID == teamID {
+		return nil, errors.New("a team cannot play itself")
+	}
+	var opponent models.Team
+	if err := database.DB.Where("id = ? AND status = ?", opponentID, "active").First(&opponent).Error; err != nil {
+		return nil, err
+	}
+	return &opponent, nil
+}
+
+// setRSVPDeadlines fills in each game's effective RSVP deadline from the
+// game's own deadline or the team default.
+func setRSVPDeadlines(teamID uuid.UUID, games []models.Game) {
+	var team models.Team
+	if err := database.DB.First(&team, "id = ?", teamID).Error; err != nil {
+		return
+	}
+	for i := range games {
+		games[i].RSVPDeadlineAt = services.RSVPDeadline(games[i], team)
+	}
+}
+
+// scheduleGameJobs schedules the game's reminders and deadline checks. A
+// failure doesn't fail the request; the daily reconcile picks the game up.
+func scheduleGameJobs(gameID uuid.UUID) {
+	if err := services.ScheduleGameJobs(gameID); err != nil {
+		log.Printf("Warning: Failed to schedule jobs for game %s: %v", gameID, err)
+	}
+}
+
+// scheduleTeamGames reschedules the team's upcoming games in the background,
+// after a change to its reminder rules or RSVP deadline.
+func scheduleTeamGames(teamID uuid.UUID) {
+	go func() {
+		if err := services.ScheduleTeamGames(teamID); err != nil {
+			log.Printf("Warning: Failed to schedule jobs for team %s: %v", teamID, err)
+		}
+	}()
+}
+
+// scheduleLeagueGames schedules the league's upcoming games in the background,
+// since a new schedule or bracket can add many at once.
+func scheduleLeagueGames(leagueID uuid.UUID) {
+	go func() {
+		if err := services.ScheduleLeagueGames(leagueID); err != nil {
+			log.Printf("Warning: Failed to schedule jobs for league %s: %v", leagueID, err)
+		}
+	}()
+}
+
+// initializeAttendance creates a "maybe" attendance record for every active
+// team member who doesn't already have one for the game, or "not_going" for
+// members with a blackout covering the game date.
+func initializeAttendance(tx *gorm.DB, teamID, gameID uuid.UUID) error {
+	var teamMembers []models.TeamMember
+	if err := tx.Where("team_id = ? AND is_active = ?", teamID, true).Find(&teamMembers).Error; err != nil {
+		return err
+	}
+
+	var game models.Game
+	if err := tx.Select("date").First(&game, "id = ?", gameID).Error; err != nil {
+		return err
+	}
+	blackedOut, err := blackedOutMembers(tx, teamID, game.Date)
+	if err != nil {
+		return err
+	}
+
+	var existingIDs []uuid.UUID
+	if err := tx.Model(&models.Attendance{}).Where("game_id = ?", gameID).Pluck("team_member_id", &existingIDs).Error; err != nil {
+		return err
+	}
+	existing := make(map[uuid.UUID]bool)
+	for _, id := range existingIDs {
+		existing[id] = true
+	}
+
+	for _, teamMember := range teamMembers {
+		if existing[teamMember.ID] {
+			continue
+		}
+		status := "maybe"
+		if blackedOut[teamMember.ID] {
+			status = "not_going"
+		}
+		attendance := models.Attendance{
+			TeamMemberID: teamMember.ID,
+			GameID:       gameID,
+			Status:       status,
+			UpdatedAt:    time.Now(),
+		}
+		if err := tx.Create(&attendance).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type RescheduleGameRequest struct {
+	Date       string     `json:"date,omitempty"`    // YYYY-MM-DD; omit to postpone without a new date yet
+	Time       string     `json:"time,omitempty"`    // HH:MM; defaults to the original time
+	VenueID    *uuid.UUID `json:"venueId,omitempty"` // defaults to the original venue
+	Reason     string     `json:"reason"`
+	Attendance string     `json:"attendance"` // "carry_over" (default) or "reset"
+	Notify     *bool      `json:"notify,omitempty"` // defaults to true
+}
+
+type RescheduleGameResponse struct {
+	Original    models.Game  `json:"original"`
+	Replacement *models.Game `json:"replacement,omitempty"`
+}
+
+// RescheduleGame postpones a game and, when a new date is given, creates the
+// make-up game. The original game keeps its attendance, lineups and scores so
+// nothing is lost. A game postponed without a date can be rescheduled later by
+// calling this endpoint again with a date. A league or tournament game is
+// moved for both teams, and the make-up games keep its league, matchup and
+// tournament.
+func RescheduleGame(w http.ResponseWriter, r *http.Request) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+		return
+	}
+	gameID, err := uuid.Parse(chi.URLParam(r, "gameID"))
+	if err != nil {
+		http.Error(w, "Invalid game ID", http.StatusBadRequest)
+		return
+	}
+
+	var req RescheduleGameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Attendance == "" {
+		req.Attendance = "carry_over"
+	}
+	if req.Attendance != "carry_over" && req.Attendance != "reset" {
+		http.Error(w, "Invalid attendance option. Must be 'carry_over' or 'reset'", http.StatusBadRequest)
+		return
+	}
+
+	var newDate time.Time
+	if req.Date != "" {
+		if newDate, err = time.Parse("2006-01-02", req.Date); err != nil {
+			http.Error(w, "Invalid date format. Use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var original models.Game
+	if result := database.DB.Where("id = ? AND team_id = ?", gameID, teamID).First(&original); result.Error != nil {
+		http.Error(w, "Game not found", http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case original.Status == "completed":
+		http.Error(w, "Completed games cannot be postponed", http.StatusConflict)
+		return
+	case original.RescheduledToID != nil:
+		http.Error(w, "Game has already been rescheduled", http.StatusConflict)
+		return
+	case original.Status == "postponed" && req.Date == "":
+		http.Error(w, "Game is already postponed. Provide a date to reschedule it", http.StatusConflict)
+		return
+	}
+
+	// A league or tournament game is moved for both teams, so the two rows
+	// stay one matchup
+	var games, replacements []models.Game
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		games = []models.Game{original}
+		if original.MatchupID != nil {
+			var others []models.Game
+			if err := tx.Where("matchup_id = ? AND id <> ?", *original.MatchupID, original.ID).Find(&others).Error; err != nil {
+				return err
+			}
+			games = append(games, others...)
+		}
+
+		var venue *models.Venue
+		if req.Date != "" && req.VenueID != nil {
+			venue, err = findTeamVenue(tx, teamID, *req.VenueID)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errVenueNotFound
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		var matchupID *uuid.UUID
+		if req.Date != "" && original.MatchupID != nil {
+			id := uuid.New()
+			matchupID = &id
+		}
+
+		for i := range games {
+			game, err := postponeGame(tx, &games[i], req, newDate, venue, matchupID)
+			if err != nil {
+				return err
+			}
+			if game != nil {
+				replacements = append(replacements, *game)
+			}
+		}
+
+		if matchupID == nil || original.TournamentID == nil {
+			return nil
+		}
+		made := replacements[0]
+		return tx.Model(&models.TournamentGame{}).Where("matchup_id = ?", *original.MatchupID).Updates(map[string]interface{}{
+			"matchup_id": *matchupID,
+			"date":       made.Date,
+			"time":       made.Time,
+			"venue_id":   made.VenueID,
+		}).Error
+	})
+
+	if errors.Is(err, errVenueNotFound) {
+		http.Error(w, "Venue not found", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to reschedule game", http.StatusInternalServerError)
+		return
+	}
+	original = games[0]
+	var replacement *models.Game
+	if len(replacements) > 0 {
+		replacement = &replacements[0]
+	}
+	for _, game := range replacements {
+		scheduleGameJobs(game.ID)
+	}
+
+	if req.Notify == nil || *req.Notify {
+		for _, game := range games {
+			go notifyGameRescheduled(game.ID, game.RescheduledToID, req.Reason)
+		}
+	}
+
+	json.NewEncoder(w).Encode(RescheduleGameResponse{
+		Original:    original,
+		Replacement: replacement,
+	})
+}
+
+// postponeGame marks one team's game postponed and, when the request has a
+// date, creates its make-up game at the venue (or the game's own), linked to
+// the other team's make-up game by matchupID. It returns the make-up game, if
+// any.
+func postponeGame(tx *gorm.DB, original *models.Game, req RescheduleGameRequest, newDate time.Time, venue *models.Venue, matchupID *uuid.UUID) (*models.Game, error) {
+	now := time.Now()
+	original.Status = "postponed"
+	if original.PostponedAt == nil {
+		original.PostponedAt = &now
+	}
+	if req.Reason != "" {
+		original.PostponedReason = req.Reason
+	}
+	if req.Date == "" {
+		return nil, tx.Save(original).Error
+	}
+
+	game := models.Game{
+		TeamID:            original.TeamID,
+		Date:              newDate,
+		Time:              original.Time,
+		Location:          original.Location,
+		OpposingTeam:      original.OpposingTeam,
+		OpponentTeamID:    original.OpponentTeamID,
+		LeagueID:          original.LeagueID,
+		MatchupID:         matchupID,
+		TournamentID:      original.TournamentID,
+		IsHome:            original.IsHome,
+		VenueID:           original.VenueID,
+		Status:            "scheduled",
+		RescheduledFromID: &original.ID,
+	}
+	if req.Time != "" {
+		game.Time = req.Time
+	}
+	if venue != nil {
+		game.VenueID = &venue.ID
+		game.Location = venue.Name
+	}
+
+	if err := tx.Create(&game).Error; err != nil {
+		return nil, err
+	}
+
+	if req.Attendance == "carry_over" {
+		// Only roster players carry over: spares agreed to the old date, not
+		// the new one. A blackout on the new date overrides the old answer.
+		var previous []models.Attendance
+		if err := tx.Joins("JOIN team_members ON team_members.id = attendances.team_member_id").
+			Where("attendances.game_id = ? AND team_members.is_active = ? AND team_members.is_spare = ?", original.ID, true, false).
+			Find(&previous).Error; err != nil {
+			return nil, err
+		}
+		blackedOut, err := blackedOutMembers(tx, game.TeamID, game.Date)
+		if err != nil {
+			return nil, err
+		}
+		for _, att := range previous {
+			carried := models.Attendance{
+				TeamMemberID: att.TeamMemberID,
+				GameID:       game.ID,
+				Status:       att.Status,
+				UpdatedAt:    now,
+			}
+			if blackedOut[att.TeamMemberID] {
+				carried.Status = "not_going"
+			}
+			if err := tx.Create(&carried).Error; err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Fill in anyone without a carried-over record (or everyone, on reset)
+	if err := initializeAttendance(tx, game.TeamID, game.ID); err != nil {
+		return nil, err
+	}
+
+	original.RescheduledToID = &game.ID
+	if err := tx.Save(original).Error; err != nil {
+		return nil, err
+	}
+	return &game, nil
+}
+
+// notifyGameRescheduled loads the games with their venues and sends the
+// postponement notice. Runs in the background since emails are throttled.
+func notifyGameRescheduled(originalID uuid.UUID, replacementID *uuid.UUID, reason string) {
+	var original models.Game
+	if err := database.DB.Preload("Venue").First(&original, "id = ?", originalID).Error; err != nil {
+		log.Printf("Warning: Failed to load postponed game %s: %v", originalID, err)
+		return
+	}
+
+	var replacement *models.Game
+	if replacementID != nil {
+		replacement = &models.Game{}
+		if err := database.DB.Preload("Venue").First(replacement, "id = ?", *replacementID).Error; err != nil {
+			log.Printf("Warning: Failed to load make-up game %s: %v", *replacementID, err)
+			return
+		}
+	}
+
+	services.NotifyGameRescheduled(notifications, original, replacement, reason)
+}
+
 type UpdateScoreRequest struct {
 	FinalScore    int `json:"finalScore"`
 	OpponentScore int `json:"opponentScore"`
@@ -365,19 +894,64 @@ func UpdateGameScore(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Saving the same score again (e.g. a double submit) shouldn't notify twice
+	changed := game.FinalScore == nil || *game.FinalScore != req.FinalScore ||
+		game.OpponentScore == nil || *game.OpponentScore != req.OpponentScore
+
 	updates := map[string]interface{}{
 		"final_score":    req.FinalScore,
 		"opponent_score": req.OpponentScore,
 	}
 
-	if result := database.DB.Model(&game).Updates(updates); result.Error != nil {
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&game).Updates(updates).Error; err != nil {
+			return err
+		}
+		// League games are stored once per team; keep the opponent's row in sync
+		if err := mirrorMatchupScore(tx, game, req.FinalScore, req.OpponentScore); err != nil {
+			return err
+		}
+		return recordTournamentResult(tx, game, req.FinalScore, req.OpponentScore)
+	})
+	if errors.Is(err, errTournamentTie) {
+		http.Error(w, "Tournament elimination games cannot end in a tie", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, errBracketAdvanced) {
+		http.Error(w, "This result has already advanced the tournament bracket and the winner can no longer be changed", http.StatusConflict)
+		return
+	}
+	if errors.Is(err, errTeamNotInLeague) {
+		http.Error(w, "A team advancing in the tournament is no longer in the league", http.StatusConflict)
+		return
+	}
+	if err != nil {
 		http.Error(w, "Failed to update score", http.StatusInternalServerError)
 		return
 	}
+	if game.TournamentID != nil && game.LeagueID != nil {
+		// The result may have put teams into their next bracket games
+		scheduleLeagueGames(*game.LeagueID)
+	}
+	if changed {
+		go notifyScoreUpdated(game.ID)
+	}
 
 	json.NewEncoder(w).Encode(game)
 }
 
+// notifyScoreUpdated reloads the game with its new score and tells both
+// teams. Runs in the background since emails are throttled.
+func notifyScoreUpdated(gameID uuid.UUID) {
+	var game models.Game
+	if err := database.DB.First(&game, "id = ?", gameID).Error; err != nil {
+		log.Printf("Warning: Failed to load scored game %s: %v", gameID, err)
+		return
+	}
+
+	services.NotifyScoreUpdated(notifications, game)
+}
+
 type InningScore struct {
 	Inning        int `json:"inning"`
 	TeamScore     int `json:"teamScore"`
@@ -391,6 +965,7 @@ type UpdateInningScoresRequest struct {
 type AdminUpdateAttendanceRequest struct {
 	TeamMemberID uuid.UUID `json:"teamMemberId"`
 	Status       string    `json:"status"` // "going", "not_going", "maybe"
+	Note         *string   `json:"note,omitempty"`
 }
 
 func AdminUpdateAttendance(w http.ResponseWriter, r *http.Request) {
@@ -418,10 +993,16 @@ func AdminUpdateAttendance(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid status. Must be 'going', 'not_going', or 'maybe'", http.StatusBadRequest)
 		return
 	}
+	note, ok := attendanceNoteUpdate(req.Note)
+	if !ok {
+		http.Error(w, fmt.Sprintf("Note must be %d characters or fewer", maxAttendanceNoteLength), http.StatusBadRequest)
+		return
+	}
 
 	// Verify the team member exists and belongs to this team
 	var teamMember models.TeamMember
-	if result := database.DB.Where("id = ? AND team_id = ? AND is_active = ?", req.TeamMemberID, teamID, true).First(&teamMember); result.Error != nil {
+	// Spares aren't active members but can still be marked for their games
+	if result := database.DB.Where("id = ? AND team_id = ? AND (is_active = ? OR is_spare = ?)", req.TeamMemberID, teamID, true, true).First(&teamMember); result.Error != nil {
 		http.Error(w, "Team member not found", http.StatusNotFound)
 		return
 	}
@@ -441,6 +1022,7 @@ func AdminUpdateAttendance(w http.ResponseWriter, r *http.Request) {
 			TeamMemberID: req.TeamMemberID,
 			GameID:       gameID,
 			Status:       req.Status,
+			Note:         note,
 			UpdatedAt:    time.Now(),
 		}
 		if result := database.DB.Create(&attendance); result.Error != nil {
@@ -449,10 +1031,14 @@ func AdminUpdateAttendance(w http.ResponseWriter, r *http.Request) {
 		}
 	} else {
 		// Update existing attendance
-		if result := database.DB.Model(&attendance).Updates(map[string]interface{}{
+		updates := map[string]interface{}{
 			"status":     req.Status,
 			"updated_at": time.Now(),
-		}); result.Error != nil {
+		}
+		if req.Note != nil {
+			updates["note"] = note
+		}
+		if result := database.DB.Model(&attendance).Updates(updates); result.Error != nil {
 			http.Error(w, result.Error.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -502,14 +1088,24 @@ func InitializeGameAttendance(w http.ResponseWriter, r *http.Request) {
 		existingMap[att.TeamMemberID] = true
 	}
 
+	blackedOut, err := blackedOutMembers(database.DB, teamID, game.Date)
+	if err != nil {
+		http.Error(w, "Failed to fetch blackout dates", http.StatusInternalServerError)
+		return
+	}
+
 	// Initialize missing attendance records
 	initializedCount := 0
 	for _, teamMember := range teamMembers {
 		if !existingMap[teamMember.ID] {
+			status := "maybe"
+			if blackedOut[teamMember.ID] {
+				status = "not_going"
+			}
 			attendance := models.Attendance{
 				TeamMemberID: teamMember.ID,
 				GameID:       gameID,
-				Status:       "maybe",
+				Status:       status,
 				UpdatedAt:    time.Now(),
 			}
 			if result := database.DB.Create(&attendance); result.Error != nil {
@@ -644,6 +1240,9 @@ func UpdateFieldingLineup(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Game not found", http.StatusNotFound)
 		return
 	}
+	if lineupFrozen(w, game) {
+		return
+	}
 
 	// Delete existing fielding lineup for this game
 	if result := database.DB.Where("game_id = ?", gameID).Delete(&models.FieldingLineup{}); result.Error != nil {
@@ -703,6 +1302,9 @@ func DeleteFieldingLineup(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Game not found", http.StatusNotFound)
 		return
 	}
+	if lineupFrozen(w, game) {
+		return
+	}
 
 	// Delete fielding lineup for this game
 	if result := database.DB.Where("game_id = ?", gameID).Delete(&models.FieldingLineup{}); result.Error != nil {
@@ -741,6 +1343,9 @@ func GenerateBattingOrder(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Game not found", http.StatusNotFound)
 		return
 	}
+	if lineupFrozen(w, game) {
+		return
+	}
 
 	// Call algorithm to generate batting order
 	generated, err := algorithms.GenerateBattingOrder(gameID)
@@ -774,6 +1379,7 @@ func GenerateBattingOrder(w http.ResponseWriter, r *http.Request) {
 
 		return nil
 	})
+	setBattingOrderNotes(gameID, generated.BattingOrder, generated.MinorityPool)
 
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(BattingOrderResponse{
@@ -806,6 +1412,9 @@ func UpdateBattingOrder(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Game not found", http.StatusNotFound)
 		return
 	}
+	if lineupFrozen(w, game) {
+		return
+	}
 
 	// Delete existing batting order and pool for this game
 	database.DB.Transaction(func(tx *gorm.DB) error {
@@ -861,6 +1470,9 @@ func DeleteBattingOrder(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Game not found", http.StatusNotFound)
 		return
 	}
+	if lineupFrozen(w, game) {
+		return
+	}
 
 	// Delete batting order for this game
 	if result := database.DB.Where("game_id = ?", gameID).Delete(&models.BattingOrder{}); result.Error != nil {
@@ -902,6 +1514,9 @@ func GenerateFieldingLineup(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Game not found", http.StatusNotFound)
 		return
 	}
+	if lineupFrozen(w, game) {
+		return
+	}
 
 	// Call algorithm to generate fielding lineup
 	fieldingLineup, err := algorithms.GenerateFieldingLineup(gameID, inning)
@@ -923,6 +1538,7 @@ func GenerateFieldingLineup(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
+	setFieldingNotes(gameID, fieldingLineup)
 
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(fieldingLineup)
@@ -946,6 +1562,9 @@ func GenerateCompleteFieldingLineup(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Game not found", http.StatusNotFound)
 		return
 	}
+	if lineupFrozen(w, game) {
+		return
+	}
 
 	// Call algorithm to generate complete fielding lineup
 	fieldingLineup, err := algorithms.GenerateCompleteFieldingLineup(gameID)
@@ -967,7 +1586,137 @@ func GenerateCompleteFieldingLineup(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
+	setFieldingNotes(gameID, fieldingLineup)
 
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(fieldingLineup)
 }
+
+// attendanceNotes maps each team member to the note left with their RSVP.
+func attendanceNotes(gameID uuid.UUID) map[uuid.UUID]string {
+	var attendance []models.Attendance
+	database.DB.Select("team_member_id", "note").Where("game_id = ? AND note <> ?", gameID, "").Find(&attendance)
+
+	notes := make(map[uuid.UUID]string, len(attendance))
+	for _, att := range attendance {
+		notes[att.TeamMemberID] = att.Note
+	}
+	return notes
+}
+
+// setBattingOrderNotes attaches RSVP notes so admins see them while building
+// the lineup.
+func setBattingOrderNotes(gameID uuid.UUID, battingOrder []models.BattingOrder, minorityPool []models.BattingOrderPool) {
+	notes := attendanceNotes(gameID)
+	for i := range battingOrder {
+		if battingOrder[i].TeamMemberID != nil {
+			battingOrder[i].AttendanceNote = notes[*battingOrder[i].TeamMemberID]
+		}
+	}
+	for i := range minorityPool {
+		minorityPool[i].AttendanceNote = notes[minorityPool[i].TeamMemberID]
+	}
+}
+
+func setFieldingNotes(gameID uuid.UUID, fieldingLineup []models.FieldingLineup) {
+	notes := attendanceNotes(gameID)
+	for i := range fieldingLineup {
+		fieldingLineup[i].AttendanceNote = notes[fieldingLineup[i].TeamMemberID]
+	}
+}
+
+// PublishLineup freezes the game's batting order and fielding grid and sends
+// each attending player their batting slot and positions, plus a summary to
+// the team's WhatsApp group. Publishing again re-sends the notifications.
+func PublishLineup(w http.ResponseWriter, r *http.Request) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+		return
+	}
+	gameID, err := uuid.Parse(chi.URLParam(r, "gameID"))
+	if err != nil {
+		http.Error(w, "Invalid game ID", http.StatusBadRequest)
+		return
+	}
+
+	var game models.Game
+	if result := database.DB.Where("id = ? AND team_id = ?", gameID, teamID).First(&game); result.Error != nil {
+		http.Error(w, "Game not found", http.StatusNotFound)
+		return
+	}
+
+	var battingCount int64
+	database.DB.Model(&models.BattingOrder{}).Where("game_id = ?", gameID).Count(&battingCount)
+	if battingCount == 0 {
+		http.Error(w, "Set a batting order before publishing the lineup", http.StatusBadRequest)
+		return
+	}
+
+	userID := r.Context().Value("userID").(uuid.UUID)
+	now := time.Now()
+	if result := database.DB.Model(&game).Updates(map[string]interface{}{
+		"lineup_published_at": now,
+		"lineup_published_by": userID,
+	}); result.Error != nil {
+		http.Error(w, "Failed to publish lineup", http.StatusInternalServerError)
+		return
+	}
+	game.LineupPublishedAt = &now
+	game.LineupPublishedBy = &userID
+
+	go notifyLineupPublished(game.ID)
+
+	json.NewEncoder(w).Encode(game)
+}
+
+// UnpublishLineup unfreezes the lineup so it can be edited again. Players are
+// not notified until it is published again.
+func UnpublishLineup(w http.ResponseWriter, r *http.Request) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+		return
+	}
+	gameID, err := uuid.Parse(chi.URLParam(r, "gameID"))
+	if err != nil {
+		http.Error(w, "Invalid game ID", http.StatusBadRequest)
+		return
+	}
+
+	var game models.Game
+	if result := database.DB.Where("id = ? AND team_id = ?", gameID, teamID).First(&game); result.Error != nil {
+		http.Error(w, "Game not found", http.StatusNotFound)
+		return
+	}
+
+	if result := database.DB.Model(&game).Updates(map[string]interface{}{
+		"lineup_published_at": nil,
+		"lineup_published_by": nil,
+	}); result.Error != nil {
+		http.Error(w, "Failed to unpublish lineup", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// lineupFrozen rejects lineup changes once the lineup has been published,
+// writing the error response.
+func lineupFrozen(w http.ResponseWriter, game models.Game) bool {
+	if game.LineupPublishedAt == nil {
+		return false
+	}
+	http.Error(w, "The lineup has been published. Unpublish it before making changes.", http.StatusConflict)
+	return true
+}
+
+func notifyLineupPublished(gameID uuid.UUID) {
+	var game models.Game
+	if err := database.DB.Preload("Venue").First(&game, "id = ?", gameID).Error; err != nil {
+		log.Printf("Warning: Failed to load game %s for lineup notifications: %v", gameID, err)
+		return
+	}
+
+	services.NotifyLineupPublished(notifications, game)
+}
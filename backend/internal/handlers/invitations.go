@@ -75,21 +75,16 @@ func InviteMember(w http.ResponseWriter, r *http.Request) {
 		println("Warning: Failed to fetch inviter details for email:", err.Error())
 	}
 
-	// Try to send the invitation email
-	emailService, err := services.NewEmailService()
-	if err != nil {
-		// Log error but don't fail the request - invitation was created successfully
-		println("Warning: Failed to initialize email service:", err.Error())
-	} else {
-		inviterName := inviter.Name
-		if inviterName == "" {
-			inviterName = inviter.Email
-		}
+	// Try to send the invitation
+	inviterName := inviter.Name
+	if inviterName == "" {
+		inviterName = inviter.Email
+	}
 
-		if err := emailService.SendInvitationEmail(req.Email, team.Name, inviterName, token); err != nil {
-			// Log error but don't fail the request
-			println("Warning: Failed to send invitation email:", err.Error())
-		}
+	invitee := services.Recipient{Email: req.Email, Team: &team}
+	if err := notifications.Notify(invitee, services.InvitationMessage(services.TeamMessageContext(team), team.Name, inviterName, token)); err != nil {
+		// Log error but don't fail the request - invitation was created successfully
+		println("Warning: Failed to send invitation:", err.Error())
 	}
 
 	w.WriteHeader(http.StatusCreated)
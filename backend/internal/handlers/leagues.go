@@ -0,0 +1,491 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/liam/screaming-toller/backend/internal/algorithms"
+	"github.com/liam/screaming-toller/backend/internal/database"
+	"github.com/liam/screaming-toller/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type LeagueRequest struct {
+	Name        string `json:"name"`
+	Season      string `json:"season"`
+	Description string `json:"description"`
+}
+
+// LeagueTeamSummary is the view of a team that other teams in the league can
+// see. It deliberately leaves out integration settings such as the WhatsApp group.
+type LeagueTeamSummary struct {
+	ID      uuid.UUID `json:"id"`
+	Name    string    `json:"name"`
+	LogoURL string    `json:"logoUrl"`
+	Season  string    `json:"season"`
+	Status  string    `json:"status"` // "invited" until a team admin accepts, then "active"
+}
+
+type LeagueAdminSummary struct {
+	UserID uuid.UUID `json:"userId"`
+	Name   string    `json:"name"`
+	Email  string    `json:"email"`
+}
+
+type LeagueResponse struct {
+	models.League
+	Teams   []LeagueTeamSummary  `json:"teams"`
+	Admins  []LeagueAdminSummary `json:"admins"`
+	IsAdmin bool                 `json:"isAdmin"`
+}
+
+func CreateLeague(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req LeagueRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		http.Error(w, "League name is required", http.StatusBadRequest)
+		return
+	}
+
+	league := models.League{
+		Name:        req.Name,
+		Season:      req.Season,
+		Description: req.Description,
+		CreatedBy:   userID,
+	}
+
+	// The creator becomes the first league admin
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&league).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.LeagueAdmin{LeagueID: league.ID, UserID: userID}).Error
+	})
+	if err != nil {
+		http.Error(w, "Failed to create league", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(league)
+}
+
+// GetLeagues lists leagues the user administers or plays in through one of their teams.
+func GetLeagues(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var leagues []models.League
+	err := database.DB.
+		Where("id IN (?)", database.DB.Model(&models.LeagueAdmin{}).Select("league_id").Where("user_id = ?", userID)).
+		Or("id IN (?)", database.DB.Model(&models.LeagueTeam{}).Select("league_teams.league_id").
+			Joins("JOIN team_members ON team_members.team_id = league_teams.team_id").
+			Where("league_teams.status = ? AND team_members.user_id = ? AND team_members.is_active = ?", "active", userID, true)).
+		Order("name asc").
+		Find(&leagues).Error
+	if err != nil {
+		http.Error(w, "Failed to fetch leagues", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(leagues)
+}
+
+func GetLeague(w http.ResponseWriter, r *http.Request) {
+	leagueID, err := uuid.Parse(chi.URLParam(r, "leagueID"))
+	if err != nil {
+		http.Error(w, "Invalid league ID", http.StatusBadRequest)
+		return
+	}
+
+	var league models.League
+	if err := database.DB.Preload("Teams.Team").Preload("Admins.User").First(&league, "id = ?", leagueID).Error; err != nil {
+		http.Error(w, "League not found", http.StatusNotFound)
+		return
+	}
+
+	isAdmin, _ := r.Context().Value("leagueAdmin").(bool)
+	json.NewEncoder(w).Encode(buildLeagueResponse(league, isAdmin))
+}
+
+func UpdateLeague(w http.ResponseWriter, r *http.Request) {
+	leagueID, err := uuid.Parse(chi.URLParam(r, "leagueID"))
+	if err != nil {
+		http.Error(w, "Invalid league ID", http.StatusBadRequest)
+		return
+	}
+
+	var league models.League
+	if err := database.DB.First(&league, "id = ?", leagueID).Error; err != nil {
+		http.Error(w, "League not found", http.StatusNotFound)
+		return
+	}
+
+	var req LeagueRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if name := strings.TrimSpace(req.Name); name != "" {
+		league.Name = name
+	}
+	league.Season = req.Season
+	league.Description = req.Description
+
+	if err := database.DB.Save(&league).Error; err != nil {
+		http.Error(w, "Failed to update league", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(league)
+}
+
+// AddLeagueTeam invites an active team to the league. The team only joins
+// once one of its team admins accepts (AcceptLeagueInvitation); until then it
+// is left out of standings, schedules and tournaments.
+func AddLeagueTeam(w http.ResponseWriter, r *http.Request) {
+	leagueID, err := uuid.Parse(chi.URLParam(r, "leagueID"))
+	if err != nil {
+		http.Error(w, "Invalid league ID", http.StatusBadRequest)
+		return
+	}
+	userID := r.Context().Value("userID").(uuid.UUID)
+
+	var req struct {
+		TeamID uuid.UUID `json:"teamId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var team models.Team
+	if err := database.DB.Where("id = ? AND status = ?", req.TeamID, "active").First(&team).Error; err != nil {
+		http.Error(w, "Team not found", http.StatusNotFound)
+		return
+	}
+
+	var existing models.LeagueTeam
+	if err := database.DB.Where("league_id = ? AND team_id = ?", leagueID, team.ID).First(&existing).Error; err == nil {
+		if existing.Status == "invited" {
+			http.Error(w, "Team has already been invited to this league", http.StatusConflict)
+		} else {
+			http.Error(w, "Team is already in this league", http.StatusConflict)
+		}
+		return
+	}
+
+	invitation := models.LeagueTeam{LeagueID: leagueID, TeamID: team.ID, Status: "invited", InvitedBy: &userID}
+	if err := database.DB.Create(&invitation).Error; err != nil {
+		http.Error(w, "Failed to invite team", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(LeagueTeamSummary{ID: team.ID, Name: team.Name, LogoURL: team.LogoURL, Season: team.Season, Status: invitation.Status})
+}
+
+// RemoveLeagueTeam takes a team out of the league, or withdraws its
+// invitation.
+func RemoveLeagueTeam(w http.ResponseWriter, r *http.Request) {
+	leagueID, err := uuid.Parse(chi.URLParam(r, "leagueID"))
+	if err != nil {
+		http.Error(w, "Invalid league ID", http.StatusBadRequest)
+		return
+	}
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+		return
+	}
+
+	result := database.DB.Where("league_id = ? AND team_id = ?", leagueID, teamID).Delete(&models.LeagueTeam{})
+	if result.Error != nil {
+		http.Error(w, "Failed to remove team", http.StatusInternalServerError)
+		return
+	}
+	if result.RowsAffected == 0 {
+		http.Error(w, "Team is not in this league", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LeagueInvitation is a league's pending invitation as the invited team's
+// admins see it.
+type LeagueInvitation struct {
+	LeagueID    uuid.UUID `json:"leagueId"`
+	Name        string    `json:"name"`
+	Season      string    `json:"season"`
+	Description string    `json:"description"`
+	InvitedBy   string    `json:"invitedBy"`
+}
+
+// GetTeamLeagueInvitations lists the leagues that have invited the team and
+// are waiting for a team admin to answer.
+func GetTeamLeagueInvitations(w http.ResponseWriter, r *http.Request) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+		return
+	}
+
+	var pending []models.LeagueTeam
+	if err := database.DB.Where("team_id = ? AND status = ?", teamID, "invited").Find(&pending).Error; err != nil {
+		http.Error(w, "Failed to fetch league invitations", http.StatusInternalServerError)
+		return
+	}
+
+	invitations := make([]LeagueInvitation, 0, len(pending))
+	for _, lt := range pending {
+		var league models.League
+		if err := database.DB.First(&league, "id = ?", lt.LeagueID).Error; err != nil {
+			continue
+		}
+		invitation := LeagueInvitation{LeagueID: league.ID, Name: league.Name, Season: league.Season, Description: league.Description}
+		if lt.InvitedBy != nil {
+			var inviter models.User
+			if database.DB.First(&inviter, "id = ?", *lt.InvitedBy).Error == nil {
+				invitation.InvitedBy = inviter.Name
+			}
+		}
+		invitations = append(invitations, invitation)
+	}
+
+	json.NewEncoder(w).Encode(invitations)
+}
+
+// AcceptLeagueInvitation joins the team to a league that invited it.
+func AcceptLeagueInvitation(w http.ResponseWriter, r *http.Request) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+		return
+	}
+	leagueID, err := uuid.Parse(chi.URLParam(r, "leagueID"))
+	if err != nil {
+		http.Error(w, "Invalid league ID", http.StatusBadRequest)
+		return
+	}
+
+	var league models.League
+	if err := database.DB.First(&league, "id = ?", leagueID).Error; err != nil {
+		http.Error(w, "League not found", http.StatusNotFound)
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.LeagueTeam{}).
+			Where("league_id = ? AND team_id = ? AND status = ?", leagueID, teamID, "invited").
+			Updates(map[string]interface{}{"status": "active", "joined_at": time.Now()})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		// Keep the legacy display name in sync
+		return tx.Model(&models.Team{}).Where("id = ?", teamID).Update("league", league.Name).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, "Invitation not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to join league", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeclineLeagueInvitation turns down a league's invitation.
+func DeclineLeagueInvitation(w http.ResponseWriter, r *http.Request) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+		return
+	}
+	leagueID, err := uuid.Parse(chi.URLParam(r, "leagueID"))
+	if err != nil {
+		http.Error(w, "Invalid league ID", http.StatusBadRequest)
+		return
+	}
+
+	result := database.DB.Where("league_id = ? AND team_id = ? AND status = ?", leagueID, teamID, "invited").Delete(&models.LeagueTeam{})
+	if result.Error != nil {
+		http.Error(w, "Failed to decline invitation", http.StatusInternalServerError)
+		return
+	}
+	if result.RowsAffected == 0 {
+		http.Error(w, "Invitation not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func AddLeagueAdmin(w http.ResponseWriter, r *http.Request) {
+	leagueID, err := uuid.Parse(chi.URLParam(r, "leagueID"))
+	if err != nil {
+		http.Error(w, "Invalid league ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var user models.User
+	if err := database.DB.Where("LOWER(email) = LOWER(?)", strings.TrimSpace(req.Email)).First(&user).Error; err != nil {
+		http.Error(w, "No user with that email has signed up yet", http.StatusNotFound)
+		return
+	}
+
+	var existing int64
+	database.DB.Model(&models.LeagueAdmin{}).Where("league_id = ? AND user_id = ?", leagueID, user.ID).Count(&existing)
+	if existing == 0 {
+		if err := database.DB.Create(&models.LeagueAdmin{LeagueID: leagueID, UserID: user.ID}).Error; err != nil {
+			http.Error(w, "Failed to add admin", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(LeagueAdminSummary{UserID: user.ID, Name: user.Name, Email: user.Email})
+}
+
+func RemoveLeagueAdmin(w http.ResponseWriter, r *http.Request) {
+	leagueID, err := uuid.Parse(chi.URLParam(r, "leagueID"))
+	if err != nil {
+		http.Error(w, "Invalid league ID", http.StatusBadRequest)
+		return
+	}
+	userID, err := uuid.Parse(chi.URLParam(r, "userID"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	// A league must always have at least one admin
+	var count int64
+	database.DB.Model(&models.LeagueAdmin{}).Where("league_id = ?", leagueID).Count(&count)
+	if count <= 1 {
+		http.Error(w, "Cannot remove the last league admin", http.StatusConflict)
+		return
+	}
+
+	if err := database.DB.Where("league_id = ? AND user_id = ?", leagueID, userID).Delete(&models.LeagueAdmin{}).Error; err != nil {
+		http.Error(w, "Failed to remove admin", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetLeagueStandings computes W/L/T, runs and streaks for every team in the
+// league from its scored league games. Only games created by the league
+// schedule count: both sides of those share a MatchupID and a mirrored score,
+// so the two teams' tables agree. Games a team linked to an opponent by hand
+// and tournament games are excluded.
+func GetLeagueStandings(w http.ResponseWriter, r *http.Request) {
+	leagueID, err := uuid.Parse(chi.URLParam(r, "leagueID"))
+	if err != nil {
+		http.Error(w, "Invalid league ID", http.StatusBadRequest)
+		return
+	}
+
+	teams, err := leagueTeams(leagueID)
+	if err != nil {
+		http.Error(w, "Failed to fetch league teams", http.StatusInternalServerError)
+		return
+	}
+	if len(teams) == 0 {
+		json.NewEncoder(w).Encode([]algorithms.StandingsRow{})
+		return
+	}
+
+	teamIDs := make([]uuid.UUID, len(teams))
+	for i, team := range teams {
+		teamIDs[i] = team.ID
+	}
+
+	var games []models.Game
+	if err := database.DB.
+		Where("team_id IN ? AND final_score IS NOT NULL AND opponent_score IS NOT NULL", teamIDs).
+		Where("league_id = ? AND matchup_id IS NOT NULL AND opponent_team_id IN ?", leagueID, teamIDs).
+		Where("tournament_id IS NULL").
+		Find(&games).Error; err != nil {
+		http.Error(w, "Failed to fetch games", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(algorithms.ComputeStandings(teams, games))
+}
+
+// leagueTeams returns the teams that have joined a league, ordered by name.
+// Invited teams that haven't accepted are left out.
+func leagueTeams(leagueID uuid.UUID) ([]models.Team, error) {
+	var teams []models.Team
+	err := database.DB.
+		Joins("JOIN league_teams ON league_teams.team_id = teams.id").
+		Where("league_teams.league_id = ? AND league_teams.status = ?", leagueID, "active").
+		Order("teams.name asc").
+		Find(&teams).Error
+	return teams, err
+}
+
+func buildLeagueResponse(league models.League, isAdmin bool) LeagueResponse {
+	response := LeagueResponse{
+		League:  league,
+		Teams:   make([]LeagueTeamSummary, 0, len(league.Teams)),
+		Admins:  make([]LeagueAdminSummary, 0, len(league.Admins)),
+		IsAdmin: isAdmin,
+	}
+	for _, lt := range league.Teams {
+		if lt.Status == "invited" && !isAdmin {
+			continue
+		}
+		response.Teams = append(response.Teams, LeagueTeamSummary{
+			ID:      lt.Team.ID,
+			Name:    lt.Team.Name,
+			LogoURL: lt.Team.LogoURL,
+			Season:  lt.Team.Season,
+			Status:  lt.Status,
+		})
+	}
+	for _, admin := range league.Admins {
+		response.Admins = append(response.Admins, LeagueAdminSummary{
+			UserID: admin.UserID,
+			Name:   admin.User.Name,
+			Email:  admin.User.Email,
+		})
+	}
+	response.League.Teams = nil
+	response.League.Admins = nil
+	return response
+}
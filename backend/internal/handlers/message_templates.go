@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/liam/screaming-toller/backend/internal/database"
+	"github.com/liam/screaming-toller/backend/internal/models"
+	"github.com/liam/screaming-toller/backend/internal/services"
+)
+
+type MessageTemplateRequest struct {
+	Name   string `json:"name"`
+	Locale string `json:"locale"`
+	Part   string `json:"part"`
+	Body   string `json:"body"`
+}
+
+// GetMessageTemplates lists the team's overrides of the built-in wording.
+func GetMessageTemplates(w http.ResponseWriter, r *http.Request) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+		return
+	}
+
+	var templates []models.MessageTemplate
+	if result := database.DB.Where("team_id = ?", teamID).Order("name, locale, part").Find(&templates); result.Error != nil {
+		http.Error(w, "Failed to fetch message templates", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(templates)
+}
+
+// GetDefaultMessageTemplates lists the built-in wording in the requested
+// locale (English by default), for editing into an override.
+func GetDefaultMessageTemplates(w http.ResponseWriter, r *http.Request) {
+	locale := r.URL.Query().Get("locale")
+	if locale == "" {
+		locale = services.DefaultLocale
+	}
+	if !services.SupportedLocale(locale) {
+		http.Error(w, "Unsupported language", http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(services.DefaultMessageTemplates(locale))
+}
+
+// SaveMessageTemplate sets the team's wording for one part of a message in
+// one locale, replacing any override it already had.
+func SaveMessageTemplate(w http.ResponseWriter, r *http.Request) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+		return
+	}
+
+	var req MessageTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	override := models.MessageTemplate{
+		TeamID: teamID,
+		Name:   req.Name,
+		Locale: req.Locale,
+		Part:   req.Part,
+		Body:   req.Body,
+	}
+	if err := services.ValidateMessageTemplate(override); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var existing models.MessageTemplate
+	result := database.DB.Where("team_id = ? AND name = ? AND locale = ? AND part = ?",
+		teamID, override.Name, override.Locale, override.Part).First(&existing)
+	if result.Error == nil {
+		existing.Body = override.Body
+		if err := database.DB.Save(&existing).Error; err != nil {
+			http.Error(w, "Failed to save message template", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(existing)
+		return
+	}
+
+	if err := database.DB.Create(&override).Error; err != nil {
+		http.Error(w, "Failed to save message template", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(override)
+}
+
+// DeleteMessageTemplate removes an override, putting the part back on the
+// built-in wording.
+func DeleteMessageTemplate(w http.ResponseWriter, r *http.Request) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+		return
+	}
+	templateID, err := uuid.Parse(chi.URLParam(r, "templateID"))
+	if err != nil {
+		http.Error(w, "Invalid message template ID", http.StatusBadRequest)
+		return
+	}
+
+	result := database.DB.Where("id = ? AND team_id = ?", templateID, teamID).Delete(&models.MessageTemplate{})
+	if result.Error != nil {
+		http.Error(w, "Failed to delete message template", http.StatusInternalServerError)
+		return
+	}
+	if result.RowsAffected == 0 {
+		http.Error(w, "Message template not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
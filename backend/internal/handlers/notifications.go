@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/liam/screaming-toller/backend/internal/database"
+	"github.com/liam/screaming-toller/backend/internal/models"
+	"github.com/liam/screaming-toller/backend/internal/services"
+	"gorm.io/gorm"
+)
+
+// notifications queues every notification the handlers send. It is built
+// once at startup and shared with the background workers.
+var notifications *services.NotificationService
+
+// SetNotificationService gives the handlers the notification service main
+// built at startup.
+func SetNotificationService(n *services.NotificationService) {
+	notifications = n
+}
+
+type NotificationPreferenceUpdate struct {
+	Type    string `json:"type"`
+	Channel string `json:"channel"`
+	Enabled bool   `json:"enabled"`
+}
+
+type UpdateNotificationPreferencesRequest struct {
+	Preferences []NotificationPreferenceUpdate `json:"preferences"`
+}
+
+type UpdateDigestRequest struct {
+	Enabled  *bool   `json:"enabled"`
+	Weekday  *int    `json:"weekday"`
+	Hour     *int    `json:"hour"`
+	Timezone *string `json:"timezone"`
+}
+
+// GetMyNotificationPreferences lists each notification type the user can
+// configure with the channels it is currently sent on.
+func GetMyNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	preferences, err := services.ChannelPreferences(userID)
+	if err != nil {
+		http.Error(w, "Failed to fetch notification preferences", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(preferences)
+}
+
+// UpdateMyNotificationPreferences turns channels on or off per notification
+// type. Types and channels not listed are left as they are.
+func UpdateMyNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req UpdateNotificationPreferencesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate everything before saving anything
+	for _, p := range req.Preferences {
+		t, ok := services.LookupNotificationType(p.Type)
+		if !ok || t.Transactional {
+			http.Error(w, "Unknown notification type: "+p.Type, http.StatusBadRequest)
+			return
+		}
+		if !validUserChannel(p.Channel) || !t.AllowsChannel(p.Channel) {
+			http.Error(w, "Unknown channel: "+p.Channel, http.StatusBadRequest)
+			return
+		}
+	}
+
+	for _, p := range req.Preferences {
+		if err := services.SetChannelPreference(userID, p.Type, p.Channel, p.Enabled); err != nil {
+			http.Error(w, "Failed to update notification preferences", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	GetMyNotificationPreferences(w, r)
+}
+
+// GetMyDigest returns the user's weekly digest settings.
+func GetMyDigest(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	subscription, err := services.UserDigestSubscription(userID)
+	if err != nil {
+		http.Error(w, "Failed to fetch digest settings", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(subscription)
+}
+
+// UpdateMyDigest turns the weekly digest on or off and sets when it is sent.
+// Fields not sent are left as they are.
+func UpdateMyDigest(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req UpdateDigestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	subscription, err := services.UserDigestSubscription(userID)
+	if err != nil {
+		http.Error(w, "Failed to fetch digest settings", http.StatusInternalServerError)
+		return
+	}
+	if req.Enabled != nil {
+		subscription.Enabled = *req.Enabled
+	}
+	if req.Weekday != nil {
+		subscription.Weekday = *req.Weekday
+	}
+	if req.Hour != nil {
+		subscription.Hour = *req.Hour
+	}
+	if req.Timezone != nil {
+		subscription.Timezone = *req.Timezone
+	}
+	if err := services.ValidateDigestSubscription(subscription); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if result := database.DB.Save(&subscription); result.Error != nil {
+		http.Error(w, "Failed to update digest settings", http.StatusInternalServerError)
+		return
+	}
+	if err := services.ScheduleDigest(subscription); err != nil {
+		http.Error(w, "Failed to schedule digest", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(subscription)
+}
+
+func validUserChannel(channel string) bool {
+	for _, c := range services.UserChannels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// GetGameNotifications lists the notifications sent for a game with their
+// delivery status, so admins can see who wasn't reached. ?status=failed
+// narrows the list.
+func GetGameNotifications(w http.ResponseWriter, r *http.Request) {
+	game, ok := teamGame(w, r)
+	if !ok {
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	if status != "" && status != "pending" && status != "sent" && status != "failed" && status != "skipped" {
+		http.Error(w, "Invalid status", http.StatusBadRequest)
+		return
+	}
+
+	messages, err := services.GameDeliveries(game.ID, status)
+	if err != nil {
+		http.Error(w, "Failed to fetch notifications", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(messages)
+}
+
+// RetryGameNotification queues a failed or skipped notification again.
+func RetryGameNotification(w http.ResponseWriter, r *http.Request) {
+	game, ok := teamGame(w, r)
+	if !ok {
+		return
+	}
+
+	notificationID, err := uuid.Parse(chi.URLParam(r, "notificationID"))
+	if err != nil {
+		http.Error(w, "Invalid notification ID", http.StatusBadRequest)
+		return
+	}
+
+	err = services.RetryDelivery(game.ID, notificationID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, "Failed notification not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to retry notification", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// teamGame loads the game in the URL, checking it belongs to the team,
+// writing the error response if not.
+func teamGame(w http.ResponseWriter, r *http.Request) (*models.Game, bool) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+		return nil, false
+	}
+	gameID, err := uuid.Parse(chi.URLParam(r, "gameID"))
+	if err != nil {
+		http.Error(w, "Invalid game ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	var game models.Game
+	if result := database.DB.Where("id = ? AND team_id = ?", gameID, teamID).First(&game); result.Error != nil {
+		http.Error(w, "Game not found", http.StatusNotFound)
+		return nil, false
+	}
+	return &game, true
+}
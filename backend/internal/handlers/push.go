@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/liam/screaming-toller/backend/internal/database"
+	"github.com/liam/screaming-toller/backend/internal/models"
+	"github.com/liam/screaming-toller/backend/internal/services"
+	"gorm.io/gorm/clause"
+)
+
+// PushSubscriptionRequest is the browser's PushSubscription as returned by
+// subscription.toJSON(), plus a label for the device.
+type PushSubscriptionRequest struct {
+	Endpoint string `json:"endpoint"`
+	Keys     struct {
+		P256dh string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys"`
+	Device string `json:"device"`
+}
+
+type PushKeyResponse struct {
+	PublicKey string `json:"publicKey"`
+}
+
+// GetPushKey returns the VAPID public key the app subscribes with
+// (applicationServerKey).
+func GetPushKey(w http.ResponseWriter, r *http.Request) {
+	sender, err := services.NewWebPushSender()
+	if err != nil {
+		log.Printf("Warning: Push not configured: %v", err)
+	}
+	if sender == nil {
+		http.Error(w, "Push notifications are not configured", http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(PushKeyResponse{PublicKey: sender.PublicKey()})
+}
+
+// GetMyPushSubscriptions lists the devices the current user gets push
+// notifications on.
+func GetMyPushSubscriptions(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uuid.UUID)
+
+	var subscriptions []models.PushSubscription
+	if result := database.DB.Where("user_id = ?", userID).Order("created_at asc").Find(&subscriptions); result.Error != nil {
+		http.Error(w, "Failed to fetch push subscriptions", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(subscriptions)
+}
+
+// CreateMyPushSubscription registers a device for push notifications. A
+// browser that subscribes again, possibly after another user logged in on
+// it, keeps one subscription that now belongs to the current user.
+func CreateMyPushSubscription(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uuid.UUID)
+
+	var req PushSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	endpoint, err := url.Parse(req.Endpoint)
+	if err != nil || endpoint.Scheme != "https" || endpoint.Host == "" {
+		http.Error(w, "Endpoint must be an https URL", http.StatusBadRequest)
+		return
+	}
+	if req.Keys.P256dh == "" || req.Keys.Auth == "" {
+		http.Error(w, "Keys p256dh and auth are required", http.StatusBadRequest)
+		return
+	}
+
+	subscription := models.PushSubscription{
+		UserID:   userID,
+		Endpoint: req.Endpoint,
+		P256dh:   req.Keys.P256dh,
+		Auth:     req.Keys.Auth,
+		Device:   strings.TrimSpace(req.Device),
+	}
+	result := database.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "endpoint"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"user_id":    userID,
+			"p256dh":     subscription.P256dh,
+			"auth":       subscription.Auth,
+			"device":     subscription.Device,
+			"updated_at": time.Now(),
+		}),
+	}).Create(&subscription)
+	if result.Error != nil {
+		http.Error(w, "Failed to save push subscription", http.StatusInternalServerError)
+		return
+	}
+
+	// On conflict the row keeps its original ID, so read it back
+	database.DB.Where("endpoint = ?", req.Endpoint).First(&subscription)
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(subscription)
+}
+
+// DeleteMyPushSubscription stops push notifications to one of the current
+// user's devices, e.g. when they log out or turn notifications off.
+func DeleteMyPushSubscription(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uuid.UUID)
+
+	subscriptionID, err := uuid.Parse(chi.URLParam(r, "subscriptionID"))
+	if err != nil {
+		http.Error(w, "Invalid subscription ID", http.StatusBadRequest)
+		return
+	}
+
+	result := database.DB.Where("id = ? AND user_id = ?", subscriptionID, userID).Delete(&models.PushSubscription{})
+	if result.Error != nil {
+		http.Error(w, "Failed to delete push subscription", http.StatusInternalServerError)
+		return
+	}
+	if result.RowsAffected == 0 {
+		http.Error(w, "Push subscription not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/liam/screaming-toller/backend/internal/database"
+	"github.com/liam/screaming-toller/backend/internal/models"
+	"github.com/liam/screaming-toller/backend/internal/services"
+)
+
+type ReminderRuleRequest struct {
+	OffsetHours int      `json:"offsetHours"`
+	Statuses    []string `json:"statuses"`
+	Channels    []string `json:"channels"`
+}
+
+type ReminderRulesResponse struct {
+	Rules        []models.ReminderRule `json:"rules"`
+	UsingDefault bool                  `json:"usingDefault"` // The team has no rules of its own
+}
+
+// GetReminderRules lists the team's reminder rules, or the default rule when
+// it hasn't set any up.
+func GetReminderRules(w http.ResponseWriter, r *http.Request) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+		return
+	}
+
+	rules, err := services.TeamReminderRules(teamID)
+	if err != nil {
+		http.Error(w, "Failed to fetch reminder rules", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(ReminderRulesResponse{
+		Rules:        rules,
+		UsingDefault: len(rules) == 1 && rules[0].ID == uuid.Nil,
+	})
+}
+
+// CreateReminderRule adds a reminder rule. The team's first rule replaces the
+// default one.
+func CreateReminderRule(w http.ResponseWriter, r *http.Request) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+		return
+	}
+
+	var req ReminderRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	rule := models.ReminderRule{
+		TeamID:      teamID,
+		OffsetHours: req.OffsetHours,
+		Statuses:    req.Statuses,
+		Channels:    req.Channels,
+	}
+	if err := services.ValidateReminderRule(&rule); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if result := database.DB.Create(&rule); result.Error != nil {
+		http.Error(w, "Failed to create reminder rule", http.StatusInternalServerError)
+		return
+	}
+	scheduleTeamGames(teamID)
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rule)
+}
+
+// UpdateReminderRule replaces a rule's offset, statuses and channels. Players
+// it has already reminded for a game aren't reminded again by it.
+func UpdateReminderRule(w http.ResponseWriter, r *http.Request) {
+	rule, ok := teamReminderRule(w, r)
+	if !ok {
+		return
+	}
+
+	var req ReminderRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	rule.OffsetHours = req.OffsetHours
+	rule.Statuses = req.Statuses
+	rule.Channels = req.Channels
+	if err := services.ValidateReminderRule(rule); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if result := database.DB.Save(rule); result.Error != nil {
+		http.Error(w, "Failed to update reminder rule", http.StatusInternalServerError)
+		return
+	}
+	scheduleTeamGames(rule.TeamID)
+
+	json.NewEncoder(w).Encode(rule)
+}
+
+// DeleteReminderRule removes a rule. Deleting the last one puts the team back
+// on the default rule.
+func DeleteReminderRule(w http.ResponseWriter, r *http.Request) {
+	rule, ok := teamReminderRule(w, r)
+	if !ok {
+		return
+	}
+
+	if result := database.DB.Delete(rule); result.Error != nil {
+		http.Error(w, "Failed to delete reminder rule", http.StatusInternalServerError)
+		return
+	}
+	database.DB.Where("rule_key = ?", rule.ID.String()).Delete(&models.ReminderSend{})
+	scheduleTeamGames(rule.TeamID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// teamReminderRule loads the rule in the URL, checking it belongs to the team,
+// writing the error response if not.
+func teamReminderRule(w http.ResponseWriter, r *http.Request) (*models.ReminderRule, bool) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+		return nil, false
+	}
+	ruleID, err := uuid.Parse(chi.URLParam(r, "ruleID"))
+	if err != nil {
+		http.Error(w, "Invalid reminder rule ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	var rule models.ReminderRule
+	if result := database.DB.Where("id = ? AND team_id = ?", ruleID, teamID).First(&rule); result.Error != nil {
+		http.Error(w, "Reminder rule not found", http.StatusNotFound)
+		return nil, false
+	}
+	return &rule, true
+}
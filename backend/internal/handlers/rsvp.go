@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/liam/screaming-toller/backend/internal/models"
+	"github.com/liam/screaming-toller/backend/internal/services"
+)
+
+var rsvpPage = template.Must(template.New("rsvp").Parse(`<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <title>RSVP</title>
+</head>
+<body style="font-family: sans-serif; padding: 20px; max-width: 480px; margin: 0 auto;">
+    <h2>{{.Heading}}</h2>
+    {{if .TeamName}}
+    <div style="background: #f0f0f0; padding: 15px; border-radius: 8px; margin: 20px 0;">
+        <p><strong>Team:</strong> {{.TeamName}}</p>
+        <p><strong>Opponent:</strong> {{.Opponent}}</p>
+        <p><strong>Date:</strong> {{.Date}}</p>
+        <p><strong>Time:</strong> {{.Time}}</p>
+    </div>
+    {{end}}
+    <p>{{.Message}}</p>
+    {{if .Confirm}}
+    <form method="post" style="margin: 20px 0;">
+        <button type="submit" name="status" value="going" style="padding: 10px 20px; background: #2e7d32; color: white; border: none; border-radius: 5px; margin-right: 8px;{{if eq .Confirm "going"}} font-weight: bold;{{end}}">Going</button>
+        <button type="submit" name="status" value="not_going" style="padding: 10px 20px; background: #757575; color: white; border: none; border-radius: 5px;{{if eq .Confirm "not_going"}} font-weight: bold;{{end}}">Not going</button>
+    </form>
+    {{end}}
+    {{if .GamesURL}}<p><a href="{{.GamesURL}}">Open the team's games</a></p>{{end}}
+</body>
+</html>
+`))
+
+type rsvpPageData struct {
+	Heading  string
+	Message  string
+	TeamName string
+	Opponent string
+	Date     string
+	Time     string
+	GamesURL string
+	Confirm  string // The answer suggested by the link; shows the answer buttons
+}
+
+// ShowRSVPLink shows the game behind a one-click link in a reminder email and
+// asks the player to confirm their answer. Nothing is recorded on GET, so
+// mail scanners and link previews that open the link don't answer for them.
+func ShowRSVPLink(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status != "going" && status != "not_going" {
+		status = "going"
+	}
+
+	attendance, err := services.LookupRSVPToken(chi.URLParam(r, "token"))
+	if err != nil {
+		renderRSVPError(w, err)
+		return
+	}
+
+	data := rsvpGameData(attendance.Game)
+	data.Confirm = status
+	if status == "going" {
+		data.Heading = "Are you going?"
+	} else {
+		data.Heading = "Can't make it?"
+	}
+	data.Message = "Confirm your answer below. This link can only be used once."
+	renderRSVPPage(w, http.StatusOK, data)
+}
+
+// RespondToRSVPLink records the answer confirmed on the RSVP link page. The
+// signed token is the only credential, so no login is needed, and it is spent
+// once the answer is recorded.
+func RespondToRSVPLink(w http.ResponseWriter, r *http.Request) {
+	status := r.FormValue("status")
+	if status != "going" && status != "not_going" {
+		renderRSVPPage(w, http.StatusBadRequest, rsvpPageData{Heading: "Invalid answer", Message: "Please use the buttons on the RSVP page."})
+		return
+	}
+
+	attendance, err := services.UseRSVPToken(chi.URLParam(r, "token"), status)
+	if err != nil {
+		renderRSVPError(w, err)
+		return
+	}
+
+	data := rsvpGameData(attendance.Game)
+	if status == "going" {
+		data.Heading = "You're in! 🥎"
+		data.Message = "Thanks, you're marked as going."
+	} else {
+		data.Heading = "Thanks for letting us know"
+		data.Message = "You're marked as not going."
+	}
+	renderRSVPPage(w, http.StatusOK, data)
+}
+
+func rsvpGameData(game models.Game) rsvpPageData {
+	return rsvpPageData{
+		TeamName: game.Team.Name,
+		Opponent: game.OpposingTeam,
+		Date:     game.Date.Format("Monday, Jan 2"),
+		Time:     game.Time,
+		GamesURL: services.TeamGamesURL(game.TeamID),
+	}
+}
+
+func renderRSVPError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrRSVPTokenInvalid):
+		renderRSVPPage(w, http.StatusNotFound, rsvpPageData{Heading: "Link not found", Message: "This link is invalid."})
+	case errors.Is(err, services.ErrRSVPTokenUsed):
+		renderRSVPPage(w, http.StatusGone, rsvpPageData{Heading: "Link already used", Message: "This link has already been used. Log in to change your attendance."})
+	case errors.Is(err, services.ErrRSVPGameClosed):
+		renderRSVPPage(w, http.StatusGone, rsvpPageData{Heading: "Game not on", Message: "This game has been cancelled, postponed or already played, so there is nothing to answer."})
+	case errors.Is(err, services.ErrRSVPTokenExpired):
+		renderRSVPPage(w, http.StatusGone, rsvpPageData{Heading: "Link expired", Message: "This link has expired. Log in to update your attendance."})
+	case errors.Is(err, services.ErrRSVPLocked):
+		renderRSVPPage(w, http.StatusForbidden, rsvpPageData{Heading: "RSVPs are closed", Message: "The RSVP deadline for this game has passed. Ask a team admin to update your attendance."})
+	default:
+		log.Printf("Error: Failed to handle RSVP link: %v", err)
+		renderRSVPPage(w, http.StatusInternalServerError, rsvpPageData{Heading: "Something went wrong", Message: "Please try again in a moment."})
+	}
+}
+
+func renderRSVPPage(w http.ResponseWriter, status int, data rsvpPageData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := rsvpPage.Execute(w, data); err != nil {
+		log.Printf("Error: Failed to render RSVP page: %v", err)
+	}
+}
@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/liam/screaming-toller/backend/internal/algorithms"
+	"github.com/liam/screaming-toller/backend/internal/database"
+	"github.com/liam/screaming-toller/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// errTeamNotInLeague is returned when a game would be created for a team
+// that hasn't accepted the league's invitation, or has left the league.
+var errTeamNotInLeague = errors.New("team is not an active member of the league")
+
+type GenerateScheduleRequest struct {
+	StartDate string      `json:"startDate"` // YYYY-MM-DD
+	EndDate   string      `json:"endDate"`   // YYYY-MM-DD
+	Double    bool        `json:"double"`    // double round robin (every pairing home and away)
+	VenueIDs  []uuid.UUID `json:"venueIds"`  // league venues to play at
+	TimeSlots []struct {
+		Weekday string `json:"weekday"` // e.g. "tuesday" or "tue"
+		Time    string `json:"time"`    // HH:MM
+	} `json:"timeSlots"`
+	ExcludeDates []string `json:"excludeDates"` // YYYY-MM-DD, e.g. holidays
+	Preview      bool     `json:"preview"`      // return the schedule without creating games
+}
+
+type ScheduledGameView struct {
+	Round        int       `json:"round"`
+	Date         string    `json:"date"`
+	Time         string    `json:"time"`
+	VenueID      uuid.UUID `json:"venueId"`
+	VenueName    string    `json:"venueName"`
+	HomeTeamID   uuid.UUID `json:"homeTeamId"`
+	HomeTeamName string    `json:"homeTeamName"`
+	AwayTeamID   uuid.UUID `json:"awayTeamId"`
+	AwayTeamName string    `json:"awayTeamName"`
+}
+
+type GenerateScheduleResponse struct {
+	Games   []ScheduledGameView `json:"games"`
+	Created bool                `json:"created"`
+}
+
+// GenerateLeagueSchedule builds a balanced round-robin for every team in the
+// league across the given venues and weekly time slots. Unless previewing, it
+// creates a Game row for both the home and the away team of each matchup.
+func GenerateLeagueSchedule(w http.ResponseWriter, r *http.Request) {
+	leagueID, err := uuid.Parse(chi.URLParam(r, "leagueID"))
+	if err != nil {
+		http.Error(w, "Invalid league ID", http.StatusBadRequest)
+		return
+	}
+
+	var req GenerateScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	startDate, err := time.Parse("2006-01-02", req.StartDate)
+	if err != nil {
+		http.Error(w, "Invalid start date format. Use YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	endDate, err := time.Parse("2006-01-02", req.EndDate)
+	if err != nil {
+		http.Error(w, "Invalid end date format. Use YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	if endDate.Before(startDate) {
+		http.Error(w, "End date must not be before start date", http.StatusBadRequest)
+		return
+	}
+
+	var excludeDates []time.Time
+	for _, d := range req.ExcludeDates {
+		date, err := time.Parse("2006-01-02", d)
+		if err != nil {
+			http.Error(w, "Invalid exclude date format. Use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		excludeDates = append(excludeDates, date)
+	}
+
+	if len(req.TimeSlots) == 0 {
+		http.Error(w, "At least one time slot is required", http.StatusBadRequest)
+		return
+	}
+	timeSlots := make([]algorithms.WeeklyTimeSlot, 0, len(req.TimeSlots))
+	for _, slot := range req.TimeSlots {
+		weekday, err := algorithms.ParseWeekday(slot.Weekday)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, err := time.Parse("15:04", slot.Time); err != nil {
+			http.Error(w, "Invalid time format. Use HH:MM", http.StatusBadRequest)
+			return
+		}
+		timeSlots = append(timeSlots, algorithms.WeeklyTimeSlot{Weekday: weekday, Time: slot.Time})
+	}
+
+	if len(req.VenueIDs) == 0 {
+		http.Error(w, "At least one venue is required", http.StatusBadRequest)
+		return
+	}
+	var venues []models.Venue
+	if err := database.DB.Where("id IN ? AND league_id = ?", req.VenueIDs, leagueID).Find(&venues).Error; err != nil {
+		http.Error(w, "Failed to fetch venues", http.StatusInternalServerError)
+		return
+	}
+	if len(venues) != len(req.VenueIDs) {
+		http.Error(w, "All venues must belong to the league", http.StatusBadRequest)
+		return
+	}
+	venuesByID := make(map[uuid.UUID]models.Venue, len(venues))
+	for _, v := range venues {
+		venuesByID[v.ID] = v
+	}
+
+	teams, err := leagueTeams(leagueID)
+	if err != nil {
+		http.Error(w, "Failed to fetch league teams", http.StatusInternalServerError)
+		return
+	}
+	if len(teams) < 2 {
+		http.Error(w, "A league needs at least two teams to generate a schedule", http.StatusBadRequest)
+		return
+	}
+	teamsByID := make(map[uuid.UUID]models.Team, len(teams))
+	teamIDs := make([]uuid.UUID, len(teams))
+	for i, team := range teams {
+		teamsByID[team.ID] = team
+		teamIDs[i] = team.ID
+	}
+
+	rounds := algorithms.RoundRobin(teamIDs, req.Double)
+	slots := algorithms.BuildScheduleSlots(startDate, endDate, timeSlots, req.VenueIDs, excludeDates)
+	scheduled, err := algorithms.AssignSlots(rounds, slots)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Cannot fit the schedule in the date range: %v", err), http.StatusUnprocessableEntity)
+		return
+	}
+
+	response := GenerateScheduleResponse{Games: make([]ScheduledGameView, 0, len(scheduled))}
+	for _, g := range scheduled {
+		response.Games = append(response.Games, ScheduledGameView{
+			Round:        g.Round,
+			Date:         g.Date.Format("2006-01-02"),
+			Time:         g.Time,
+			VenueID:      g.VenueID,
+			VenueName:    venuesByID[g.VenueID].Name,
+			HomeTeamID:   g.HomeTeamID,
+			HomeTeamName: teamsByID[g.HomeTeamID].Name,
+			AwayTeamID:   g.AwayTeamID,
+			AwayTeamName: teamsByID[g.AwayTeamID].Name,
+		})
+	}
+
+	if req.Preview {
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		for _, g := range scheduled {
+			venue := venuesByID[g.VenueID]
+			if _, err := createLeagueMatchup(tx, leagueID, g.Date, g.Time, &venue, teamsByID[g.HomeTeamID], teamsByID[g.AwayTeamID]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errTeamNotInLeague) {
+		http.Error(w, "The league's teams changed while the schedule was generated; try again", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to create games", http.StatusInternalServerError)
+		return
+	}
+	scheduleLeagueGames(leagueID)
+
+	response.Created = true
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// createLeagueMatchup creates the home and away teams' Game rows for one
+// league game, linked by a shared MatchupID, and initializes attendance for
+// both rosters. Both teams must be active members of the league; otherwise
+// errTeamNotInLeague is returned. It returns the home team's game.
+func createLeagueMatchup(tx *gorm.DB, leagueID uuid.UUID, date time.Time, gameTime string, venue *models.Venue, home, away models.Team) (*models.Game, error) {
+	var members int64
+	if err := tx.Model(&models.LeagueTeam{}).
+		Where("league_id = ? AND team_id IN ? AND status = ?", leagueID, []uuid.UUID{home.ID, away.ID}, "active").
+		Count(&members).Error; err != nil {
+		return nil, err
+	}
+	if members != 2 {
+		return nil, errTeamNotInLeague
+	}
+
+	matchupID := uuid.New()
+
+	var venueID *uuid.UUID
+	location := ""
+	if venue != nil {
+		venueID = &venue.ID
+		location = venue.Name
+	}
+
+	homeGame := models.Game{
+		TeamID:         home.ID,
+		Date:           date,
+		Time:           gameTime,
+		Location:       location,
+		VenueID:        venueID,
+		OpposingTeam:   away.Name,
+		OpponentTeamID: &away.ID,
+		IsHome:         true,
+		Status:         "scheduled",
+		LeagueID:       &leagueID,
+		MatchupID:      &matchupID,
+	}
+	awayGame := models.Game{
+		TeamID:         away.ID,
+		Date:           date,
+		Time:           gameTime,
+		Location:       location,
+		VenueID:        venueID,
+		OpposingTeam:   home.Name,
+		OpponentTeamID: &home.ID,
+		Status:         "scheduled",
+		LeagueID:       &leagueID,
+		MatchupID:      &matchupID,
+	}
+
+	// IsHome has a database default of true, which GORM applies in place of a
+	// false zero value on create, so the away row is corrected afterwards.
+	for _, game := range []*models.Game{&homeGame, &awayGame} {
+		if err := tx.Create(game).Error; err != nil {
+			return nil, err
+		}
+		if err := initializeAttendance(tx, game.TeamID, game.ID); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Model(&awayGame).Update("is_home", false).Error; err != nil {
+		return nil, err
+	}
+
+	return &homeGame, nil
+}
+
+// mirrorMatchupScore copies a score recorded on one team's row of a league
+// game onto the opponent's row, from the opponent's perspective.
+func mirrorMatchupScore(tx *gorm.DB, game models.Game, finalScore, opponentScore int) error {
+	if game.MatchupID == nil {
+		return nil
+	}
+	return tx.Model(&models.Game{}).
+		Where("matchup_id = ? AND id <> ?", *game.MatchupID, game.ID).
+		Updates(map[string]interface{}{
+			"final_score":    opponentScore,
+			"opponent_score": finalScore,
+		}).Error
+}
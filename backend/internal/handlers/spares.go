@@ -0,0 +1,519 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/liam/screaming-toller/backend/internal/database"
+	"github.com/liam/screaming-toller/backend/internal/models"
+	"github.com/liam/screaming-toller/backend/internal/services"
+	"gorm.io/gorm"
+)
+
+type SpareDetailsRequest struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Phone    string `json:"phone"`
+	Gender   string `json:"gender"`   // "M" or "F"
+	Priority int    `json:"priority"` // Lower is asked first
+	Notes    string `json:"notes"`
+	IsActive *bool  `json:"isActive"`
+}
+
+// validate checks the request and returns a user-facing error message, or "" if valid.
+func (req *SpareDetailsRequest) validate() string {
+	req.Name = strings.TrimSpace(req.Name)
+	req.Email = strings.TrimSpace(req.Email)
+	if req.Name == "" {
+		return "Spare name is required"
+	}
+	if req.Email == "" {
+		return "Spare email is required"
+	}
+	if req.Gender != "M" && req.Gender != "F" {
+		return "Gender must be 'M' or 'F'"
+	}
+	return ""
+}
+
+type CreateSpareRequestRequest struct {
+	Gender        string `json:"gender"`        // "M" or "F"
+	ResponseHours int    `json:"responseHours"` // Time each spare has to answer, default 6
+}
+
+func GetTeamSpares(w http.ResponseWriter, r *http.Request) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+		return
+	}
+
+	// Include spares shared by the leagues this team plays in
+	var spares []models.Spare
+	if result := database.DB.
+		Where("team_id = ? OR league_id IN (?)", teamID,
+			database.DB.Model(&models.LeagueTeam{}).Select("league_id").Where("team_id = ? AND status = ?", teamID, "active")).
+		Order("priority asc, name asc").
+		Find(&spares); result.Error != nil {
+		http.Error(w, "Failed to fetch spares", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(spares)
+}
+
+func CreateSpare(w http.ResponseWriter, r *http.Request) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+		return
+	}
+
+	var req SpareDetailsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	spare := models.Spare{TeamID: &teamID}
+	createSpare(w, &spare, req)
+}
+
+func UpdateSpare(w http.ResponseWriter, r *http.Request) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+		return
+	}
+	spareID, err := uuid.Parse(chi.URLParam(r, "spareID"))
+	if err != nil {
+		http.Error(w, "Invalid spare ID", http.StatusBadRequest)
+		return
+	}
+
+	var spare models.Spare
+	if result := database.DB.Where("id = ? AND team_id = ?", spareID, teamID).First(&spare); result.Error != nil {
+		http.Error(w, "Spare not found", http.StatusNotFound)
+		return
+	}
+
+	var req SpareDetailsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	saveSpare(w, &spare, req)
+}
+
+func DeleteSpare(w http.ResponseWriter, r *http.Request) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+		return
+	}
+	spareID, err := uuid.Parse(chi.URLParam(r, "spareID"))
+	if err != nil {
+		http.Error(w, "Invalid spare ID", http.StatusBadRequest)
+		return
+	}
+
+	if result := database.DB.Where("id = ? AND team_id = ?", spareID, teamID).Delete(&models.Spare{}); result.Error != nil {
+		http.Error(w, "Failed to delete spare", http.StatusInternalServerError)
+		return
+	} else if result.RowsAffected == 0 {
+		http.Error(w, "Spare not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func GetLeagueSpares(w http.ResponseWriter, r *http.Request) {
+	leagueID, err := uuid.Parse(chi.URLParam(r, "leagueID"))
+	if err != nil {
+		http.Error(w, "Invalid league ID", http.StatusBadRequest)
+		return
+	}
+
+	var spares []models.Spare
+	if result := database.DB.Where("league_id = ?", leagueID).Order("priority asc, name asc").Find(&spares); result.Error != nil {
+		http.Error(w, "Failed to fetch spares", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(spares)
+}
+
+func CreateLeagueSpare(w http.ResponseWriter, r *http.Request) {
+	leagueID, err := uuid.Parse(chi.URLParam(r, "leagueID"))
+	if err != nil {
+		http.Error(w, "Invalid league ID", http.StatusBadRequest)
+		return
+	}
+
+	var req SpareDetailsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	spare := models.Spare{LeagueID: &leagueID}
+	createSpare(w, &spare, req)
+}
+
+func UpdateLeagueSpare(w http.ResponseWriter, r *http.Request) {
+	leagueID, err := uuid.Parse(chi.URLParam(r, "leagueID"))
+	if err != nil {
+		http.Error(w, "Invalid league ID", http.StatusBadRequest)
+		return
+	}
+	spareID, err := uuid.Parse(chi.URLParam(r, "spareID"))
+	if err != nil {
+		http.Error(w, "Invalid spare ID", http.StatusBadRequest)
+		return
+	}
+
+	var spare models.Spare
+	if result := database.DB.Where("id = ? AND league_id = ?", spareID, leagueID).First(&spare); result.Error != nil {
+		http.Error(w, "Spare not found", http.StatusNotFound)
+		return
+	}
+
+	var req SpareDetailsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	saveSpare(w, &spare, req)
+}
+
+func DeleteLeagueSpare(w http.ResponseWriter, r *http.Request) {
+	leagueID, err := uuid.Parse(chi.URLParam(r, "leagueID"))
+	if err != nil {
+		http.Error(w, "Invalid league ID", http.StatusBadRequest)
+		return
+	}
+	spareID, err := uuid.Parse(chi.URLParam(r, "spareID"))
+	if err != nil {
+		http.Error(w, "Invalid spare ID", http.StatusBadRequest)
+		return
+	}
+
+	if result := database.DB.Where("id = ? AND league_id = ?", spareID, leagueID).Delete(&models.Spare{}); result.Error != nil {
+		http.Error(w, "Failed to delete spare", http.StatusInternalServerError)
+		return
+	} else if result.RowsAffected == 0 {
+		http.Error(w, "Spare not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func applySpareRequest(spare *models.Spare, req SpareDetailsRequest) {
+	spare.Name = req.Name
+	spare.Email = req.Email
+	spare.Phone = req.Phone
+	spare.Gender = req.Gender
+	spare.Priority = req.Priority
+	spare.Notes = req.Notes
+	if req.IsActive != nil {
+		spare.IsActive = *req.IsActive
+	}
+}
+
+func createSpare(w http.ResponseWriter, spare *models.Spare, req SpareDetailsRequest) {
+	applySpareRequest(spare, req)
+
+	if result := database.DB.Create(spare); result.Error != nil {
+		http.Error(w, "Failed to create spare", http.StatusInternalServerError)
+		return
+	}
+	// IsActive defaults to true on create
+	if req.IsActive != nil && !*req.IsActive {
+		database.DB.Model(spare).Update("is_active", false)
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(spare)
+}
+
+func saveSpare(w http.ResponseWriter, spare *models.Spare, req SpareDetailsRequest) {
+	applySpareRequest(spare, req)
+
+	if result := database.DB.Save(spare); result.Error != nil {
+		http.Error(w, "Failed to update spare", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(spare)
+}
+
+// CreateSpareRequest starts asking spares of the given gender, one at a time
+// in priority order, to play in the game.
+func CreateSpareRequest(w http.ResponseWriter, r *http.Request) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+		return
+	}
+	gameID, err := uuid.Parse(chi.URLParam(r, "gameID"))
+	if err != nil {
+		http.Error(w, "Invalid game ID", http.StatusBadRequest)
+		return
+	}
+	userID := r.Context().Value("userID").(uuid.UUID)
+
+	var req CreateSpareRequestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Gender != "M" && req.Gender != "F" {
+		http.Error(w, "Gender must be 'M' or 'F'", http.StatusBadRequest)
+		return
+	}
+	if req.ResponseHours < 0 {
+		http.Error(w, "responseHours must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	var game models.Game
+	if result := database.DB.Where("id = ? AND team_id = ?", gameID, teamID).First(&game); result.Error != nil {
+		http.Error(w, "Game not found", http.StatusNotFound)
+		return
+	}
+	if game.Status == "cancelled" || game.Status == "postponed" || game.Status == "completed" {
+		http.Error(w, "Spares can only be requested for upcoming games", http.StatusBadRequest)
+		return
+	}
+
+	request := models.SpareRequest{
+		GameID:        gameID,
+		TeamID:        teamID,
+		Gender:        req.Gender,
+		Status:        "open",
+		ResponseHours: req.ResponseHours,
+		RequestedBy:   userID,
+	}
+	if result := database.DB.Create(&request); result.Error != nil {
+		http.Error(w, "Failed to create spare request", http.StatusInternalServerError)
+		return
+	}
+
+	if err := services.InviteNextSpare(notifications, request.ID); err != nil {
+		log.Printf("Warning: Failed to invite a spare for request %s: %v", request.ID, err)
+	}
+
+	database.DB.Preload("Invites.Spare").First(&request, "id = ?", request.ID)
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(request)
+}
+
+func GetSpareRequests(w http.ResponseWriter, r *http.Request) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+		return
+	}
+	gameID, err := uuid.Parse(chi.URLParam(r, "gameID"))
+	if err != nil {
+		http.Error(w, "Invalid game ID", http.StatusBadRequest)
+		return
+	}
+
+	var requests []models.SpareRequest
+	if result := database.DB.
+		Preload("Invites", func(db *gorm.DB) *gorm.DB { return db.Order("created_at asc") }).
+		Preload("Invites.Spare").
+		Where("game_id = ? AND team_id = ?", gameID, teamID).
+		Order("created_at desc").
+		Find(&requests); result.Error != nil {
+		http.Error(w, "Failed to fetch spare requests", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(requests)
+}
+
+func CancelSpareRequest(w http.ResponseWriter, r *http.Request) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+		return
+	}
+	requestID, err := uuid.Parse(chi.URLParam(r, "requestID"))
+	if err != nil {
+		http.Error(w, "Invalid request ID", http.StatusBadRequest)
+		return
+	}
+
+	var request models.SpareRequest
+	if result := database.DB.Where("id = ? AND team_id = ?", requestID, teamID).First(&request); result.Error != nil {
+		http.Error(w, "Spare request not found", http.StatusNotFound)
+		return
+	}
+	if request.Status != "open" {
+		http.Error(w, "Only open requests can be cancelled", http.StatusConflict)
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&request).Update("status", "cancelled").Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.SpareInvite{}).
+			Where("spare_request_id = ? AND status = ?", request.ID, "pending").
+			Update("status", "expired").Error
+	})
+	if err != nil {
+		http.Error(w, "Failed to cancel spare request", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+var spareInvitePage = template.Must(template.New("spare-invite").Parse(`<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <title>Spare Request</title>
+</head>
+<body style="font-family: sans-serif; padding: 20px; max-width: 480px; margin: 0 auto;">
+    <h2>{{.Heading}}</h2>
+    {{if .Game}}
+    <div style="background: #f0f0f0; padding: 15px; border-radius: 8px; margin: 20px 0;">
+        <p><strong>Team:</strong> {{.TeamName}}</p>
+        <p><strong>Opponent:</strong> {{.Game.OpposingTeam}}</p>
+        <p><strong>Date:</strong> {{.Date}}</p>
+        <p><strong>Time:</strong> {{.Time}}</p>
+        <p><strong>Location:</strong> {{.Game.Location}}</p>
+    </div>
+    {{end}}
+    <p>{{.Message}}</p>
+    {{if .Open}}
+    <form method="post" action="{{.Token}}/accept" style="display: inline;">
+        <button type="submit" style="padding: 10px 20px; background: rgba(247, 82, 31, 1); color: white; border: none; border-radius: 5px;">I'm in</button>
+    </form>
+    <form method="post" action="{{.Token}}/decline" style="display: inline;">
+        <button type="submit" style="padding: 10px 20px; border-radius: 5px;">Can't make it</button>
+    </form>
+    {{end}}
+</body>
+</html>
+`))
+
+type spareInvitePageData struct {
+	Heading  string
+	Message  string
+	Token    string
+	Open     bool
+	TeamName string
+	Game     *models.Game
+	Date     string
+	Time     string
+}
+
+// GetSpareInvite shows a spare the game they're asked to play in, with
+// buttons to accept or decline. Spares may not have an account, so the page
+// is public and the invite token is the only credential.
+func GetSpareInvite(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+
+	data := spareInvitePageData{Token: token}
+	var invite models.SpareInvite
+	if result := database.DB.Preload("Spare").Where("token = ?", token).First(&invite); result.Error != nil {
+		data.Heading = "Request not found"
+		data.Message = "This link is invalid."
+		renderSpareInvitePage(w, http.StatusNotFound, data)
+		return
+	}
+
+	var request models.SpareRequest
+	var game models.Game
+	var team models.Team
+	if err := database.DB.First(&request, "id = ?", invite.SpareRequestID).Error; err == nil {
+		if err := database.DB.First(&game, "id = ?", request.GameID).Error; err == nil {
+			data.Game = &game
+			data.Date = game.Date.Format("Monday, Jan 2")
+			data.Time = game.Time
+		}
+		database.DB.First(&team, "id = ?", request.TeamID)
+		data.TeamName = team.Name
+	}
+
+	switch {
+	case invite.Status == "accepted":
+		data.Heading = "You're in! 🥎"
+		data.Message = "Thanks for filling in. See you at the game."
+	case invite.Status != "pending" || request.Status != "open" || invite.ExpiresAt.Before(time.Now()):
+		data.Heading = "This request has closed"
+		data.Message = "Thanks anyway — the team has found a player or moved on."
+	default:
+		data.Heading = "Can you play?"
+		data.Message = "Hi " + invite.Spare.Name + ", the team is short a player. Can you fill in?"
+		data.Open = true
+	}
+	renderSpareInvitePage(w, http.StatusOK, data)
+}
+
+func AcceptSpareInvite(w http.ResponseWriter, r *http.Request) {
+	respondToSpareInvite(w, chi.URLParam(r, "token"), true)
+}
+
+func DeclineSpareInvite(w http.ResponseWriter, r *http.Request) {
+	respondToSpareInvite(w, chi.URLParam(r, "token"), false)
+}
+
+func respondToSpareInvite(w http.ResponseWriter, token string, accept bool) {
+	_, err := services.RespondToSpareInvite(notifications, token, accept)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		renderSpareInvitePage(w, http.StatusNotFound, spareInvitePageData{Heading: "Request not found", Message: "This link is invalid."})
+	case errors.Is(err, services.ErrSpareInviteClosed):
+		renderSpareInvitePage(w, http.StatusConflict, spareInvitePageData{Heading: "This request has closed", Message: "Thanks anyway — the team has found a player or moved on."})
+	case err != nil:
+		log.Printf("Error: Failed to record spare response: %v", err)
+		renderSpareInvitePage(w, http.StatusInternalServerError, spareInvitePageData{Heading: "Something went wrong", Message: "Please try again in a moment."})
+	case accept:
+		renderSpareInvitePage(w, http.StatusOK, spareInvitePageData{Heading: "You're in! 🥎", Message: "Thanks for filling in. The team has been told and you're marked as going."})
+	default:
+		renderSpareInvitePage(w, http.StatusOK, spareInvitePageData{Heading: "No problem", Message: "Thanks for letting us know. We'll ask someone else."})
+	}
+}
+
+func renderSpareInvitePage(w http.ResponseWriter, status int, data spareInvitePageData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := spareInvitePage.Execute(w, data); err != nil {
+		log.Printf("Error: Failed to render spare invite page: %v", err)
+	}
+}
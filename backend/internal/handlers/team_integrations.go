@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/liam/screaming-toller/backend/internal/database"
+	"github.com/liam/screaming-toller/backend/internal/models"
+	"github.com/liam/screaming-toller/backend/internal/services"
+)
+
+type UpdateWhatsAppIntegrationRequest struct {
+	Token string `json:"token"` // "" removes the team's token; "********" keeps it
+}
+
+// WhatsAppIntegrationResponse is the team's WhatsApp token (masked) and
+// health: where the token in use comes from and when sends last worked or
+// failed.
+type WhatsAppIntegrationResponse struct {
+	Token         string     `json:"token"`
+	TokenSource   string     `json:"tokenSource"` // "team", "member" or "" when group messages can't be sent
+	LastSuccessAt *time.Time `json:"lastSuccessAt,omitempty"`
+	LastFailureAt *time.Time `json:"lastFailureAt,omitempty"`
+	LastError     string     `json:"lastError,omitempty"`
+	Healthy       bool       `json:"healthy"` // The last send or test succeeded
+}
+
+type WhatsAppTestResponse struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+	WhatsAppIntegrationResponse
+}
+
+// GetWhatsAppIntegration shows the team's WhatsApp token and health.
+func GetWhatsAppIntegration(w http.ResponseWriter, r *http.Request) {
+	team, ok := loadIntegrationTeam(w, r)
+	if !ok {
+		return
+	}
+
+	response, err := whatsAppIntegrationResponse(team)
+	if err != nil {
+		http.Error(w, "Failed to fetch WhatsApp settings", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateWhatsAppIntegration sets the team's own Whapi token, which is used
+// instead of borrowing a member's personal token.
+func UpdateWhatsAppIntegration(w http.ResponseWriter, r *http.Request) {
+	team, ok := loadIntegrationTeam(w, r)
+	if !ok {
+		return
+	}
+	userID := r.Context().Value("userID").(uuid.UUID)
+
+	var req UpdateWhatsAppIntegrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	token := strings.TrimSpace(req.Token)
+	if token != "********" {
+		if err := services.SetTeamWhapiToken(team.ID, userID, token); err != nil {
+			http.Error(w, "Failed to save WhatsApp token", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	response, err := whatsAppIntegrationResponse(team)
+	if err != nil {
+		http.Error(w, "Failed to fetch WhatsApp settings", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// TestWhatsAppIntegration checks the team's token with the WhatsApp provider
+// without sending a message. The result is recorded in the team's health.
+func TestWhatsAppIntegration(w http.ResponseWriter, r *http.Request) {
+	team, ok := loadIntegrationTeam(w, r)
+	if !ok {
+		return
+	}
+
+	testErr := services.TestTeamWhatsApp(team)
+	if errors.Is(testErr, errors.ErrUnsupported) {
+		http.Error(w, "The configured WhatsApp provider can't test a connection", http.StatusNotImplemented)
+		return
+	}
+
+	status, err := whatsAppIntegrationResponse(team)
+	if err != nil {
+		http.Error(w, "Failed to fetch WhatsApp settings", http.StatusInternalServerError)
+		return
+	}
+
+	response := WhatsAppTestResponse{OK: testErr == nil, WhatsAppIntegrationResponse: status}
+	if testErr != nil {
+		response.Error = testErr.Error()
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func loadIntegrationTeam(w http.ResponseWriter, r *http.Request) (models.Team, bool) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+		return models.Team{}, false
+	}
+
+	var team models.Team
+	if err := database.DB.First(&team, "id = ?", teamID).Error; err != nil {
+		http.Error(w, "Team not found", http.StatusNotFound)
+		return models.Team{}, false
+	}
+	return team, true
+}
+
+func whatsAppIntegrationResponse(team models.Team) (WhatsAppIntegrationResponse, error) {
+	integration, err := services.TeamIntegration(team.ID, services.IntegrationWhatsApp)
+	if err != nil {
+		return WhatsAppIntegrationResponse{}, err
+	}
+
+	response := WhatsAppIntegrationResponse{
+		Token:         maskToken(integration.Token),
+		TokenSource:   services.WhapiTokenSource(team),
+		LastSuccessAt: integration.LastSuccessAt,
+		LastFailureAt: integration.LastFailureAt,
+		LastError:     integration.LastError,
+	}
+	response.Healthy = response.LastSuccessAt != nil &&
+		(response.LastFailureAt == nil || response.LastSuccessAt.After(*response.LastFailureAt))
+	return response, nil
+}
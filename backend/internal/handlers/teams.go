@@ -73,13 +73,10 @@ func ApproveTeam(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Send email to team creator
+	// Notify the team creator
 	var creator models.TeamMember
 	if err := database.DB.Preload("User").Where("team_id = ? AND is_admin = ?", team.ID, true).First(&creator).Error; err == nil {
-		emailService, _ := services.NewEmailService()
-		if emailService != nil {
-			emailService.SendTeamApprovedEmail(creator.User.Email, team.Name)
-		}
+		notifications.Notify(services.UserRecipient(creator.User, &team), services.TeamApprovedMessage(services.UserMessageContext(creator.User, &team), team.Name))
 	}
 
 	json.NewEncoder(w).Encode(team)
@@ -111,13 +108,10 @@ func RejectTeam(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Send email to team creator before deletion
+	// Notify the team creator before deletion
 	var creator models.TeamMember
 	if err := database.DB.Preload("User").Where("team_id = ? AND is_admin = ?", team.ID, true).First(&creator).Error; err == nil {
-		emailService, _ := services.NewEmailService()
-		if emailService != nil {
-			emailService.SendTeamRejectedEmail(creator.User.Email, team.Name)
-		}
+		notifications.Notify(services.UserRecipient(creator.User, nil), services.TeamRejectedMessage(services.UserMessageContext(creator.User, nil), team.Name))
 	}
 
 	// Delete the team and its associations in a transaction to ensure data integrity
@@ -203,18 +197,15 @@ func CreateTeam(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Send email to Super Admin(s)
-	emailService, err := services.NewEmailService()
-	if err == nil {
-		var user models.User
-		database.DB.First(&user, userID)
-		
-		var superAdmins []models.User
-		database.DB.Where("is_super_admin = ?", true).Find(&superAdmins)
-		
-		for _, admin := range superAdmins {
-			emailService.SendTeamRequestEmail(admin.Email, user.Name, team.Name)
-		}
+	// Notify Super Admin(s)
+	var user models.User
+	database.DB.First(&user, userID)
+
+	var superAdmins []models.User
+	database.DB.Where("is_super_admin = ?", true).Find(&superAdmins)
+
+	for _, admin := range superAdmins {
+		notifications.Notify(services.UserRecipient(admin, nil), services.TeamRequestMessage(services.UserMessageContext(admin, nil), user.Name, team.Name))
 	}
 
 	w.WriteHeader(http.StatusCreated)
@@ -289,6 +280,10 @@ func UpdateTeam(w http.ResponseWriter, r *http.Request) {
 		Season                 string     `json:"season"`
 		WhatsAppGroupID        string     `json:"whatsAppGroupId"`
 		WhapiTokenSourceUserID *uuid.UUID `json:"whapiTokenSourceUserId"`
+		RSVPDeadlineHours      *int       `json:"rsvpDeadlineHours"`
+		RSVPDeadlineAction     *string    `json:"rsvpDeadlineAction"`
+		RSVPMaybeStatus        *string    `json:"rsvpMaybeStatus"`
+		Locale                 *string    `json:"locale"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
@@ -296,6 +291,36 @@ func UpdateTeam(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// RSVP deadline settings are only changed when sent
+	if updates.RSVPDeadlineHours != nil {
+		if *updates.RSVPDeadlineHours < 0 || *updates.RSVPDeadlineHours > 24*14 {
+			http.Error(w, "RSVP deadline must be between 0 and 336 hours before the game", http.StatusBadRequest)
+			return
+		}
+		team.RSVPDeadlineHours = *updates.RSVPDeadlineHours
+	}
+	if updates.RSVPDeadlineAction != nil {
+		if *updates.RSVPDeadlineAction != "lock" && *updates.RSVPDeadlineAction != "convert" {
+			http.Error(w, "RSVP deadline action must be 'lock' or 'convert'", http.StatusBadRequest)
+			return
+		}
+		team.RSVPDeadlineAction = *updates.RSVPDeadlineAction
+	}
+	if updates.RSVPMaybeStatus != nil {
+		if *updates.RSVPMaybeStatus != "going" && *updates.RSVPMaybeStatus != "not_going" {
+			http.Error(w, "RSVP maybe status must be 'going' or 'not_going'", http.StatusBadRequest)
+			return
+		}
+		team.RSVPMaybeStatus = *updates.RSVPMaybeStatus
+	}
+	if updates.Locale != nil {
+		if *updates.Locale != "" && !services.SupportedLocale(*updates.Locale) {
+			http.Error(w, "Unsupported language", http.StatusBadRequest)
+			return
+		}
+		team.Locale = *updates.Locale
+	}
+
 	// Update fields if provided
 	if updates.Name != "" {
 		team.Name = updates.Name
@@ -323,6 +348,9 @@ func UpdateTeam(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if updates.RSVPDeadlineHours != nil {
+		scheduleTeamGames(team.ID)
+	}
 
 	json.NewEncoder(w).Encode(team)
 }
@@ -0,0 +1,887 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/liam/screaming-toller/backend/internal/algorithms"
+	"github.com/liam/screaming-toller/backend/internal/database"
+	"github.com/liam/screaming-toller/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	errTournamentTie      = errors.New("elimination games cannot end in a tie")
+	errBracketAdvanced    = errors.New("the bracket has already advanced past this game")
+	errPoolPlayUnfinished = errors.New("all pool games must be scored before the bracket is generated")
+)
+
+type CreateTournamentRequest struct {
+	Name      string     `json:"name"`
+	Format    string     `json:"format"`    // "single_elimination" (default) or "double_elimination"
+	StartDate string     `json:"startDate"` // YYYY-MM-DD
+	EndDate   string     `json:"endDate"`   // YYYY-MM-DD, defaults to the start date
+	VenueID   *uuid.UUID `json:"venueId"`   // league venue used for every game unless rescheduled
+	PoolTimes []string   `json:"poolTimes"` // HH:MM start time for each pool round, on the start date
+	Teams     []struct {
+		TeamID uuid.UUID `json:"teamId"`
+		Pool   string    `json:"pool"` // leave empty on every team to skip pool play
+	} `json:"teams"` // without pools, seeds follow this order
+}
+
+type GenerateBracketRequest struct {
+	AdvancePerPool int `json:"advancePerPool"` // 0 advances every team
+}
+
+type UpdateTournamentGameRequest struct {
+	Date    *string    `json:"date"` // YYYY-MM-DD
+	Time    *string    `json:"time"` // HH:MM
+	VenueID *uuid.UUID `json:"venueId"`
+}
+
+type TournamentTeamView struct {
+	TeamID  uuid.UUID `json:"teamId"`
+	Name    string    `json:"name"`
+	LogoURL string    `json:"logoUrl"`
+	Pool    string    `json:"pool"`
+	Seed    int       `json:"seed"`
+}
+
+type TournamentGameView struct {
+	models.TournamentGame
+	HomeTeamName   string `json:"homeTeamName"`
+	AwayTeamName   string `json:"awayTeamName"`
+	WinnerTeamName string `json:"winnerTeamName,omitempty"`
+}
+
+type TournamentResponse struct {
+	models.Tournament
+	ChampionName  string                               `json:"championName,omitempty"`
+	Teams         []TournamentTeamView                 `json:"teams"`
+	PoolStandings map[string][]algorithms.StandingsRow `json:"poolStandings"`
+	Games         []TournamentGameView                 `json:"games"`
+}
+
+func GetLeagueTournaments(w http.ResponseWriter, r *http.Request) {
+	leagueID, err := uuid.Parse(chi.URLParam(r, "leagueID"))
+	if err != nil {
+		http.Error(w, "Invalid league ID", http.StatusBadRequest)
+		return
+	}
+
+	var tournaments []models.Tournament
+	if result := database.DB.Where("league_id = ?", leagueID).Order("start_date desc").Find(&tournaments); result.Error != nil {
+		http.Error(w, "Failed to fetch tournaments", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(tournaments)
+}
+
+// CreateTournament registers league teams for a tournament. Teams assigned to
+// pools play a round robin within their pool first; otherwise the bracket is
+// generated straight away, seeded in the order the teams were given.
+func CreateTournament(w http.ResponseWriter, r *http.Request) {
+	leagueID, err := uuid.Parse(chi.URLParam(r, "leagueID"))
+	if err != nil {
+		http.Error(w, "Invalid league ID", http.StatusBadRequest)
+		return
+	}
+	userID := r.Context().Value("userID").(uuid.UUID)
+
+	var req CreateTournamentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		http.Error(w, "Tournament name is required", http.StatusBadRequest)
+		return
+	}
+	if req.Format == "" {
+		req.Format = "single_elimination"
+	}
+	if req.Format != "single_elimination" && req.Format != "double_elimination" {
+		http.Error(w, "Format must be single_elimination or double_elimination", http.StatusBadRequest)
+		return
+	}
+
+	startDate, err := time.Parse("2006-01-02", req.StartDate)
+	if err != nil {
+		http.Error(w, "Invalid start date format. Use YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	endDate := startDate
+	if req.EndDate != "" {
+		if endDate, err = time.Parse("2006-01-02", req.EndDate); err != nil {
+			http.Error(w, "Invalid end date format. Use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		if endDate.Before(startDate) {
+			http.Error(w, "End date must not be before start date", http.StatusBadRequest)
+			return
+		}
+	}
+	for _, t := range req.PoolTimes {
+		if _, err := time.Parse("15:04", t); err != nil {
+			http.Error(w, "Invalid time format. Use HH:MM", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if req.VenueID != nil {
+		var venue models.Venue
+		if result := database.DB.Where("id = ? AND league_id = ?", *req.VenueID, leagueID).First(&venue); result.Error != nil {
+			http.Error(w, "Venue must belong to the league", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if len(req.Teams) < 2 {
+		http.Error(w, "A tournament needs at least two teams", http.StatusBadRequest)
+		return
+	}
+	teams, err := leagueTeams(leagueID)
+	if err != nil {
+		http.Error(w, "Failed to fetch league teams", http.StatusInternalServerError)
+		return
+	}
+	inLeague := make(map[uuid.UUID]bool, len(teams))
+	for _, team := range teams {
+		inLeague[team.ID] = true
+	}
+
+	pooled := 0
+	seen := make(map[uuid.UUID]bool, len(req.Teams))
+	for i := range req.Teams {
+		entry := &req.Teams[i]
+		if !inLeague[entry.TeamID] {
+			http.Error(w, "All teams must belong to the league", http.StatusBadRequest)
+			return
+		}
+		if seen[entry.TeamID] {
+			http.Error(w, "A team can only be entered once", http.StatusBadRequest)
+			return
+		}
+		seen[entry.TeamID] = true
+		entry.Pool = strings.TrimSpace(entry.Pool)
+		if entry.Pool != "" {
+			pooled++
+		}
+	}
+	if pooled != 0 && pooled != len(req.Teams) {
+		http.Error(w, "Either every team or no team must be assigned to a pool", http.StatusBadRequest)
+		return
+	}
+
+	tournament := models.Tournament{
+		LeagueID:  leagueID,
+		Name:      req.Name,
+		Format:    req.Format,
+		Status:    "pool_play",
+		StartDate: startDate,
+		EndDate:   endDate,
+		VenueID:   req.VenueID,
+		CreatedBy: userID,
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&tournament).Error; err != nil {
+			return err
+		}
+
+		pools := make(map[string][]uuid.UUID)
+		var seeds []uuid.UUID
+		for _, entry := range req.Teams {
+			if err := tx.Create(&models.TournamentTeam{
+				TournamentID: tournament.ID,
+				TeamID:       entry.TeamID,
+				Pool:         entry.Pool,
+			}).Error; err != nil {
+				return err
+			}
+			pools[entry.Pool] = append(pools[entry.Pool], entry.TeamID)
+			seeds = append(seeds, entry.TeamID)
+		}
+
+		if pooled == 0 {
+			return generateBracket(tx, &tournament, seeds)
+		}
+		return generatePoolPlay(tx, &tournament, pools, req.PoolTimes)
+	})
+	if errors.Is(err, errTeamNotInLeague) {
+		http.Error(w, "All teams must belong to the league", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to create tournament", http.StatusInternalServerError)
+		return
+	}
+	scheduleLeagueGames(tournament.LeagueID)
+
+	response, err := buildTournamentResponse(tournament.ID)
+	if err != nil {
+		http.Error(w, "Failed to fetch tournament", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetTournament returns the tournament with its pool standings and the full
+// bracket, including games whose teams are not yet known.
+func GetTournament(w http.ResponseWriter, r *http.Request) {
+	tournament, ok := findLeagueTournament(w, r)
+	if !ok {
+		return
+	}
+
+	response, err := buildTournamentResponse(tournament.ID)
+	if err != nil {
+		http.Error(w, "Failed to fetch tournament", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// GenerateTournamentBracket ends pool play: teams are seeded from their pool
+// standings and the elimination bracket is created.
+func GenerateTournamentBracket(w http.ResponseWriter, r *http.Request) {
+	tournament, ok := findLeagueTournament(w, r)
+	if !ok {
+		return
+	}
+	if tournament.Status != "pool_play" {
+		http.Error(w, "The bracket has already been generated", http.StatusConflict)
+		return
+	}
+
+	var req GenerateBracketRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.AdvancePerPool < 0 {
+		http.Error(w, "advancePerPool must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var unfinished int64
+		if err := tx.Model(&models.TournamentGame{}).
+			Where("tournament_id = ? AND stage = ? AND status <> ?", tournament.ID, algorithms.StagePool, "completed").
+			Count(&unfinished).Error; err != nil {
+			return err
+		}
+		if unfinished > 0 {
+			return errPoolPlayUnfinished
+		}
+
+		standings, err := poolStandings(tx, tournament.ID)
+		if err != nil {
+			return err
+		}
+		return generateBracket(tx, tournament, algorithms.SeedFromPools(standings, req.AdvancePerPool))
+	})
+	if errors.Is(err, errPoolPlayUnfinished) {
+		http.Error(w, "All pool games must be scored before the bracket is generated", http.StatusConflict)
+		return
+	}
+	if errors.Is(err, errTeamNotInLeague) {
+		http.Error(w, "Fewer than two of the pool teams are still in the league", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to generate bracket", http.StatusInternalServerError)
+		return
+	}
+	scheduleLeagueGames(tournament.LeagueID)
+
+	response, err := buildTournamentResponse(tournament.ID)
+	if err != nil {
+		http.Error(w, "Failed to fetch tournament", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateTournamentGame sets when and where a tournament game is played. If
+// the teams' games already exist, they are updated too, re-opening their RSVPs
+// and applying blackouts on a new date just as UpdateGame does.
+func UpdateTournamentGame(w http.ResponseWriter, r *http.Request) {
+	tournament, ok := findLeagueTournament(w, r)
+	if !ok {
+		return
+	}
+	gameID, err := uuid.Parse(chi.URLParam(r, "tournamentGameID"))
+	if err != nil {
+		http.Error(w, "Invalid game ID", http.StatusBadRequest)
+		return
+	}
+
+	var tg models.TournamentGame
+	if result := database.DB.Where("id = ? AND tournament_id = ?", gameID, tournament.ID).First(&tg); result.Error != nil {
+		http.Error(w, "Game not found", http.StatusNotFound)
+		return
+	}
+
+	var req UpdateTournamentGameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	gameUpdates := make(map[string]interface{})
+	if req.Date != nil {
+		date, err := time.Parse("2006-01-02", *req.Date)
+		if err != nil {
+			http.Error(w, "Invalid date format. Use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		tg.Date = &date
+		gameUpdates["date"] = date
+	}
+	if req.Time != nil {
+		if *req.Time != "" {
+			if _, err := time.Parse("15:04", *req.Time); err != nil {
+				http.Error(w, "Invalid time format. Use HH:MM", http.StatusBadRequest)
+				return
+			}
+		}
+		tg.Time = *req.Time
+		gameUpdates["time"] = *req.Time
+	}
+	if req.VenueID != nil {
+		var venue models.Venue
+		if result := database.DB.Where("id = ? AND league_id = ?", *req.VenueID, tournament.LeagueID).First(&venue); result.Error != nil {
+			http.Error(w, "Venue must belong to the league", http.StatusBadRequest)
+			return
+		}
+		tg.VenueID = &venue.ID
+		gameUpdates["venue_id"] = venue.ID
+		gameUpdates["location"] = venue.Name
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&tg).Error; err != nil {
+			return err
+		}
+		if tg.MatchupID == nil || len(gameUpdates) == 0 {
+			return nil
+		}
+		// The teams' games are moved as UpdateGame moves a game
+		reopenRSVPs(gameUpdates)
+		if err := tx.Model(&models.Game{}).Where("matchup_id = ?", *tg.MatchupID).Updates(gameUpdates).Error; err != nil {
+			return err
+		}
+		if _, ok := gameUpdates["date"]; !ok {
+			return nil
+		}
+		var games []models.Game
+		if err := tx.Where("matchup_id = ?", *tg.MatchupID).Find(&games).Error; err != nil {
+			return err
+		}
+		for _, game := range games {
+			if err := applyBlackoutsToGame(tx, game); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		http.Error(w, "Failed to update game", http.StatusInternalServerError)
+		return
+	}
+	scheduleLeagueGames(tournament.LeagueID)
+
+	json.NewEncoder(w).Encode(tg)
+}
+
+func findLeagueTournament(w http.ResponseWriter, r *http.Request) (*models.Tournament, bool) {
+	leagueID, err := uuid.Parse(chi.URLParam(r, "leagueID"))
+	if err != nil {
+		http.Error(w, "Invalid league ID", http.StatusBadRequest)
+		return nil, false
+	}
+	tournamentID, err := uuid.Parse(chi.URLParam(r, "tournamentID"))
+	if err != nil {
+		http.Error(w, "Invalid tournament ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	var tournament models.Tournament
+	if result := database.DB.Where("id = ? AND league_id = ?", tournamentID, leagueID).First(&tournament); result.Error != nil {
+		http.Error(w, "Tournament not found", http.StatusNotFound)
+		return nil, false
+	}
+	return &tournament, true
+}
+
+// generatePoolPlay creates a round robin within each pool. Every round is on
+// the start date, at the matching entry of poolTimes when one is given.
+func generatePoolPlay(tx *gorm.DB, tournament *models.Tournament, pools map[string][]uuid.UUID, poolTimes []string) error {
+	names := make([]string, 0, len(pools))
+	for name := range pools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, round := range algorithms.RoundRobin(pools[name], false) {
+			for position, m := range round {
+				gameTime := ""
+				if m.Round <= len(poolTimes) {
+					gameTime = poolTimes[m.Round-1]
+				}
+				homeID, awayID := m.HomeTeamID, m.AwayTeamID
+				tg := models.TournamentGame{
+					TournamentID: tournament.ID,
+					Stage:        algorithms.StagePool,
+					Pool:         name,
+					Round:        m.Round,
+					Position:     position,
+					HomeTeamID:   &homeID,
+					AwayTeamID:   &awayID,
+					HomeReady:    true,
+					AwayReady:    true,
+					Status:       "pending",
+					Date:         &tournament.StartDate,
+					Time:         gameTime,
+					VenueID:      tournament.VenueID,
+				}
+				if err := tx.Create(&tg).Error; err != nil {
+					return err
+				}
+				if err := scheduleTournamentGame(tx, tournament, &tg); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// generateBracket creates every game of the elimination bracket up front, then
+// places the seeded teams. Teams that are no longer active in the league are
+// left out of the seeding. First-round byes advance immediately.
+func generateBracket(tx *gorm.DB, tournament *models.Tournament, seeds []uuid.UUID) error {
+	seeds, err := confirmedLeagueTeams(tx, tournament.LeagueID, seeds)
+	if err != nil {
+		return err
+	}
+	if len(seeds) < 2 {
+		return errTeamNotInLeague
+	}
+
+	var plan []algorithms.BracketGame
+	if tournament.Format == "double_elimination" {
+		plan = algorithms.DoubleEliminationBracket(len(seeds))
+	} else {
+		plan = algorithms.SingleEliminationBracket(len(seeds))
+	}
+
+	ids := make(map[string]uuid.UUID, len(plan))
+	for _, bg := range plan {
+		ids[bg.Key] = uuid.New()
+	}
+
+	for _, bg := range plan {
+		tg := models.TournamentGame{
+			ID:             ids[bg.Key],
+			TournamentID:   tournament.ID,
+			Stage:          bg.Stage,
+			Round:          bg.Round,
+			Position:       bg.Position,
+			HomeSeed:       bg.HomeSeed,
+			AwaySeed:       bg.AwaySeed,
+			Status:         "pending",
+			VenueID:        tournament.VenueID,
+			WinnerNextSlot: bg.WinnerSlot,
+			LoserNextSlot:  bg.LoserSlot,
+		}
+		if bg.WinnerTo != "" {
+			next := ids[bg.WinnerTo]
+			tg.WinnerNextGameID = &next
+		}
+		if bg.LoserTo != "" {
+			next := ids[bg.LoserTo]
+			tg.LoserNextGameID = &next
+		}
+		if err := tx.Create(&tg).Error; err != nil {
+			return err
+		}
+	}
+
+	for i, teamID := range seeds {
+		if err := tx.Model(&models.TournamentTeam{}).
+			Where("tournament_id = ? AND team_id = ?", tournament.ID, teamID).
+			Update("seed", i+1).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Model(tournament).Update("status", "bracket").Error; err != nil {
+		return err
+	}
+
+	// Seeds beyond the number of teams are byes
+	seedTeam := func(seed int) *uuid.UUID {
+		if seed > len(seeds) {
+			return nil
+		}
+		id := seeds[seed-1]
+		return &id
+	}
+	for _, bg := range plan {
+		if bg.HomeSeed == 0 {
+			continue
+		}
+		if err := fillTournamentSlot(tx, tournament, ids[bg.Key], "home", seedTeam(bg.HomeSeed)); err != nil {
+			return err
+		}
+		if err := fillTournamentSlot(tx, tournament, ids[bg.Key], "away", seedTeam(bg.AwaySeed)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// confirmedLeagueTeams keeps the teams, in order, that are active members of
+// the league, dropping any whose invitation is still pending or that have
+// left.
+func confirmedLeagueTeams(tx *gorm.DB, leagueID uuid.UUID, teamIDs []uuid.UUID) ([]uuid.UUID, error) {
+	var active []uuid.UUID
+	if err := tx.Model(&models.LeagueTeam{}).
+		Where("league_id = ? AND team_id IN ? AND status = ?", leagueID, teamIDs, "active").
+		Pluck("team_id", &active).Error; err != nil {
+		return nil, err
+	}
+	isActive := make(map[uuid.UUID]bool, len(active))
+	for _, id := range active {
+		isActive[id] = true
+	}
+
+	confirmed := make([]uuid.UUID, 0, len(teamIDs))
+	for _, id := range teamIDs {
+		if isActive[id] {
+			confirmed = append(confirmed, id)
+		}
+	}
+	return confirmed, nil
+}
+
+// fillTournamentSlot places a team (or a bye, when teamID is nil) into one
+// side of a bracket game. When both sides are settled the game is either
+// scheduled or, if a side is a bye, decided on the spot.
+func fillTournamentSlot(tx *gorm.DB, tournament *models.Tournament, gameID uuid.UUID, slot string, teamID *uuid.UUID) error {
+	var tg models.TournamentGame
+	if err := tx.First(&tg, "id = ?", gameID).Error; err != nil {
+		return err
+	}
+
+	if slot == "home" {
+		tg.HomeTeamID = teamID
+		tg.HomeReady = true
+	} else {
+		tg.AwayTeamID = teamID
+		tg.AwayReady = true
+	}
+	if !tg.HomeReady || !tg.AwayReady {
+		return tx.Save(&tg).Error
+	}
+
+	if tg.HomeTeamID != nil && tg.AwayTeamID != nil {
+		if err := tx.Save(&tg).Error; err != nil {
+			return err
+		}
+		return scheduleTournamentGame(tx, tournament, &tg)
+	}
+
+	// A bye: the team present (if any) advances without playing
+	tg.Status = "bye"
+	tg.WinnerTeamID = tg.HomeTeamID
+	if tg.WinnerTeamID == nil {
+		tg.WinnerTeamID = tg.AwayTeamID
+	}
+	if err := tx.Save(&tg).Error; err != nil {
+		return err
+	}
+	return advanceTournamentGame(tx, tournament, &tg)
+}
+
+// scheduleTournamentGame creates both teams' Game rows for a tournament game.
+// Bracket games without a date default to the tournament's last day.
+func scheduleTournamentGame(tx *gorm.DB, tournament *models.Tournament, tg *models.TournamentGame) error {
+	var home, away models.Team
+	if err := tx.First(&home, "id = ?", *tg.HomeTeamID).Error; err != nil {
+		return err
+	}
+	if err := tx.First(&away, "id = ?", *tg.AwayTeamID).Error; err != nil {
+		return err
+	}
+
+	date := tournament.EndDate
+	if tg.Date != nil {
+		date = *tg.Date
+	}
+
+	var venue *models.Venue
+	if tg.VenueID != nil {
+		venue = &models.Venue{}
+		if err := tx.First(venue, "id = ?", *tg.VenueID).Error; err != nil {
+			return err
+		}
+	}
+
+	game, err := createLeagueMatchup(tx, tournament.LeagueID, date, tg.Time, venue, home, away)
+	if err != nil {
+		return err
+	}
+	if err := tx.Model(&models.Game{}).Where("matchup_id = ?", *game.MatchupID).Update("tournament_id", tournament.ID).Error; err != nil {
+		return err
+	}
+
+	tg.Date = &date
+	tg.MatchupID = game.MatchupID
+	tg.Status = "scheduled"
+	return tx.Save(tg).Error
+}
+
+// advanceTournamentGame moves the winner and loser of a decided game on to
+// their next games, or finishes the tournament after the final. In double
+// elimination, a grand final won by the losers' bracket champion (the away
+// team) forces a reset game.
+func advanceTournamentGame(tx *gorm.DB, tournament *models.Tournament, tg *models.TournamentGame) error {
+	if tg.WinnerNextGameID != nil {
+		if err := fillTournamentSlot(tx, tournament, *tg.WinnerNextGameID, tg.WinnerNextSlot, tg.WinnerTeamID); err != nil {
+			return err
+		}
+	}
+	if tg.LoserNextGameID != nil {
+		if err := fillTournamentSlot(tx, tournament, *tg.LoserNextGameID, tg.LoserNextSlot, tg.LoserTeamID); err != nil {
+			return err
+		}
+	}
+	if tg.WinnerNextGameID != nil || tg.Stage == algorithms.StagePool {
+		return nil
+	}
+
+	if tg.Stage == algorithms.StageFinal && tournament.Format == "double_elimination" &&
+		tg.AwayTeamID != nil && tg.WinnerTeamID != nil && *tg.WinnerTeamID == *tg.AwayTeamID {
+		reset := models.TournamentGame{
+			TournamentID: tournament.ID,
+			Stage:        algorithms.StageFinalReset,
+			Round:        1,
+			HomeTeamID:   tg.HomeTeamID,
+			AwayTeamID:   tg.AwayTeamID,
+			HomeReady:    true,
+			AwayReady:    true,
+			Status:       "pending",
+			Date:         tg.Date,
+			VenueID:      tg.VenueID,
+		}
+		if err := tx.Create(&reset).Error; err != nil {
+			return err
+		}
+		return scheduleTournamentGame(tx, tournament, &reset)
+	}
+
+	tournament.Status = "completed"
+	tournament.ChampionTeamID = tg.WinnerTeamID
+	return tx.Model(tournament).Updates(map[string]interface{}{
+		"status":           tournament.Status,
+		"champion_team_id": tournament.ChampionTeamID,
+	}).Error
+}
+
+// recordTournamentResult applies a final score recorded on a team's game to
+// the tournament game it belongs to, advancing the bracket the first time a
+// result is recorded. Corrections that keep the same winner are allowed;
+// corrections that change the winner of an already advanced game are not.
+func recordTournamentResult(tx *gorm.DB, game models.Game, finalScore, opponentScore int) error {
+	if game.TournamentID == nil || game.MatchupID == nil {
+		return nil
+	}
+
+	var tg models.TournamentGame
+	if err := tx.Where("matchup_id = ?", *game.MatchupID).First(&tg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	var tournament models.Tournament
+	if err := tx.First(&tournament, "id = ?", tg.TournamentID).Error; err != nil {
+		return err
+	}
+
+	homeScore, awayScore := finalScore, opponentScore
+	if tg.HomeTeamID == nil || *tg.HomeTeamID != game.TeamID {
+		homeScore, awayScore = opponentScore, finalScore
+	}
+
+	var winner, loser *uuid.UUID
+	switch {
+	case homeScore > awayScore:
+		winner, loser = tg.HomeTeamID, tg.AwayTeamID
+	case awayScore > homeScore:
+		winner, loser = tg.AwayTeamID, tg.HomeTeamID
+	}
+	if winner == nil && tg.Stage != algorithms.StagePool {
+		return errTournamentTie
+	}
+
+	alreadyDecided := tg.Status == "completed"
+	if alreadyDecided && tg.Stage != algorithms.StagePool && *tg.WinnerTeamID != *winner {
+		return errBracketAdvanced
+	}
+
+	tg.HomeScore = &homeScore
+	tg.AwayScore = &awayScore
+	tg.WinnerTeamID = winner
+	tg.LoserTeamID = loser
+	tg.Status = "completed"
+	if err := tx.Save(&tg).Error; err != nil {
+		return err
+	}
+
+	if alreadyDecided {
+		return nil
+	}
+	return advanceTournamentGame(tx, &tournament, &tg)
+}
+
+// poolStandings ranks each pool from its pool games only.
+func poolStandings(tx *gorm.DB, tournamentID uuid.UUID) (map[string][]algorithms.StandingsRow, error) {
+	var entries []models.TournamentTeam
+	if err := tx.Preload("Team").Where("tournament_id = ?", tournamentID).Find(&entries).Error; err != nil {
+		return nil, err
+	}
+
+	var poolGames []models.TournamentGame
+	if err := tx.Where("tournament_id = ? AND stage = ? AND matchup_id IS NOT NULL", tournamentID, algorithms.StagePool).
+		Find(&poolGames).Error; err != nil {
+		return nil, err
+	}
+	matchupIDs := make([]uuid.UUID, 0, len(poolGames))
+	for _, tg := range poolGames {
+		matchupIDs = append(matchupIDs, *tg.MatchupID)
+	}
+
+	var games []models.Game
+	if len(matchupIDs) > 0 {
+		if err := tx.Where("matchup_id IN ?", matchupIDs).Find(&games).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	teamsByPool := make(map[string][]models.Team)
+	for _, entry := range entries {
+		if entry.Pool != "" {
+			teamsByPool[entry.Pool] = append(teamsByPool[entry.Pool], entry.Team)
+		}
+	}
+
+	standings := make(map[string][]algorithms.StandingsRow, len(teamsByPool))
+	for pool, teams := range teamsByPool {
+		standings[pool] = algorithms.ComputeStandings(teams, games)
+	}
+	return standings, nil
+}
+
+func buildTournamentResponse(tournamentID uuid.UUID) (*TournamentResponse, error) {
+	var tournament models.Tournament
+	if err := database.DB.
+		Preload("Teams.Team").
+		Preload("Games", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at asc")
+		}).
+		First(&tournament, "id = ?", tournamentID).Error; err != nil {
+		return nil, err
+	}
+
+	standings, err := poolStandings(database.DB, tournament.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &TournamentResponse{
+		Tournament:    tournament,
+		Teams:         make([]TournamentTeamView, 0, len(tournament.Teams)),
+		PoolStandings: standings,
+		Games:         make([]TournamentGameView, 0, len(tournament.Games)),
+	}
+
+	names := make(map[uuid.UUID]string, len(tournament.Teams))
+	for _, entry := range tournament.Teams {
+		names[entry.TeamID] = entry.Team.Name
+		response.Teams = append(response.Teams, TournamentTeamView{
+			TeamID:  entry.TeamID,
+			Name:    entry.Team.Name,
+			LogoURL: entry.Team.LogoURL,
+			Pool:    entry.Pool,
+			Seed:    entry.Seed,
+		})
+	}
+	sort.SliceStable(response.Teams, func(i, j int) bool {
+		a, b := response.Teams[i], response.Teams[j]
+		if a.Pool != b.Pool {
+			return a.Pool < b.Pool
+		}
+		if a.Seed != b.Seed {
+			return a.Seed != 0 && (b.Seed == 0 || a.Seed < b.Seed)
+		}
+		return a.Name < b.Name
+	})
+
+	nameOf := func(id *uuid.UUID) string {
+		if id == nil {
+			return ""
+		}
+		return names[*id]
+	}
+	stageOrder := map[string]int{
+		algorithms.StagePool:       0,
+		algorithms.StageWinners:    1,
+		algorithms.StageLosers:     2,
+		algorithms.StageFinal:      3,
+		algorithms.StageFinalReset: 4,
+	}
+	for _, tg := range tournament.Games {
+		response.Games = append(response.Games, TournamentGameView{
+			TournamentGame: tg,
+			HomeTeamName:   nameOf(tg.HomeTeamID),
+			AwayTeamName:   nameOf(tg.AwayTeamID),
+			WinnerTeamName: nameOf(tg.WinnerTeamID),
+		})
+	}
+	sort.SliceStable(response.Games, func(i, j int) bool {
+		a, b := response.Games[i], response.Games[j]
+		if stageOrder[a.Stage] != stageOrder[b.Stage] {
+			return stageOrder[a.Stage] < stageOrder[b.Stage]
+		}
+		if a.Pool != b.Pool {
+			return a.Pool < b.Pool
+		}
+		if a.Round != b.Round {
+			return a.Round < b.Round
+		}
+		return a.Position < b.Position
+	})
+
+	response.ChampionName = nameOf(tournament.ChampionTeamID)
+	response.Tournament.Teams = nil
+	response.Tournament.Games = nil
+	return response, nil
+}
@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/liam/screaming-toller/backend/internal/database"
+	"github.com/liam/screaming-toller/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type VenueRequest struct {
+	Name          string   `json:"name"`
+	Address       string   `json:"address"`
+	Latitude      *float64 `json:"latitude"`
+	Longitude     *float64 `json:"longitude"`
+	DiamondNumber string   `json:"diamondNumber"`
+	ParkingNotes  string   `json:"parkingNotes"`
+	Timezone      string   `json:"timezone"`
+}
+
+// validate checks the request and returns a user-facing error message, or "" if valid.
+func (req *VenueRequest) validate() string {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return "Venue name is required"
+	}
+	if (req.Latitude == nil) != (req.Longitude == nil) {
+		return "Latitude and longitude must be provided together"
+	}
+	if req.Latitude != nil && (*req.Latitude < -90 || *req.Latitude > 90 || *req.Longitude < -180 || *req.Longitude > 180) {
+		return "Invalid coordinates"
+	}
+	if req.Timezone != "" {
+		if _, err := time.LoadLocation(req.Timezone); err != nil {
+			return "Invalid timezone. Use an IANA name such as America/Vancouver"
+		}
+	}
+	return ""
+}
+
+func GetTeamVenues(w http.ResponseWriter, r *http.Request) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+		return
+	}
+
+	// Include venues shared by the leagues this team plays in
+	var venues []models.Venue
+	if result := teamVenuesScope(database.DB, teamID).Order("name asc").Find(&venues); result.Error != nil {
+		http.Error(w, "Failed to fetch venues", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(venues)
+}
+
+func CreateVenue(w http.ResponseWriter, r *http.Request) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+		return
+	}
+
+	var req VenueRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	venue := models.Venue{TeamID: &teamID}
+	createVenue(w, &venue, req)
+}
+
+func UpdateVenue(w http.ResponseWriter, r *http.Request) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+		return
+	}
+	venueID, err := uuid.Parse(chi.URLParam(r, "venueID"))
+	if err != nil {
+		http.Error(w, "Invalid venue ID", http.StatusBadRequest)
+		return
+	}
+
+	var venue models.Venue
+	if result := database.DB.Where("id = ? AND team_id = ?", venueID, teamID).First(&venue); result.Error != nil {
+		http.Error(w, "Venue not found", http.StatusNotFound)
+		return
+	}
+
+	var req VenueRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	saveVenue(w, &venue, req)
+}
+
+func DeleteVenue(w http.ResponseWriter, r *http.Request) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+		return
+	}
+	venueID, err := uuid.Parse(chi.URLParam(r, "venueID"))
+	if err != nil {
+		http.Error(w, "Invalid venue ID", http.StatusBadRequest)
+		return
+	}
+
+	var venue models.Venue
+	if result := database.DB.Where("id = ? AND team_id = ?", venueID, teamID).First(&venue); result.Error != nil {
+		http.Error(w, "Venue not found", http.StatusNotFound)
+		return
+	}
+
+	deleteVenue(w, &venue)
+}
+
+func GetLeagueVenues(w http.ResponseWriter, r *http.Request) {
+	leagueID, err := uuid.Parse(chi.URLParam(r, "leagueID"))
+	if err != nil {
+		http.Error(w, "Invalid league ID", http.StatusBadRequest)
+		return
+	}
+
+	var venues []models.Venue
+	if result := database.DB.Where("league_id = ?", leagueID).Order("name asc").Find(&venues); result.Error != nil {
+		http.Error(w, "Failed to fetch venues", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(venues)
+}
+
+func CreateLeagueVenue(w http.ResponseWriter, r *http.Request) {
+	leagueID, err := uuid.Parse(chi.URLParam(r, "leagueID"))
+	if err != nil {
+		http.Error(w, "Invalid league ID", http.StatusBadRequest)
+		return
+	}
+
+	var req VenueRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	venue := models.Venue{LeagueID: &leagueID}
+	createVenue(w, &venue, req)
+}
+
+func UpdateLeagueVenue(w http.ResponseWriter, r *http.Request) {
+	leagueID, err := uuid.Parse(chi.URLParam(r, "leagueID"))
+	if err != nil {
+		http.Error(w, "Invalid league ID", http.StatusBadRequest)
+		return
+	}
+	venueID, err := uuid.Parse(chi.URLParam(r, "venueID"))
+	if err != nil {
+		http.Error(w, "Invalid venue ID", http.StatusBadRequest)
+		return
+	}
+
+	var venue models.Venue
+	if result := database.DB.Where("id = ? AND league_id = ?", venueID, leagueID).First(&venue); result.Error != nil {
+		http.Error(w, "Venue not found", http.StatusNotFound)
+		return
+	}
+
+	var req VenueRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	saveVenue(w, &venue, req)
+}
+
+func DeleteLeagueVenue(w http.ResponseWriter, r *http.Request) {
+	leagueID, err := uuid.Parse(chi.URLParam(r, "leagueID"))
+	if err != nil {
+		http.Error(w, "Invalid league ID", http.StatusBadRequest)
+		return
+	}
+	venueID, err := uuid.Parse(chi.URLParam(r, "venueID"))
+	if err != nil {
+		http.Error(w, "Invalid venue ID", http.StatusBadRequest)
+		return
+	}
+
+	var venue models.Venue
+	if result := database.DB.Where("id = ? AND league_id = ?", venueID, leagueID).First(&venue); result.Error != nil {
+		http.Error(w, "Venue not found", http.StatusNotFound)
+		return
+	}
+
+	deleteVenue(w, &venue)
+}
+
+func applyVenueRequest(venue *models.Venue, req VenueRequest) {
+	venue.Name = req.Name
+	venue.Address = req.Address
+	venue.Latitude = req.Latitude
+	venue.Longitude = req.Longitude
+	venue.DiamondNumber = req.DiamondNumber
+	venue.ParkingNotes = req.ParkingNotes
+	venue.Timezone = req.Timezone
+}
+
+func createVenue(w http.ResponseWriter, venue *models.Venue, req VenueRequest) {
+	applyVenueRequest(venue, req)
+
+	if result := database.DB.Create(venue); result.Error != nil {
+		http.Error(w, "Failed to create venue", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(venue)
+}
+
+func saveVenue(w http.ResponseWriter, venue *models.Venue, req VenueRequest) {
+	oldName := venue.Name
+	applyVenueRequest(venue, req)
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(venue).Error; err != nil {
+			return err
+		}
+		if venue.Name == oldName {
+			return nil
+		}
+		// Keep the display fallback on upcoming linked games in sync with the
+		// venue name. Past games, and games given a location of their own,
+		// keep theirs.
+		return tx.Model(&models.Game{}).
+			Where("venue_id = ? AND location = ? AND date >= ?", venue.ID, oldName, today()).
+			Update("location", venue.Name).Error
+	})
+	if err != nil {
+		http.Error(w, "Failed to update venue", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(venue)
+}
+
+func deleteVenue(w http.ResponseWriter, venue *models.Venue) {
+	// Games keep their Location text; the foreign key is nulled by the constraint
+	if result := database.DB.Delete(venue); result.Error != nil {
+		http.Error(w, "Failed to delete venue", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// teamVenuesScope selects the venues a team can use: its own plus those shared
+// by any league the team belongs to. db is database.DB or a transaction.
+func teamVenuesScope(db *gorm.DB, teamID uuid.UUID) *gorm.DB {
+	return db.Where("team_id = ? OR league_id IN (?)", teamID,
+		db.Model(&models.LeagueTeam{}).Select("league_id").Where("team_id = ? AND status = ?", teamID, "active"))
+}
+
+// findTeamVenue loads a venue and verifies it is usable by the given team,
+// reading through db (database.DB or a transaction).
+func findTeamVenue(db *gorm.DB, teamID, venueID uuid.UUID) (*models.Venue, error) {
+	var venue models.Venue
+	if err := teamVenuesScope(db, teamID).Where("id = ?", venueID).First(&venue).Error; err != nil {
+		return nil, err
+	}
+	return &venue, nil
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/liam/screaming-toller/backend/internal/services"
+)
+
+// webhookBodyLimit caps the size of a webhook request body.
+const webhookBodyLimit = 1 << 20
+
+// WhapiWebhook receives message events from Whapi so players can RSVP by
+// replying "in", "out" or "maybe" in their team's WhatsApp group. Each request
+// must be signed: an X-Webhook-Signature header holding the hex HMAC-SHA256
+// of the raw body, keyed with WHAPI_WEBHOOK_SECRET (optionally prefixed
+// "sha256=").
+func WhapiWebhook(w http.ResponseWriter, r *http.Request) {
+	secret := os.Getenv("WHAPI_WEBHOOK_SECRET")
+	if secret == "" {
+		http.Error(w, "WhatsApp webhook is not configured", http.StatusServiceUnavailable)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, webhookBodyLimit))
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !validWhapiSignature(secret, body, r.Header.Get("X-Webhook-Signature")) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var payload services.WhapiWebhook
+	if err := json.Unmarshal(body, &payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	whatsAppService, err := services.NewWhatsAppService()
+	if err != nil {
+		log.Printf("WhatsApp Inbound Error: %v", err)
+		http.Error(w, "WhatsApp is not configured", http.StatusServiceUnavailable)
+		return
+	}
+	for _, msg := range payload.Messages {
+		whatsAppService.HandleInboundMessage(msg)
+	}
+	if len(payload.Messages) > 0 {
+		log.Printf("WhatsApp Inbound: Processed %d message(s)", len(payload.Messages))
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// validWhapiSignature reports whether signature is the hex HMAC-SHA256 of
+// body keyed with secret.
+func validWhapiSignature(secret string, body []byte, signature string) bool {
+	given, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(given, mac.Sum(nil))
+}
+
+// SMSWebhook receives replies sent to the SMS number, in Twilio's form-encoded
+// format (From, Body), so players can text STOP or START. Requests must carry
+// Twilio's X-Twilio-Signature, made with TWILIO_AUTH_TOKEN. The reply, if
+// any, is returned as TwiML.
+func SMSWebhook(w http.ResponseWriter, r *http.Request) {
+	authToken := os.Getenv("TWILIO_AUTH_TOKEN")
+	if authToken == "" {
+		http.Error(w, "SMS webhook is not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, webhookBodyLimit)
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !validTwilioSignature(authToken, smsWebhookURL(r), r.PostForm, r.Header.Get("X-Twilio-Signature")) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	reply := services.HandleInboundSMS(r.PostForm.Get("From"), r.PostForm.Get("Body"))
+
+	w.Header().Set("Content-Type", "application/xml")
+	if reply == "" {
+		w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><Response></Response>`))
+		return
+	}
+	var escaped bytes.Buffer
+	xml.EscapeText(&escaped, []byte(reply))
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><Response><Message>%s</Message></Response>`, escaped.String())
+}
+
+// smsWebhookURL is the URL Twilio signed: SMS_WEBHOOK_URL when set (it must
+// match the number's webhook setting exactly), or else the URL the request
+// arrived at.
+func smsWebhookURL(r *http.Request) string {
+	if url := os.Getenv("SMS_WEBHOOK_URL"); url != "" {
+		return url
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
+
+// validTwilioSignature reports whether signature is Twilio's signature of the
+// request: the base64 HMAC-SHA1, keyed with the auth token, of the full URL
+// followed by each POST parameter's name and value, sorted by name.
+func validTwilioSignature(authToken, url string, params map[string][]string, signature string) bool {
+	given, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	mac := hmac.New(sha1.New, []byte(authToken))
+	mac.Write([]byte(url))
+	for _, key := range keys {
+		for _, value := range params[key] {
+			mac.Write([]byte(key + value))
+		}
+	}
+	return hmac.Equal(given, mac.Sum(nil))
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/liam/screaming-toller/backend/internal/database"
+	"github.com/liam/screaming-toller/backend/internal/models"
+)
+
+// RequireLeagueAccess allows league admins, super admins and active members of
+// any team that has joined the league. Whether the caller is a league admin is stored in
+// the context under "leagueAdmin".
+func RequireLeagueAccess(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		leagueID, err := uuid.Parse(chi.URLParam(r, "leagueID"))
+		if err != nil {
+			http.Error(w, "Invalid league ID", http.StatusBadRequest)
+			return
+		}
+
+		userID, ok := r.Context().Value("userID").(uuid.UUID)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var user models.User
+		if err := database.DB.First(&user, "id = ?", userID).Error; err != nil {
+			http.Error(w, "User not found", http.StatusUnauthorized)
+			return
+		}
+
+		var adminCount int64
+		database.DB.Model(&models.LeagueAdmin{}).Where("league_id = ? AND user_id = ?", leagueID, userID).Count(&adminCount)
+		isAdmin := adminCount > 0 || user.IsSuperAdmin
+
+		if !isAdmin {
+			var memberCount int64
+			database.DB.Model(&models.TeamMember{}).
+				Joins("JOIN league_teams ON league_teams.team_id = team_members.team_id").
+				Where("league_teams.league_id = ? AND league_teams.status = ?", leagueID, "active").
+				Where("team_members.user_id = ? AND team_members.is_active = ?", userID, true).
+				Count(&memberCount)
+			if memberCount == 0 {
+				http.Error(w, "Not a member of this league", http.StatusForbidden)
+				return
+			}
+		}
+
+		ctx := context.WithValue(r.Context(), "leagueAdmin", isAdmin)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func RequireLeagueAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		isAdmin, ok := r.Context().Value("leagueAdmin").(bool)
+
+		if !ok || !isAdmin {
+			http.Error(w, "Requires league admin role", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"fmt"
+	"net/url"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,6 +17,9 @@ type User struct {
 	IsSuperAdmin bool      `gorm:"default:false" json:"isSuperAdmin"`
 	OptOutReminders bool   `gorm:"default:false" json:"optOutReminders"`
 	WhapiToken      string    `json:"whapiToken,omitempty"` // Encrypted
+	Phone           string    `gorm:"uniqueIndex:idx_users_phone,where:phone <> '' AND phone_verified_at IS NOT NULL" json:"phone"` // Digits only with country code, e.g. "16045551234"; one verified account per number
+	Locale          string    `json:"locale"` // Language for their messages, e.g. "fr"; "" uses the team's
+	PhoneVerifiedAt *time.Time `json:"phoneVerifiedAt,omitempty"` // When they confirmed the code texted to Phone; cleared when Phone changes
 	CreatedAt       time.Time `json:"createdAt"`
 	UpdatedAt       time.Time `json:"updatedAt"`
 }
@@ -36,7 +41,14 @@ type Team struct {
 	Status           string      `gorm:"default:'pending'" json:"status"` // "pending", "active", "rejected"
 	IsActive         bool        `gorm:"default:true" json:"isActive"`
 	WhatsAppGroupID  string      `gorm:"default:''" json:"whatsAppGroupId"` // Whapi group chat ID, e.g. "120363xxx@g.us"
-	WhapiTokenSourceUserID *uuid.UUID `gorm:"type:uuid" json:"whapiTokenSourceUserId,omitempty"`
+	WhapiTokenSourceUserID *uuid.UUID `gorm:"type:uuid" json:"whapiTokenSourceUserId,omitempty"` // Fallback when the team has no token of its own (TeamIntegration)
+	// RSVP deadline defaults: hours before start (0 = no deadline), and what
+	// happens once it passes: "lock" stops players changing their RSVP,
+	// "convert" turns unanswered "maybe" entries into RSVPMaybeStatus.
+	RSVPDeadlineHours  int    `gorm:"default:0" json:"rsvpDeadlineHours"`
+	RSVPDeadlineAction string `gorm:"default:'lock'" json:"rsvpDeadlineAction"` // "lock" or "convert"
+	RSVPMaybeStatus    string `gorm:"default:'not_going'" json:"rsvpMaybeStatus"` // "going" or "not_going"
+	Locale             string `json:"locale"` // Language for team messages and the WhatsApp group; "" is English
 	CreatedAt        time.Time   `json:"createdAt"`
 	UpdatedAt        time.Time   `json:"updatedAt"`
 	Membership       *TeamMember `gorm:"-" json:"membership,omitempty"`
@@ -57,6 +69,10 @@ type TeamMember struct {
 	Role     string    `json:"role"`   // "player", "pitcher" (admin is now IsAdmin)
 	IsAdmin  bool      `gorm:"default:false" json:"isAdmin"`
 	IsActive bool      `gorm:"default:true" json:"isActive"`
+	// IsSpare marks a non-roster player who accepted a spare request. Spares
+	// are kept inactive so they stay off the roster, but hold attendance for
+	// the games they agreed to play.
+	IsSpare  bool      `gorm:"default:false" json:"isSpare"`
 	JoinedAt time.Time `json:"joinedAt"`
 	LeftAt   *time.Time `json:"leftAt,omitempty"`
 
@@ -97,15 +113,38 @@ type Game struct {
 	Time                     string     `json:"time"`
 	Location                 string     `json:"location"`
 	OpposingTeam             string     `json:"opposingTeam"`
+	OpponentTeamID           *uuid.UUID `gorm:"type:uuid;index" json:"opponentTeamId,omitempty"` // Set when the opponent is also on the platform
+	LeagueID                 *uuid.UUID `gorm:"type:uuid;index" json:"leagueId,omitempty"`
+	// MatchupID is shared by the home and away teams' rows for the same
+	// league game, so scores recorded on one side can be mirrored to the other.
+	MatchupID                *uuid.UUID `gorm:"type:uuid;index" json:"matchupId,omitempty"`
+	TournamentID             *uuid.UUID `gorm:"type:uuid;index" json:"tournamentId,omitempty"`
 	IsHome                   bool       `gorm:"default:true" json:"isHome"`
 	FinalScore               *int       `json:"finalScore,omitempty"`
 	OpponentScore            *int       `json:"opponentScore,omitempty"`
-	Status                   string     `gorm:"default:'scheduled'" json:"status"` // "scheduled", "in_progress", "completed", "cancelled"
+	Status                   string     `gorm:"default:'scheduled'" json:"status"` // "scheduled", "in_progress", "completed", "cancelled", "postponed"
 	WhatsAppReminderSentAt   *time.Time `json:"whatsAppReminderSentAt,omitempty"` // Set when group WA reminder is sent
+	LineupPublishedAt        *time.Time `json:"lineupPublishedAt,omitempty"` // Lineup is frozen while set
+	LineupPublishedBy        *uuid.UUID `gorm:"type:uuid" json:"lineupPublishedBy,omitempty"`
+	RSVPDeadline             *time.Time `json:"rsvpDeadline,omitempty"`   // Overrides the team's default offset
+	RSVPResolvedAt           *time.Time `json:"rsvpResolvedAt,omitempty"` // Set once the passed deadline has been applied
+	RSVPDeadlineAt           *time.Time `gorm:"-" json:"rsvpDeadlineAt,omitempty"` // Effective deadline, filled in by handlers
+	// Forfeit risk alerts escalate as the game nears; the level resets to 0
+	// once enough players confirm.
+	ForfeitAlertLevel        int        `gorm:"default:0" json:"forfeitAlertLevel"`
+	ForfeitAlertSentAt       *time.Time `json:"forfeitAlertSentAt,omitempty"`
+	VenueID                  *uuid.UUID `gorm:"type:uuid;index" json:"venueId,omitempty"`
+	// Postponement history. A postponed game keeps its attendance and lineups;
+	// the make-up game links back to it via RescheduledFromID.
+	PostponedAt              *time.Time `json:"postponedAt,omitempty"`
+	PostponedReason          string     `gorm:"default:''" json:"postponedReason,omitempty"`
+	RescheduledFromID        *uuid.UUID `gorm:"type:uuid;index" json:"rescheduledFromId,omitempty"`
+	RescheduledToID          *uuid.UUID `gorm:"type:uuid" json:"rescheduledToId,omitempty"`
 	CreatedAt                time.Time  `json:"createdAt"`
 	UpdatedAt                time.Time  `json:"updatedAt"`
 
 	Team         Team          `gorm:"foreignKey:TeamID" json:"team,omitempty"`
+	Venue        *Venue        `gorm:"foreignKey:VenueID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"venue,omitempty"`
 	InningScores []InningScore `gorm:"foreignKey:GameID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"inningScores,omitempty"`
 }
 
@@ -116,11 +155,480 @@ func (g *Game) BeforeCreate(tx *gorm.DB) (err error) {
 	return
 }
 
+// Venue is a field or park where games are played. Games reference a venue
+// by VenueID; Game.Location is kept as a display fallback for older games.
+type Venue struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	TeamID        *uuid.UUID `gorm:"type:uuid;index" json:"teamId,omitempty"`
+	LeagueID      *uuid.UUID `gorm:"type:uuid;index" json:"leagueId,omitempty"` // Shared by every team in the league
+	Name          string     `json:"name"`
+	Address       string     `json:"address"`
+	Latitude      *float64   `json:"latitude,omitempty"`
+	Longitude     *float64   `json:"longitude,omitempty"`
+	DiamondNumber string     `gorm:"default:''" json:"diamondNumber"`
+	ParkingNotes  string     `gorm:"default:''" json:"parkingNotes"`
+	Timezone      string     `gorm:"default:''" json:"timezone"` // IANA name, e.g. "America/Vancouver"; empty = server default
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+}
+
+func (v *Venue) BeforeCreate(tx *gorm.DB) (err error) {
+	if v.ID == uuid.Nil {
+		v.ID = uuid.New()
+	}
+	return
+}
+
+// MapsURL returns a Google Maps link for the venue, preferring coordinates
+// over the street address. Returns "" if neither is set.
+func (v *Venue) MapsURL() string {
+	if v.Latitude != nil && v.Longitude != nil {
+		return fmt.Sprintf("https://www.google.com/maps/search/?api=1&query=%f,%f", *v.Latitude, *v.Longitude)
+	}
+	if v.Address != "" {
+		return "https://www.google.com/maps/search/?api=1&query=" + url.QueryEscape(v.Address)
+	}
+	return ""
+}
+
+// League groups teams that play each other so they can share venues and a
+// standings table. Team.League is kept as a display name for teams not
+// linked to a league.
+type League struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	Name        string    `json:"name"`
+	Season      string    `json:"season"`
+	Description string    `json:"description"`
+	CreatedBy   uuid.UUID `gorm:"type:uuid" json:"createdBy"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+
+	Teams  []LeagueTeam  `gorm:"foreignKey:LeagueID;constraint:OnDelete:CASCADE;" json:"teams,omitempty"`
+	Admins []LeagueAdmin `gorm:"foreignKey:LeagueID;constraint:OnDelete:CASCADE;" json:"admins,omitempty"`
+}
+
+func (l *League) BeforeCreate(tx *gorm.DB) (err error) {
+	if l.ID == uuid.Nil {
+		l.ID = uuid.New()
+	}
+	return
+}
+
+// LeagueTeam links a team to a league. A league admin invites the team and
+// it only takes part (standings, schedules, tournaments, shared venues and
+// spares) once one of its team admins accepts.
+type LeagueTeam struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	LeagueID  uuid.UUID  `gorm:"type:uuid;index" json:"leagueId"`
+	TeamID    uuid.UUID  `gorm:"type:uuid;index" json:"teamId"`
+	Status    string     `gorm:"default:'active'" json:"status"` // "invited" or "active"
+	InvitedBy *uuid.UUID `gorm:"type:uuid" json:"invitedBy,omitempty"`
+	JoinedAt  time.Time  `json:"joinedAt"` // When the invitation was accepted
+
+	Team Team `gorm:"foreignKey:TeamID" json:"team,omitempty"`
+}
+
+func (lt *LeagueTeam) BeforeCreate(tx *gorm.DB) (err error) {
+	if lt.ID == uuid.Nil {
+		lt.ID = uuid.New()
+	}
+	if lt.JoinedAt.IsZero() {
+		lt.JoinedAt = time.Now()
+	}
+	return
+}
+
+type LeagueAdmin struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	LeagueID  uuid.UUID `gorm:"type:uuid;index" json:"leagueId"`
+	UserID    uuid.UUID `gorm:"type:uuid;index" json:"userId"`
+	CreatedAt time.Time `json:"createdAt"`
+
+	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+func (la *LeagueAdmin) BeforeCreate(tx *gorm.DB) (err error) {
+	if la.ID == uuid.Nil {
+		la.ID = uuid.New()
+	}
+	return
+}
+
+// Tournament is an event between league teams: optional pool play followed by
+// a single or double elimination bracket seeded from the pool results.
+type Tournament struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	LeagueID       uuid.UUID  `gorm:"type:uuid;index" json:"leagueId"`
+	Name           string     `json:"name"`
+	Format         string     `gorm:"default:'single_elimination'" json:"format"` // "single_elimination", "double_elimination"
+	Status         string     `gorm:"default:'pool_play'" json:"status"`          // "pool_play", "bracket", "completed"
+	StartDate      time.Time  `gorm:"type:date" json:"startDate"`
+	EndDate        time.Time  `gorm:"type:date" json:"endDate"`
+	VenueID        *uuid.UUID `gorm:"type:uuid" json:"venueId,omitempty"`
+	ChampionTeamID *uuid.UUID `gorm:"type:uuid" json:"championTeamId,omitempty"`
+	CreatedBy      uuid.UUID  `gorm:"type:uuid" json:"createdBy"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+
+	Teams []TournamentTeam `gorm:"foreignKey:TournamentID;constraint:OnDelete:CASCADE;" json:"teams,omitempty"`
+	Games []TournamentGame `gorm:"foreignKey:TournamentID;constraint:OnDelete:CASCADE;" json:"games,omitempty"`
+}
+
+func (t *Tournament) BeforeCreate(tx *gorm.DB) (err error) {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return
+}
+
+type TournamentTeam struct {
+	ID           uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	TournamentID uuid.UUID `gorm:"type:uuid;index" json:"tournamentId"`
+	TeamID       uuid.UUID `gorm:"type:uuid;index" json:"teamId"`
+	Pool         string    `json:"pool"`
+	Seed         int       `json:"seed"` // Bracket seed, 0 until the bracket is generated or if eliminated in pool play
+
+	Team Team `gorm:"foreignKey:TeamID" json:"team,omitempty"`
+}
+
+func (tt *TournamentTeam) BeforeCreate(tx *gorm.DB) (err error) {
+	if tt.ID == uuid.Nil {
+		tt.ID = uuid.New()
+	}
+	return
+}
+
+// TournamentGame is one slot in a tournament's pool play or bracket. Once both
+// teams are known, the teams' Game rows are created and linked by MatchupID;
+// scores recorded on those games advance the winner (and, in double
+// elimination, the loser) to the next games.
+type TournamentGame struct {
+	ID           uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	TournamentID uuid.UUID  `gorm:"type:uuid;index" json:"tournamentId"`
+	Stage        string     `json:"stage"` // "pool", "winners", "losers", "final", "final_reset"
+	Pool         string     `json:"pool,omitempty"`
+	Round        int        `json:"round"`
+	Position     int        `json:"position"`
+	HomeTeamID   *uuid.UUID `gorm:"type:uuid" json:"homeTeamId,omitempty"`
+	AwayTeamID   *uuid.UUID `gorm:"type:uuid" json:"awayTeamId,omitempty"`
+	HomeSeed     int        `json:"homeSeed,omitempty"`
+	AwaySeed     int        `json:"awaySeed,omitempty"`
+	// A slot is ready once its team is known or it is known to be a bye
+	HomeReady    bool       `json:"homeReady"`
+	AwayReady    bool       `json:"awayReady"`
+	HomeScore    *int       `json:"homeScore,omitempty"`
+	AwayScore    *int       `json:"awayScore,omitempty"`
+	WinnerTeamID *uuid.UUID `gorm:"type:uuid" json:"winnerTeamId,omitempty"`
+	LoserTeamID  *uuid.UUID `gorm:"type:uuid" json:"loserTeamId,omitempty"`
+	Status       string     `gorm:"default:'pending'" json:"status"` // "pending", "scheduled", "completed", "bye"
+	Date         *time.Time `gorm:"type:date" json:"date,omitempty"`
+	Time         string     `json:"time"`
+	VenueID      *uuid.UUID `gorm:"type:uuid" json:"venueId,omitempty"`
+	MatchupID    *uuid.UUID `gorm:"type:uuid;index" json:"matchupId,omitempty"`
+
+	WinnerNextGameID *uuid.UUID `gorm:"type:uuid" json:"winnerNextGameId,omitempty"`
+	WinnerNextSlot   string     `json:"winnerNextSlot,omitempty"` // "home" or "away"
+	LoserNextGameID  *uuid.UUID `gorm:"type:uuid" json:"loserNextGameId,omitempty"`
+	LoserNextSlot    string     `json:"loserNextSlot,omitempty"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func (tg *TournamentGame) BeforeCreate(tx *gorm.DB) (err error) {
+	if tg.ID == uuid.Nil {
+		tg.ID = uuid.New()
+	}
+	return
+}
+
+// RSVPToken backs a one-click RSVP link sent in a reminder email. The link
+// carries the token ID and its signature; the row records when it expires
+// and the answer it was used for. Each token can be used once.
+type RSVPToken struct {
+	ID           uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	AttendanceID uuid.UUID  `gorm:"type:uuid;index" json:"attendanceId"`
+	ExpiresAt    time.Time  `json:"expiresAt"`
+	UsedAt       *time.Time `json:"usedAt,omitempty"`     // When the link was used; it can't be used again
+	UsedStatus   string     `json:"usedStatus,omitempty"` // The answer given with the link
+	CreatedAt    time.Time  `json:"createdAt"`
+
+	Attendance Attendance `gorm:"foreignKey:AttendanceID;constraint:OnDelete:CASCADE;" json:"-"`
+}
+
+func (t *RSVPToken) BeforeCreate(tx *gorm.DB) (err error) {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return
+}
+
+// PhoneVerification is the code texted to a user to confirm their phone
+// number before any other text is sent to it. Only the latest code counts.
+type PhoneVerification struct {
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID     uuid.UUID `gorm:"type:uuid;uniqueIndex" json:"userId"`
+	Phone      string    `json:"phone"` // The number the code was sent to
+	CodeHash   string    `json:"-"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	Attempts   int       `json:"attempts"`   // Wrong codes entered
+	Sends      int       `json:"sends"`      // Codes sent since SendsSince, to any number
+	SendsSince time.Time `json:"sendsSince"` // Start of the day the sends are counted over
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"` // When the code was last sent
+
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;" json:"-"`
+}
+
+func (v *PhoneVerification) BeforeCreate(tx *gorm.DB) (err error) {
+	if v.ID == uuid.Nil {
+		v.ID = uuid.New()
+	}
+	return
+}
+
+// ReminderRule is one attendance reminder a team sends before each game:
+// OffsetHours before the start, to members whose RSVP is one of Statuses, on
+// each of Channels ("preferred" for the member's notification preferences,
+// "email", "whatsapp", or "whatsapp_group" for one post in the team's group).
+type ReminderRule struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	TeamID      uuid.UUID `gorm:"type:uuid;index" json:"teamId"`
+	OffsetHours int       `json:"offsetHours"`
+	Statuses    []string  `gorm:"serializer:json" json:"statuses"`
+	Channels    []string  `gorm:"serializer:json" json:"channels"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+
+	Team Team `gorm:"foreignKey:TeamID;constraint:OnDelete:CASCADE;" json:"-"`
+}
+
+func (rr *ReminderRule) BeforeCreate(tx *gorm.DB) (err error) {
+	if rr.ID == uuid.Nil {
+		rr.ID = uuid.New()
+	}
+	return
+}
+
+// ReminderSend records that a reminder rule has fired for one attendance
+// record, so each rule reminds each player once per game. AttendanceID is
+// uuid.Nil for the rule's WhatsApp group post. RuleKey is the rule's ID, or
+// "default" for the built-in rule used by teams without any.
+type ReminderSend struct {
+	ID           uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	RuleKey      string    `gorm:"uniqueIndex:idx_reminder_send" json:"ruleKey"`
+	GameID       uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_reminder_send" json:"gameId"`
+	AttendanceID uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_reminder_send" json:"attendanceId"`
+	SentAt       time.Time `json:"sentAt"`
+}
+
+func (rs *ReminderSend) BeforeCreate(tx *gorm.DB) (err error) {
+	if rs.ID == uuid.Nil {
+		rs.ID = uuid.New()
+	}
+	return
+}
+
+// MessageTemplate is a team's own wording for one part of a built-in message
+// in one locale, e.g. the subject of the attendance reminder in French. Body
+// is a Go template over the same values as the built-in one.
+type MessageTemplate struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	TeamID    uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_message_template" json:"teamId"`
+	Name      string    `gorm:"uniqueIndex:idx_message_template" json:"name"`   // e.g. "attendance_reminder"
+	Locale    string    `gorm:"uniqueIndex:idx_message_template" json:"locale"` // "en" or "fr"
+	Part      string    `gorm:"uniqueIndex:idx_message_template" json:"part"`   // "subject", "text", "html" or "whatsapp"
+	Body      string    `gorm:"type:text" json:"body"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+
+	Team Team `gorm:"foreignKey:TeamID;constraint:OnDelete:CASCADE;" json:"-"`
+}
+
+func (mt *MessageTemplate) BeforeCreate(tx *gorm.DB) (err error) {
+	if mt.ID == uuid.Nil {
+		mt.ID = uuid.New()
+	}
+	return
+}
+
+// ScheduledJob is background work due at a set time, such as a game's
+// reminder or RSVP deadline. Key identifies what the job is for, so moving a
+// game moves its jobs rather than adding more.
+type ScheduledJob struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	Kind        string     `gorm:"index" json:"kind"` // "reminder", "rsvp_deadline", "forfeit_check", "spare_invites", "reconcile"
+	Key         string     `gorm:"uniqueIndex" json:"key"`
+	GameID      *uuid.UUID `gorm:"type:uuid;index" json:"gameId,omitempty"`
+	Payload     string     `json:"payload,omitempty"`                                                      // Kind-specific, e.g. the reminder rule key
+	FireAt      time.Time  `json:"fireAt"`                                                                 // When the job is meant to run, e.g. the reminder's time
+	RunAt       time.Time  `gorm:"index:idx_scheduled_job_due,priority:2" json:"runAt"`                    // When it will next run: FireAt, or later after a failed attempt
+	Status      string     `gorm:"default:'pending';index:idx_scheduled_job_due,priority:1" json:"status"` // "pending", "done", "failed"
+	Attempts    int        `gorm:"default:0" json:"attempts"`
+	LastError   string     `json:"lastError,omitempty"`
+	LockedUntil *time.Time `json:"lockedUntil,omitempty"` // Set while a worker is running the job
+	LockedBy    string     `json:"lockedBy,omitempty"`    // Host and process ID of that worker
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+func (j *ScheduledJob) BeforeCreate(tx *gorm.DB) (err error) {
+	if j.ID == uuid.Nil {
+		j.ID = uuid.New()
+	}
+	return
+}
+
+// PushSubscription is one browser or device a user has allowed to receive
+// Web Push notifications. Endpoint and keys come from the browser's
+// PushSubscription.
+type PushSubscription struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID     uuid.UUID  `gorm:"type:uuid;index" json:"userId"`
+	Endpoint   string     `gorm:"type:text;uniqueIndex" json:"endpoint"`
+	P256dh     string     `json:"-"`
+	Auth       string     `json:"-"`
+	Device     string     `json:"device"` // Label for the user, e.g. "Chrome on Android"
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;" json:"-"`
+}
+
+func (p *PushSubscription) BeforeCreate(tx *gorm.DB) (err error) {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return
+}
+
+// TeamIntegration is a team's own credentials for an outside service, such
+// as the Whapi token it posts to its WhatsApp group with, and how the last
+// sends through it went. The row is kept without a token when only the
+// health is recorded.
+type TeamIntegration struct {
+	ID              uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	TeamID          uuid.UUID  `gorm:"type:uuid;uniqueIndex:idx_team_integration" json:"teamId"`
+	Service         string     `gorm:"uniqueIndex:idx_team_integration" json:"service"` // "whatsapp"
+	Token           string     `json:"token"`                                           // Encrypted
+	UpdatedByUserID *uuid.UUID `gorm:"type:uuid" json:"updatedByUserId,omitempty"`      // Who last set the token
+	LastSuccessAt   *time.Time `json:"lastSuccessAt,omitempty"`
+	LastFailureAt   *time.Time `json:"lastFailureAt,omitempty"`
+	LastError       string     `gorm:"type:text" json:"lastError"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
+
+	Team Team `gorm:"foreignKey:TeamID;constraint:OnDelete:CASCADE;" json:"-"`
+}
+
+func (ti *TeamIntegration) BeforeCreate(tx *gorm.DB) (err error) {
+	if ti.ID == uuid.Nil {
+		ti.ID = uuid.New()
+	}
+	return
+}
+
+// NotificationPreference overrides whether a user gets one notification type
+// on one channel. Without a row the type's default channels apply.
+type NotificationPreference struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID    uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_notification_preference" json:"userId"`
+	Type      string    `gorm:"uniqueIndex:idx_notification_preference" json:"type"`
+	Channel   string    `gorm:"uniqueIndex:idx_notification_preference" json:"channel"`
+	Enabled   bool      `json:"enabled"`
+	UpdatedAt time.Time `json:"updatedAt"`
+
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;" json:"-"`
+}
+
+func (p *NotificationPreference) BeforeCreate(tx *gorm.DB) (err error) {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return
+}
+
+// DigestSubscription is a user's opt-in to the weekly digest email, sent on
+// Weekday at Hour in their Timezone.
+type DigestSubscription struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID     uuid.UUID  `gorm:"type:uuid;uniqueIndex" json:"userId"`
+	Enabled    bool       `json:"enabled"`
+	Weekday    int        `json:"weekday"` // 0 = Sunday
+	Hour       int        `json:"hour"`    // 0-23
+	Timezone   string     `json:"timezone"`
+	LastSentAt *time.Time `json:"lastSentAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;" json:"-"`
+}
+
+func (d *DigestSubscription) BeforeCreate(tx *gorm.DB) (err error) {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return
+}
+
+// OutboxMessage is one notification queued for delivery on one channel. The
+// outbox worker sends it, retrying with backoff until it is sent or runs out
+// of attempts.
+type OutboxMessage struct {
+	ID                uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	Type              string     `gorm:"index" json:"type"`
+	Channel           string     `gorm:"index:idx_outbox_due,priority:1" json:"channel"`
+	Address           string     `json:"address"` // Email, phone number or group ID
+	RecipientUserID   *uuid.UUID `gorm:"type:uuid;index" json:"recipientUserId,omitempty"`
+	TeamID            *uuid.UUID `gorm:"type:uuid;index" json:"teamId,omitempty"`
+	GameID            *uuid.UUID `gorm:"type:uuid;index" json:"gameId,omitempty"`
+	Subject           string     `json:"subject"`
+	HTML              string     `json:"-"`
+	Text              string     `json:"-"`
+	Status            string     `gorm:"default:'pending';index:idx_outbox_due,priority:2" json:"status"` // "pending", "sent", "failed", "skipped"
+	Attempts          int        `gorm:"default:0" json:"attempts"`
+	NextAttemptAt     time.Time  `gorm:"index:idx_outbox_due,priority:3" json:"nextAttemptAt"`
+	LastError         string     `json:"lastError,omitempty"`
+	ProviderMessageID string     `json:"providerMessageId,omitempty"`
+	SentAt            *time.Time `json:"sentAt,omitempty"`
+	CreatedAt         time.Time  `json:"createdAt"`
+	UpdatedAt         time.Time  `json:"updatedAt"`
+}
+
+func (m *OutboxMessage) BeforeCreate(tx *gorm.DB) (err error) {
+	if m.ID == uuid.Nil {
+		m.ID = uuid.New()
+	}
+	return
+}
+
+// Blackout is a date range a member has said they are away for. Games on the
+// team that fall inside it are marked not_going for them.
+type Blackout struct {
+	ID           uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	TeamMemberID uuid.UUID `gorm:"type:uuid;index" json:"teamMemberId"`
+	StartDate    time.Time `gorm:"type:date" json:"startDate"`
+	EndDate      time.Time `gorm:"type:date" json:"endDate"` // Inclusive
+	Reason       string    `json:"reason"`
+	CreatedAt    time.Time `json:"createdAt"`
+
+	TeamMember TeamMember `gorm:"foreignKey:TeamMemberID;constraint:OnDelete:CASCADE;" json:"teamMember,omitempty"`
+}
+
+func (b *Blackout) BeforeCreate(tx *gorm.DB) (err error) {
+	if b.ID == uuid.Nil {
+		b.ID = uuid.New()
+	}
+	return
+}
+
 type Attendance struct {
 	ID           uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
 	TeamMemberID uuid.UUID `gorm:"type:uuid;index" json:"teamMemberId"`
 	GameID       uuid.UUID `gorm:"type:uuid;index" json:"gameId"`
 	Status       string    `json:"status"` // "going", "not_going", "maybe"
+	Note         string    `gorm:"size:280" json:"note"` // e.g. "running 15 min late"
 	ReminderSentAt *time.Time `json:"reminderSentAt,omitempty"`
 	UpdatedAt    time.Time `json:"updatedAt"`
 
@@ -146,6 +654,7 @@ type BattingOrder struct {
 	IsPlaceholder     bool      `gorm:"default:false" json:"isPlaceholder"`
 	PlaceholderGender string    `gorm:"default:''" json:"placeholderGender"`
 	CreatedAt         time.Time `json:"createdAt"`
+	AttendanceNote    string    `gorm:"-" json:"attendanceNote,omitempty"` // The player's RSVP note, filled in by handlers
 
 	Game       Game       `gorm:"foreignKey:GameID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"game,omitempty"`
 	TeamMember TeamMember `gorm:"foreignKey:TeamMemberID" json:"teamMember,omitempty"`
@@ -166,6 +675,7 @@ type BattingOrderPool struct {
 	TeamMemberID uuid.UUID  `gorm:"type:uuid" json:"teamMemberId"`
 	PoolPosition int        `json:"poolPosition"` // 1-based order within the pool
 	CreatedAt    time.Time  `json:"createdAt"`
+	AttendanceNote string   `gorm:"-" json:"attendanceNote,omitempty"`
 
 	Game       Game       `gorm:"foreignKey:GameID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"game,omitempty"`
 	TeamMember TeamMember `gorm:"foreignKey:TeamMemberID" json:"teamMember,omitempty"`
@@ -186,6 +696,7 @@ type FieldingLineup struct {
 	Position     string    `json:"position"` // "1B", "2B", "3B", "SS", "LF", "CF", "RF", "C", "Rover"
 	IsGenerated  bool      `json:"isGenerated"`
 	CreatedAt    time.Time `json:"createdAt"`
+	AttendanceNote string  `gorm:"-" json:"attendanceNote,omitempty"`
 
 	Game       Game       `gorm:"foreignKey:GameID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"game,omitempty"`
 	TeamMember TeamMember `gorm:"foreignKey:TeamMemberID" json:"teamMember,omitempty"`
@@ -215,6 +726,75 @@ func (is *InningScore) BeforeCreate(tx *gorm.DB) (err error) {
 	return
 }
 
+// Spare is a non-roster player who can be asked to fill in for a game. A spare
+// belongs either to one team or to a league, where every team can ask them.
+type Spare struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	TeamID    *uuid.UUID `gorm:"type:uuid;index" json:"teamId,omitempty"`
+	LeagueID  *uuid.UUID `gorm:"type:uuid;index" json:"leagueId,omitempty"`
+	UserID    *uuid.UUID `gorm:"type:uuid" json:"userId,omitempty"` // Set once they first accept
+	Name      string     `json:"name"`
+	Email     string     `json:"email"`
+	Phone     string     `json:"phone"`
+	Gender    string     `json:"gender"`   // "M" or "F"
+	Priority  int        `json:"priority"` // Lower is asked first
+	Notes     string     `json:"notes"`
+	IsActive  bool       `gorm:"default:true" json:"isActive"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
+
+func (s *Spare) BeforeCreate(tx *gorm.DB) (err error) {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return
+}
+
+// SpareRequest asks spares of one gender, one at a time in priority order,
+// to play in a game until one accepts.
+type SpareRequest struct {
+	ID              uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	GameID          uuid.UUID  `gorm:"type:uuid;index" json:"gameId"`
+	TeamID          uuid.UUID  `gorm:"type:uuid;index" json:"teamId"`
+	Gender          string     `json:"gender"`                       // "M" or "F"
+	Status          string     `gorm:"default:'open'" json:"status"` // "open", "filled", "exhausted", "cancelled"
+	ResponseHours   int        `json:"responseHours"`                // How long each spare has to answer
+	RequestedBy     uuid.UUID  `gorm:"type:uuid" json:"requestedBy"`
+	FilledBySpareID *uuid.UUID `gorm:"type:uuid" json:"filledBySpareId,omitempty"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
+
+	Invites []SpareInvite `gorm:"foreignKey:SpareRequestID;constraint:OnDelete:CASCADE;" json:"invites,omitempty"`
+}
+
+func (sr *SpareRequest) BeforeCreate(tx *gorm.DB) (err error) {
+	if sr.ID == uuid.Nil {
+		sr.ID = uuid.New()
+	}
+	return
+}
+
+type SpareInvite struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	SpareRequestID uuid.UUID  `gorm:"type:uuid;index" json:"spareRequestId"`
+	SpareID        uuid.UUID  `gorm:"type:uuid;index" json:"spareId"`
+	Token          string     `gorm:"uniqueIndex" json:"-"`
+	Status         string     `gorm:"default:'pending'" json:"status"` // "pending", "accepted", "declined", "expired"
+	ExpiresAt      time.Time  `json:"expiresAt"`
+	RespondedAt    *time.Time `json:"respondedAt,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+
+	Spare Spare `gorm:"foreignKey:SpareID" json:"spare,omitempty"`
+}
+
+func (si *SpareInvite) BeforeCreate(tx *gorm.DB) (err error) {
+	if si.ID == uuid.Nil {
+		si.ID = uuid.New()
+	}
+	return
+}
+
 type Invitation struct {
 	ID         uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
 	TeamID     uuid.UUID  `gorm:"type:uuid" json:"teamId"`
@@ -0,0 +1,318 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/liam/screaming-toller/backend/internal/database"
+	"github.com/liam/screaming-toller/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// When the weekly digest goes out for users who haven't picked a time:
+// Monday at 8 AM, Vancouver time.
+const (
+	DefaultDigestWeekday  = time.Monday
+	DefaultDigestHour     = 8
+	DefaultDigestTimezone = "America/Vancouver"
+)
+
+// DigestGame is an upcoming game in a player's weekly digest.
+type DigestGame struct {
+	TeamName     string
+	Opponent     string
+	Date         string
+	Time         string
+	Venue        GameVenue
+	Status       string // The player's RSVP
+	GoingMales   int
+	GoingFemales int
+	Maybe        int
+	AtRisk       bool
+	InLineup     bool // A published lineup includes the player
+	BattingSlot  LineupSlot
+	Positions    []InningPosition
+
+	start time.Time
+}
+
+// DigestResult is a game played in the last week.
+type DigestResult struct {
+	TeamName      string
+	Opponent      string
+	Date          string
+	TeamScore     int
+	OpponentScore int
+	Innings       []string // One line per inning, e.g. "1: 2-0"
+
+	start time.Time
+}
+
+// DefaultDigestSubscription is used for users who haven't set up the digest:
+// off, at the default time.
+func DefaultDigestSubscription(userID uuid.UUID) models.DigestSubscription {
+	return models.DigestSubscription{
+		UserID:   userID,
+		Weekday:  int(DefaultDigestWeekday),
+		Hour:     DefaultDigestHour,
+		Timezone: DefaultDigestTimezone,
+	}
+}
+
+// UserDigestSubscription returns the user's digest settings, or the default
+// if they have none.
+func UserDigestSubscription(userID uuid.UUID) (models.DigestSubscription, error) {
+	var subscription models.DigestSubscription
+	err := database.DB.Where("user_id = ?", userID).First(&subscription).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return DefaultDigestSubscription(userID), nil
+	}
+	return subscription, err
+}
+
+// ValidateDigestSubscription checks the weekday, hour and timezone.
+func ValidateDigestSubscription(subscription models.DigestSubscription) error {
+	if subscription.Weekday < 0 || subscription.Weekday > 6 {
+		return fmt.Errorf("weekday must be between 0 (Sunday) and 6 (Saturday)")
+	}
+	if subscription.Hour < 0 || subscription.Hour > 23 {
+		return fmt.Errorf("hour must be between 0 and 23")
+	}
+	if _, err := time.LoadLocation(subscription.Timezone); err != nil || subscription.Timezone == "" {
+		return fmt.Errorf("unknown timezone %q", subscription.Timezone)
+	}
+	return nil
+}
+
+// ScheduleDigest schedules the user's next digest. A disabled subscription's
+// pending job is left to run and send nothing.
+func ScheduleDigest(subscription models.DigestSubscription) error {
+	if !subscription.Enabled {
+		return nil
+	}
+	runAt := nextDigestTime(subscription, time.Now())
+	return ScheduleJob(JobDigest, fmt.Sprintf("%s:%s", JobDigest, subscription.UserID), nil, subscription.UserID.String(), runAt)
+}
+
+// nextDigestTime is the first time after now that falls on the subscription's
+// weekday and hour.
+func nextDigestTime(subscription models.DigestSubscription, now time.Time) time.Time {
+	loc, err := time.LoadLocation(subscription.Timezone)
+	if err != nil {
+		loc = defaultLocation()
+	}
+	local := now.In(loc)
+	days := (subscription.Weekday - int(local.Weekday()) + 7) % 7
+	next := time.Date(local.Year(), local.Month(), local.Day()+days, subscription.Hour, 0, 0, 0, loc)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 7)
+	}
+	return next
+}
+
+// sendDigest emails the user their weekly digest and schedules the next one.
+// Users with nothing coming up and no results from the last week get no email
+// that week.
+func (s *ReminderService) sendDigest(userID uuid.UUID) error {
+	var subscription models.DigestSubscription
+	if err := database.DB.Preload("User").Where("user_id = ?", userID).First(&subscription).Error; err != nil {
+		return err
+	}
+	if !subscription.Enabled {
+		return nil
+	}
+	if err := ScheduleDigest(subscription); err != nil {
+		return fmt.Errorf("failed to schedule next digest: %w", err)
+	}
+
+	// Claim this week's digest, so a job run twice only sends it once
+	now := time.Now()
+	result := database.DB.Model(&models.DigestSubscription{}).
+		Where("id = ? AND (last_sent_at IS NULL OR last_sent_at < ?)", subscription.ID, now.Add(-12*time.Hour)).
+		Update("last_sent_at", now)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+
+	mc := UserMessageContext(subscription.User, nil)
+	games, results, err := buildDigest(subscription.User, now, mc.Locale)
+	if err != nil {
+		database.DB.Model(&subscription).Update("last_sent_at", subscription.LastSentAt)
+		return fmt.Errorf("failed to build digest: %w", err)
+	}
+	if len(games) == 0 && len(results) == 0 {
+		log.Printf("Digest: Nothing to report for %s this week", subscription.User.Email)
+		return nil
+	}
+
+	msg := WeeklyDigestMessage(mc, subscription.User.Name, games, results)
+	if err := s.notifications.Notify(UserRecipient(subscription.User, nil), msg); err != nil {
+		database.DB.Model(&subscription).Update("last_sent_at", subscription.LastSentAt)
+		return err
+	}
+	log.Printf("Digest: Queued for %s (%d games, %d results)", subscription.User.Email, len(games), len(results))
+	return nil
+}
+
+// buildDigest collects, across every team the user is active on, the games in
+// the week ahead and the results from the week just gone, with dates in the
+// locale.
+func buildDigest(user models.User, now time.Time, locale string) ([]DigestGame, []DigestResult, error) {
+	var members []models.TeamMember
+	if err := database.DB.Preload("Team").
+		Where("user_id = ? AND is_active = ?", user.ID, true).
+		Find(&members).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var games []DigestGame
+	var results []DigestResult
+	for _, member := range members {
+		if member.Team.Status != "active" {
+			continue
+		}
+
+		var teamGames []models.Game
+		if err := database.DB.Preload("Venue").
+			Where("team_id = ? AND date >= ? AND date <= ? AND status NOT IN ?", member.TeamID, now.AddDate(0, 0, -8), now.AddDate(0, 0, 8), []string{"cancelled", "postponed"}).
+			Find(&teamGames).Error; err != nil {
+			return nil, nil, err
+		}
+
+		for _, game := range teamGames {
+			start, err := GameStartTime(game, defaultLocation())
+			if err != nil {
+				continue
+			}
+			switch {
+			case start.After(now) && start.Before(now.AddDate(0, 0, 7)) && game.Status != "completed":
+				digestGame, err := digestUpcomingGame(member, game, start, locale)
+				if err != nil {
+					return nil, nil, err
+				}
+				games = append(games, digestGame)
+			case !start.After(now) && start.After(now.AddDate(0, 0, -7)):
+				result, ok, err := digestGameResult(member.Team, game, start, locale)
+				if err != nil {
+					return nil, nil, err
+				}
+				if ok {
+					results = append(results, result)
+				}
+			}
+		}
+	}
+
+	sort.Slice(games, func(i, j int) bool { return games[i].start.Before(games[j].start) })
+	sort.Slice(results, func(i, j int) bool { return results[i].start.Before(results[j].start) })
+	return games, results, nil
+}
+
+func digestUpcomingGame(member models.TeamMember, game models.Game, start time.Time, locale string) (DigestGame, error) {
+	gameDate, gameTime := formatGameWhen(game, locale)
+	digestGame := DigestGame{
+		TeamName: member.Team.Name,
+		Opponent: game.OpposingTeam,
+		Date:     gameDate,
+		Time:     gameTime,
+		Venue:    gameVenue(game),
+		Status:   "maybe",
+		start:    start,
+	}
+
+	var attendance models.Attendance
+	err := database.DB.Where("game_id = ? AND team_member_id = ?", game.ID, member.ID).First(&attendance).Error
+	switch {
+	case err == nil:
+		digestGame.Status = attendance.Status
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return DigestGame{}, err
+	}
+
+	risk, err := GameForfeitRisk(game.ID)
+	if err != nil {
+		return DigestGame{}, err
+	}
+	digestGame.GoingMales = risk.GoingMales
+	digestGame.GoingFemales = risk.GoingFemales
+	digestGame.Maybe = risk.Maybe
+	digestGame.AtRisk = risk.AtRisk
+
+	if game.LineupPublishedAt == nil {
+		return digestGame, nil
+	}
+
+	var battingOrder []models.BattingOrder
+	if err := database.DB.Where("game_id = ?", game.ID).Order("batting_position").Find(&battingOrder).Error; err != nil {
+		return DigestGame{}, err
+	}
+	var minorityPool []models.BattingOrderPool
+	if err := database.DB.Preload("TeamMember").Where("game_id = ?", game.ID).Order("pool_position").Find(&minorityPool).Error; err != nil {
+		return DigestGame{}, err
+	}
+	var fielding []models.FieldingLineup
+	if err := database.DB.Where("game_id = ?", game.ID).Order("inning, position").Find(&fielding).Error; err != nil {
+		return DigestGame{}, err
+	}
+
+	if inLineup(member.ID, battingOrder, minorityPool, fielding) {
+		digestGame.InLineup = true
+		digestGame.BattingSlot = battingSlot(member.ID, battingOrder, minorityPool)
+		digestGame.Positions = playerPositions(member.ID, fielding, lineupInnings(fielding))
+	}
+	return digestGame, nil
+}
+
+// digestGameResult reports a played game's score: the final score when one
+// was recorded, otherwise the inning scores added up. Games with neither are
+// left out.
+func digestGameResult(team models.Team, game models.Game, start time.Time, locale string) (DigestResult, bool, error) {
+	var innings []models.InningScore
+	if err := database.DB.Where("game_id = ?", game.ID).Order("inning").Find(&innings).Error; err != nil {
+		return DigestResult{}, false, err
+	}
+	if game.FinalScore == nil && len(innings) == 0 {
+		return DigestResult{}, false, nil
+	}
+
+	gameDate, _ := formatGameWhen(game, locale)
+	result := DigestResult{
+		TeamName: team.Name,
+		Opponent: game.OpposingTeam,
+		Date:     gameDate,
+		start:    start,
+	}
+	for _, inning := range innings {
+		result.TeamScore += inning.TeamScore
+		result.OpponentScore += inning.OpponentScore
+		result.Innings = append(result.Innings, fmt.Sprintf("%d: %d-%d", inning.Inning, inning.TeamScore, inning.OpponentScore))
+	}
+	if game.FinalScore != nil && game.OpponentScore != nil {
+		result.TeamScore = *game.FinalScore
+		result.OpponentScore = *game.OpponentScore
+	}
+	return result, true, nil
+}
+
+func inLineup(memberID uuid.UUID, battingOrder []models.BattingOrder, minorityPool []models.BattingOrderPool, fielding []models.FieldingLineup) bool {
+	for _, slot := range battingOrder {
+		if slot.TeamMemberID != nil && *slot.TeamMemberID == memberID {
+			return true
+		}
+	}
+	for _, pool := range minorityPool {
+		if pool.TeamMemberID == memberID {
+			return true
+		}
+	}
+	for _, f := range fielding {
+		if f.TeamMemberID == memberID {
+			return true
+		}
+	}
+	return false
+}
@@ -1,267 +1,280 @@
 package services
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
 	"os"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/resend/resend-go/v2"
 )
 
+// Email transports, chosen with EMAIL_TRANSPORT.
+const (
+	EmailTransportResend = "resend"
+	EmailTransportSMTP   = "smtp"
+	EmailTransportFile   = "file" // Writes each email to EMAIL_FILE ("stdout" or a path) instead of sending it
+)
+
+// EmailSender hands a finished email to a transport.
+type EmailSender interface {
+	// Send delivers the message and returns the transport's ID for it.
+	Send(from, to string, msg Message) (string, error)
+	// SendInterval is the minimum gap between sends, to respect the
+	// transport's rate limit.
+	SendInterval() time.Duration
+}
+
 type EmailService struct {
-	client  *resend.Client
+	sender    EmailSender
 	fromEmail string
-	appURL    string
 }
 
-// NewEmailService creates a new email service instance
+// NewEmailService creates a new email service instance with the transport in
+// EMAIL_TRANSPORT. When it isn't set, Resend is used if RESEND_API_KEY is set.
+// The notification service creates one at startup; Close it on shutdown.
 func NewEmailService() (*EmailService, error) {
-	apiKey := os.Getenv("RESEND_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("RESEND_API_KEY environment variable is not set")
-	}
-
 	fromEmail := os.Getenv("FROM_EMAIL")
 	if fromEmail == "" {
 		fromEmail = "noreply@yourdomain.com" // Default fallback
 	}
 
-	appURL := os.Getenv("APP_URL")
-	if appURL == "" {
-		appURL = "http://localhost:5173" // Default to local development
+	transport := os.Getenv("EMAIL_TRANSPORT")
+	if transport == "" {
+		if os.Getenv("RESEND_API_KEY") == "" {
+			return nil, fmt.Errorf("neither EMAIL_TRANSPORT nor RESEND_API_KEY is set")
+		}
+		transport = EmailTransportResend
 	}
 
-	client := resend.NewClient(apiKey)
+	sender, err := newEmailSender(transport)
+	if err != nil {
+		return nil, err
+	}
 
 	return &EmailService{
-		client:    client,
+		sender:    sender,
 		fromEmail: fromEmail,
-		appURL:    appURL,
 	}, nil
 }
 
-// SendInvitationEmail sends an invitation email to a new team member
-func (s *EmailService) SendInvitationEmail(toEmail, teamName, inviterName, token string) error {
-	invitationURL := fmt.Sprintf("%s/accept-invitation/%s", s.appURL, token)
-
-	htmlContent := s.buildInvitationHTML(teamName, inviterName, invitationURL)
-	textContent := s.buildInvitationText(teamName, inviterName, invitationURL)
-
-	params := &resend.SendEmailRequest{
-		From:    s.fromEmail,
-		To:      []string{toEmail},
-		Subject: fmt.Sprintf("You've been invited to join %s", teamName),
-		Html:    htmlContent,
-		Text:    textContent,
+func newEmailSender(transport string) (EmailSender, error) {
+	switch transport {
+	case EmailTransportResend:
+		return NewResendSender(os.Getenv("RESEND_API_KEY"))
+	case EmailTransportSMTP:
+		return NewSMTPSender(os.Getenv("SMTP_HOST"), os.Getenv("SMTP_PORT"), os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"))
+	case EmailTransportFile:
+		dest := os.Getenv("EMAIL_FILE")
+		if dest == "" {
+			dest = "stdout"
+		}
+		return NewFileSender(dest)
+	default:
+		return nil, fmt.Errorf("unknown EMAIL_TRANSPORT %q (want %s, %s or %s)",
+			transport, EmailTransportResend, EmailTransportSMTP, EmailTransportFile)
 	}
+}
 
-	_, err := s.client.Emails.Send(params)
+// Send emails a message to a single address and returns the transport's
+// email ID.
+func (s *EmailService) Send(toEmail string, msg Message) (string, error) {
+	id, err := s.sender.Send(s.fromEmail, toEmail, msg)
 	if err != nil {
-		return fmt.Errorf("failed to send invitation email: %w", err)
+		return "", fmt.Errorf("failed to send %s email: %w", msg.Type, err)
 	}
-
-	return nil
+	return id, nil
 }
 
-// SendTeamRequestEmail sends an email to the super admin about a new team creation request
-func (s *EmailService) SendTeamRequestEmail(adminEmail, requesterName, teamName string) error {
-	subject := fmt.Sprintf("New Team Request: %s", teamName)
-	htmlContent := fmt.Sprintf(`
-		<h1>New Team Request</h1>
-		<p><strong>%s</strong> has requested to create a new team: <strong>%s</strong>.</p>
-		<p>Please log in to the admin dashboard to approve or reject this request.</p>
-		<p><a href="%s/admin/teams">View Requests</a></p>
-	`, requesterName, teamName, s.appURL)
+// SendInterval is the transport's minimum gap between sends.
+func (s *EmailService) SendInterval() time.Duration { return s.sender.SendInterval() }
 
-	params := &resend.SendEmailRequest{
-		From:    s.fromEmail,
-		To:      []string{adminEmail},
-		Subject: subject,
-		Html:    htmlContent,
+// Close releases the transport's resources, such as the file the file
+// transport writes to.
+func (s *EmailService) Close() error {
+	if closer, ok := s.sender.(io.Closer); ok {
+		return closer.Close()
 	}
-
-	_, err := s.client.Emails.Send(params)
-	return err
+	return nil
 }
 
-// SendTeamApprovedEmail sends an email to the requester when their team is approved
-func (s *EmailService) SendTeamApprovedEmail(toEmail, teamName string) error {
-	subject := fmt.Sprintf("Team Approved: %s", teamName)
-	htmlContent := fmt.Sprintf(`
-		<h1>Congratulations!</h1>
-		<p>Your team <strong>%s</strong> has been approved.</p>
-		<p>You can now start managing your team and inviting members.</p>
-		<p><a href="%s/teams">Go to Teams</a></p>
-	`, teamName, s.appURL)
+// ResendSender sends through the Resend API.
+type ResendSender struct {
+	client *resend.Client
+}
 
-	params := &resend.SendEmailRequest{
-		From:    s.fromEmail,
-		To:      []string{toEmail},
-		Subject: subject,
-		Html:    htmlContent,
+func NewResendSender(apiKey string) (*ResendSender, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("RESEND_API_KEY environment variable is not set")
 	}
-
-	_, err := s.client.Emails.Send(params)
-	return err
+	return &ResendSender{client: resend.NewClient(apiKey)}, nil
 }
 
-// SendTeamRejectedEmail sends an email to the requester when their team is rejected
-func (s *EmailService) SendTeamRejectedEmail(toEmail, teamName string) error {
-	subject := fmt.Sprintf("Team Request Update: %s", teamName)
-	htmlContent := fmt.Sprintf(`
-		<h1>Team Request Update</h1>
-		<p>We're sorry, but your request to create the team <strong>%s</strong> has been declined at this time.</p>
-		<p>If you have any questions, please contact support.</p>
-	`, teamName)
-
+func (s *ResendSender) Send(from, to string, msg Message) (string, error) {
 	params := &resend.SendEmailRequest{
-		From:    s.fromEmail,
-		To:      []string{toEmail},
-		Subject: subject,
-		Html:    htmlContent,
+		From:    from,
+		To:      []string{to},
+		Subject: msg.Subject,
+		Html:    msg.HTML,
+		Text:    msg.Text,
 	}
 
-	_, err := s.client.Emails.Send(params)
-	return err
+	sent, err := s.client.Emails.Send(params)
+	if err != nil {
+		return "", err
+	}
+	return sent.Id, nil
 }
 
-// SendAttendanceReminderEmail sends a reminder to a user marked as 'maybe' for an upcoming game
-func (s *EmailService) SendAttendanceReminderEmail(toEmail, teamName, opponent, gameDate, gameTime, location, teamID string) error {
-	attendanceURL := fmt.Sprintf("%s/teams/%s/games", s.appURL, teamID) // Corrected to use teamID
-	subject := fmt.Sprintf("Game tomorrow vs %s", opponent)
+// SendInterval keeps under Resend's limit of 5 emails per second.
+func (s *ResendSender) SendInterval() time.Duration { return 250 * time.Millisecond }
 
-	htmlContent := s.buildReminderHTML(teamName, opponent, gameDate, gameTime, location, attendanceURL)
-	textContent := s.buildReminderText(teamName, opponent, gameDate, gameTime, location, attendanceURL)
+// SMTPSender sends through an SMTP server, such as Mailpit in local
+// development or a self-hosted Postfix. STARTTLS is used when the server
+// offers it; credentials are optional.
+type SMTPSender struct {
+	addr string
+	auth smtp.Auth
+}
 
-	params := &resend.SendEmailRequest{
-		From:    s.fromEmail,
-		To:      []string{toEmail},
-		Subject: subject,
-		Html:    htmlContent,
-		Text:    textContent,
+// NewSMTPSender sends to host:port (port 587 when empty), authenticating
+// with username and password when a username is given.
+func NewSMTPSender(host, port, username, password string) (*SMTPSender, error) {
+	if host == "" {
+		return nil, fmt.Errorf("SMTP_HOST environment variable is not set")
+	}
+	if port == "" {
+		port = "587"
 	}
 
-	_, err := s.client.Emails.Send(params)
-	return err
+	s := &SMTPSender{addr: net.JoinHostPort(host, port)}
+	if username != "" {
+		s.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return s, nil
 }
 
-// buildInvitationHTML creates the HTML email template
-func (s *EmailService) buildInvitationHTML(teamName, inviterName, invitationURL string) string {
-	return fmt.Sprintf(`
-<!DOCTYPE html>
-<html>
-<head>
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>Team Invitation</title>
-</head>
-<body style="margin: 0; padding: 0; font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, 'Helvetica Neue', Arial, sans-serif; background-color: #f5f5f5;">
-    <table role="presentation" style="width: 100%%; border-collapse: collapse;">
-        <tr>
-            <td align="center" style="padding: 40px 0;">
-                <table role="presentation" style="width: 600px; max-width: 100%%; background-color: #ffffff; border-radius: 8px; box-shadow: 0 2px 4px rgba(0,0,0,0.1);">
-                    <!-- Header -->
-                    <tr>
-                        <td style="padding: 40px 40px 20px; text-align: center; background: linear-gradient(135deg, #667eea 0%%, #764ba2 100%); border-radius: 8px 8px 0 0;">
-                            <h1 style="margin: 0; color: #ffffff; font-size: 28px; font-weight: 600;">Team Invitation</h1>
-                        </td>
-                    </tr>
-                    
-                    <!-- Content -->
-                    <tr>
-                        <td style="padding: 40px;">
-                            <p style="margin: 0 0 20px; font-size: 16px; line-height: 24px; color: #333333;">
-                                Hi there! 👋
-                            </p>
-                            <p style="margin: 0 0 20px; font-size: 16px; line-height: 24px; color: #333333;">
-                                <strong>%s</strong> has invited you to join the team <strong>%s</strong>.
-                            </p>
-                            <p style="margin: 0 0 30px; font-size: 16px; line-height: 24px; color: #666666;">
-                                Click the button below to accept the invitation and join the team. This invitation will expire in 7 days.
-                            </p>
-                            
-                            <!-- CTA Button -->
-                            <table role="presentation" style="width: 100%%; border-collapse: collapse;">
-                                <tr>
-                                    <td align="center" style="padding: 20px 0;">
-                                        <a href="%s" style="display: inline-block; padding: 14px 32px; background: linear-gradient(135deg, #667eea 0%%, #764ba2 100%); color: #ffffff; text-decoration: none; border-radius: 6px; font-size: 16px; font-weight: 600; box-shadow: 0 4px 6px rgba(102, 126, 234, 0.3);">
-                                            Accept Invitation
-                                        </a>
-                                    </td>
-                                </tr>
-                            </table>
-                            
-                            <p style="margin: 30px 0 0; font-size: 14px; line-height: 20px; color: #999999;">
-                                Or copy and paste this link into your browser:<br>
-                                <a href="%s" style="color: #667eea; word-break: break-all;">%s</a>
-                            </p>
-                        </td>
-                    </tr>
-                    
-                    <!-- Footer -->
-                    <tr>
-                        <td style="padding: 20px 40px; background-color: #f8f9fa; border-radius: 0 0 8px 8px; text-align: center;">
-                            <p style="margin: 0; font-size: 12px; line-height: 18px; color: #999999;">
-                                If you didn't expect this invitation, you can safely ignore this email.
-                            </p>
-                        </td>
-                    </tr>
-                </table>
-            </td>
-        </tr>
-    </table>
-</body>
-</html>
-`, inviterName, teamName, invitationURL, invitationURL, invitationURL)
+func (s *SMTPSender) Send(from, to string, msg Message) (string, error) {
+	id, body, err := buildEmail(from, to, msg)
+	if err != nil {
+		return "", err
+	}
+	if err := smtp.SendMail(s.addr, s.auth, emailAddress(from), []string{to}, body); err != nil {
+		return "", err
+	}
+	return id, nil
 }
 
-// buildInvitationText creates the plain text email template
-func (s *EmailService) buildInvitationText(teamName, inviterName, invitationURL string) string {
-	return fmt.Sprintf(`
-Team Invitation
+func (s *SMTPSender) SendInterval() time.Duration { return 100 * time.Millisecond }
 
-Hi there!
+// FileSender writes each email, as the raw message an SMTP server would
+// receive, to stdout or a file instead of sending it.
+type FileSender struct {
+	mu   sync.Mutex
+	out  io.Writer
+	file *os.File // nil when writing to stdout
+}
 
-%s has invited you to join the team %s.
+// NewFileSender writes to stdout when dest is "stdout", otherwise appends to
+// the file at dest.
+func NewFileSender(dest string) (*FileSender, error) {
+	if dest == "stdout" {
+		return &FileSender{out: os.Stdout}, nil
+	}
+	f, err := os.OpenFile(dest, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open email file: %w", err)
+	}
+	return &FileSender{out: f, file: f}, nil
+}
 
-Click the link below to accept the invitation and join the team. This invitation will expire in 7 days.
+// Close closes the email file, if there is one.
+func (s *FileSender) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
 
-%s
+func (s *FileSender) Send(from, to string, msg Message) (string, error) {
+	id, body, err := buildEmail(from, to, msg)
+	if err != nil {
+		return "", err
+	}
 
-If you didn't expect this invitation, you can safely ignore this email.
-`, inviterName, teamName, invitationURL)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := fmt.Fprintf(s.out, "=== %s [%s]\n%s\n\n", time.Now().Format(time.RFC3339), msg.Type, body); err != nil {
+		return "", err
+	}
+	return id, nil
 }
 
-func (s *EmailService) buildReminderHTML(teamName, opponent, gameDate, gameTime, location, attendanceURL string) string {
-	return fmt.Sprintf(`
-<!DOCTYPE html>
-<html>
-<body style="font-family: sans-serif; padding: 20px;">
-    <h2>Game Tomorrow! ⚾</h2>
-    <p>This is a reminder that you have not currently responded or are marked as <strong>'Maybe'</strong> for tomorrow's <strong>%s</strong> game.</p>
-    <div style="background: #f0f0f0; padding: 15px; border-radius: 8px; margin: 20px 0;">
-        <p><strong>Opponent:</strong> %s</p>
-        <p><strong>Date:</strong> %s</p>
-        <p><strong>Time:</strong> %s</p>
-        <p><strong>Location:</strong> %s</p>
-    </div>
-    <p>Please update your attendance status so your team can plan the lineup.</p>
-    <a href="%s" style="display: inline-block; padding: 10px 20px; background: rgba(247, 82, 31, 1); color: white; text-decoration: none; border-radius: 5px;">Update Attendance</a>
-</body>
-</html>
-`, teamName, opponent, gameDate, gameTime, location, attendanceURL)
-}
+func (s *FileSender) SendInterval() time.Duration { return 0 }
+
+// buildEmail renders the message as a MIME email with text and HTML
+// alternatives, returning its Message-ID and bytes.
+func buildEmail(from, to string, msg Message) (string, []byte, error) {
+	id := fmt.Sprintf("<%s@%s>", uuid.New(), emailDomain(from))
+	boundary := "alt-" + uuid.New().String()
+
+	var b bytes.Buffer
+	headers := [][2]string{
+		{"From", from},
+		{"To", to},
+		{"Subject", mime.QEncoding.Encode("utf-8", msg.Subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-ID", id},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", fmt.Sprintf("multipart/alternative; boundary=%q", boundary)},
+	}
+	for _, h := range headers {
+		fmt.Fprintf(&b, "%s: %s\r\n", h[0], h[1])
+	}
 
-func (s *EmailService) buildReminderText(teamName, opponent, gameDate, gameTime, location, attendanceURL string) string {
-	return fmt.Sprintf(`
-Game Tomorrow!
+	parts := [][2]string{{"text/plain", msg.Text}, {"text/html", msg.HTML}}
+	for _, part := range parts {
+		if part[1] == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "\r\n--%s\r\n", boundary)
+		fmt.Fprintf(&b, "Content-Type: %s; charset=utf-8\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\n", part[0])
+		w := quotedprintable.NewWriter(&b)
+		if _, err := w.Write([]byte(part[1])); err != nil {
+			return "", nil, err
+		}
+		if err := w.Close(); err != nil {
+			return "", nil, err
+		}
+	}
+	fmt.Fprintf(&b, "\r\n--%s--\r\n", boundary)
 
-This is a reminder that you have not currently responded or are marked as 'Maybe' for tomorrow's %s game.
+	return id, b.Bytes(), nil
+}
 
-Opponent: %s
-Date: %s
-Time: %s
-Location: %s
+// emailAddress is the bare address of "Name <address>" or "address".
+func emailAddress(from string) string {
+	if i := strings.LastIndex(from, "<"); i >= 0 {
+		return strings.TrimSuffix(from[i+1:], ">")
+	}
+	return from
+}
 
-Please update your attendance status here: %s
-`, teamName, opponent, gameDate, gameTime, location, attendanceURL)
+// emailDomain is the domain of the sender's address, for Message-IDs.
+func emailDomain(from string) string {
+	address := emailAddress(from)
+	if i := strings.LastIndex(address, "@"); i >= 0 {
+		return address[i+1:]
+	}
+	return "localhost"
 }
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/liam/screaming-toller/backend/internal/algorithms"
+	"github.com/liam/screaming-toller/backend/internal/database"
+	"github.com/liam/screaming-toller/backend/internal/models"
+)
+
+// forfeitAlertStages escalate as the game nears: each stage fires once, when
+// the game is within the given time and still at risk.
+var forfeitAlertStages = []struct {
+	Level  int
+	Within time.Duration
+}{
+	{Level: 1, Within: 72 * time.Hour},
+	{Level: 2, Within: 48 * time.Hour},
+	{Level: 3, Within: 24 * time.Hour}, // urgent, also posted to WhatsApp
+}
+
+// GameForfeitRisk counts the game's going and maybe players by gender and
+// checks them against the lineup rules.
+func GameForfeitRisk(gameID uuid.UUID) (algorithms.ForfeitRisk, error) {
+	var attendance []models.Attendance
+	if err := database.DB.Preload("TeamMember").
+		Where("game_id = ? AND status IN ?", gameID, []string{"going", "maybe"}).
+		Find(&attendance).Error; err != nil {
+		return algorithms.ForfeitRisk{}, err
+	}
+
+	var goingM, goingF, maybeM, maybeF int
+	for _, att := range attendance {
+		switch {
+		case att.Status == "going" && att.TeamMember.Gender == "M":
+			goingM++
+		case att.Status == "going" && att.TeamMember.Gender == "F":
+			goingF++
+		case att.Status == "maybe" && att.TeamMember.Gender == "M":
+			maybeM++
+		case att.Status == "maybe" && att.TeamMember.Gender == "F":
+			maybeF++
+		}
+	}
+	return algorithms.AssessForfeitRisk(goingM, goingF, maybeM, maybeF), nil
+}
+
+// checkForfeitRisk assesses an upcoming game. If it can't field a legal
+// lineup the team admins are alerted and the "maybe" players of the gender the
+// team is short are reminded, escalating at 72, 48 and 24 hours.
+func (s *ReminderService) checkForfeitRisk(gameID uuid.UUID) error {
+	now := time.Now().In(s.location)
+
+	var game models.Game
+	if err := database.DB.Preload("Venue").First(&game, "id = ?", gameID).Error; err != nil {
+		return err
+	}
+	if game.Status == "cancelled" || game.Status == "postponed" || game.Status == "completed" {
+		return nil
+	}
+
+	start, err := GameStartTime(game, s.location)
+	if err != nil || !start.After(now) {
+		return nil
+	}
+
+	risk, err := GameForfeitRisk(game.ID)
+	if err != nil {
+		return fmt.Errorf("failed to assess game: %w", err)
+	}
+
+	if !risk.AtRisk {
+		if game.ForfeitAlertLevel > 0 {
+			// Enough players confirmed; alert again if that changes
+			database.DB.Model(&game).Update("forfeit_alert_level", 0)
+		}
+		return nil
+	}
+
+	level := 0
+	for _, stage := range forfeitAlertStages {
+		if start.Sub(now) <= stage.Within {
+			level = stage.Level
+		}
+	}
+	if level <= game.ForfeitAlertLevel {
+		return nil
+	}
+
+	// Claim the level before alerting; if another replica checking the game
+	// at the same time got there first, it sends the alerts
+	result := database.DB.Model(&models.Game{}).
+		Where("id = ? AND forfeit_alert_level < ?", game.ID, level).
+		Updates(map[string]interface{}{
+			"forfeit_alert_level":   level,
+			"forfeit_alert_sent_at": time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	log.Printf("ForfeitRisk: Game %s vs %s at risk (level %d): %s", game.ID, game.OpposingTeam, level, strings.Join(risk.Reasons, "; "))
+	s.sendForfeitAlerts(game, start, risk, level)
+	return nil
+}
+
+func (s *ReminderService) sendForfeitAlerts(game models.Game, start time.Time, risk algorithms.ForfeitRisk, level int) {
+	var team models.Team
+	if err := database.DB.First(&team, "id = ?", game.TeamID).Error; err != nil {
+		log.Printf("ForfeitRisk Error: Could not load team for game %s: %v", game.ID, err)
+		return
+	}
+
+	urgent := level == forfeitAlertStages[len(forfeitAlertStages)-1].Level
+
+	var admins []models.TeamMember
+	if err := database.DB.Preload("User").
+		Where("team_id = ? AND is_active = ? AND is_admin = ?", team.ID, true, true).
+		Find(&admins).Error; err != nil {
+		log.Printf("ForfeitRisk Error: Failed to fetch admins for team %s: %v", team.Name, err)
+	}
+	for _, admin := range admins {
+		mc := UserMessageContext(admin.User, &team)
+		err := s.notifications.Notify(UserRecipient(admin.User, &team), ForfeitRiskAlertMessage(mc, team.Name, game.OpposingTeam,
+			formatDay(start, mc.Locale), formatClock(start, mc.Locale), risk, urgent, team.ID.String()).ForGame(game.ID))
+		if err != nil {
+			log.Printf("ForfeitRisk Error: Failed to alert %s: %v", admin.User.Email, err)
+		}
+	}
+
+	// Only nudge the players whose answer could fix the lineup
+	shortGenders := risk.ShortGenders()
+	var maybes []models.Attendance
+	if err := database.DB.Preload("TeamMember.User").
+		Joins("JOIN team_members ON team_members.id = attendances.team_member_id").
+		Where("attendances.game_id = ? AND attendances.status = ? AND team_members.gender IN ?", game.ID, "maybe", shortGenders).
+		Find(&maybes).Error; err != nil {
+		log.Printf("ForfeitRisk Error: Failed to fetch maybe players for game %s: %v", game.ID, err)
+	}
+	for _, att := range maybes {
+		user := att.TeamMember.User
+		if user.OptOutReminders {
+			continue
+		}
+		mc := UserMessageContext(user, &team)
+		err := s.notifications.Notify(UserRecipient(user, &team), ShortHandedReminderMessage(mc, team.Name, game.OpposingTeam,
+			formatDay(start, mc.Locale), formatClock(start, mc.Locale), gameVenue(game), att.TeamMember.Gender, team.ID.String()).ForGame(game.ID))
+		if err != nil {
+			log.Printf("ForfeitRisk Error: Failed to remind %s: %v", user.Email, err)
+		}
+	}
+
+	if urgent {
+		s.sendForfeitWhatsApp(team, game, start, risk)
+	}
+}
+
+func (s *ReminderService) sendForfeitWhatsApp(team models.Team, game models.Game, start time.Time, risk algorithms.ForfeitRisk) {
+	if team.WhatsAppGroupID == "" {
+		return
+	}
+
+	mc := TeamMessageContext(team)
+	message := renderWhatsApp(mc, "forfeit_alert", forfeitAlertData(team.Name, game.OpposingTeam,
+		formatDay(start, mc.Locale), formatClock(start, mc.Locale), risk, true, team.ID.String()))
+
+	if err := s.notifications.NotifyTeam(team, Message{Type: TypeForfeitAlert, Text: message}.ForGame(game.ID)); err != nil {
+		log.Printf("ForfeitRisk Error: Failed to send WhatsApp alert for game %s: %v", game.ID, err)
+	}
+}
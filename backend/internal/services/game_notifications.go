@@ -0,0 +1,128 @@
+package services
+
+import (
+	"log"
+
+	"github.com/liam/screaming-toller/backend/internal/database"
+	"github.com/liam/screaming-toller/backend/internal/models"
+)
+
+// NotifyGameRescheduled tells every active member of the team that a game was
+// postponed, on their notification channels and in the team's WhatsApp group.
+// replacement is the make-up game, or nil if no new date has been set yet.
+// Nothing is sent for a league game whose team is no longer in the league.
+func NotifyGameRescheduled(notifications *NotificationService, original models.Game, replacement *models.Game, reason string) {
+	if confirmed, err := leagueTeamConfirmed(original); err != nil || !confirmed {
+		return
+	}
+
+	var team models.Team
+	if err := database.DB.First(&team, "id = ?", original.TeamID).Error; err != nil {
+		log.Printf("GameNotifications Error: Could not load team for game %s: %v", original.ID, err)
+		return
+	}
+
+	// when words the dates in a locale; newDate and newTime are empty until
+	// the make-up date is set
+	when := func(locale string) (originalDate, newDate, newTime string) {
+		originalDate, _ = formatGameWhen(original, locale)
+		if replacement != nil {
+			newDate, newTime = formatGameWhen(*replacement, locale)
+		}
+		return originalDate, newDate, newTime
+	}
+	venue := gameVenue(original)
+	if replacement != nil {
+		venue = gameVenue(*replacement)
+	}
+
+	var members []models.TeamMember
+	if err := database.DB.Preload("User").Where("team_id = ? AND is_active = ?", team.ID, true).Find(&members).Error; err != nil {
+		log.Printf("GameNotifications Error: Failed to fetch members for team %s: %v", team.Name, err)
+	}
+
+	for _, member := range members {
+		mc := UserMessageContext(member.User, &team)
+		originalDate, newDate, newTime := when(mc.Locale)
+		err := notifications.Notify(UserRecipient(member.User, &team), GameRescheduledMessage(
+			mc,
+			team.Name,
+			original.OpposingTeam,
+			originalDate,
+			newDate,
+			newTime,
+			venue,
+			reason,
+			team.ID.String(),
+		).ForGame(original.ID))
+		if err != nil {
+			log.Printf("GameNotifications Error: Failed to send postponement notice to %s: %v", member.User.Email, err)
+		}
+	}
+
+	if team.WhatsAppGroupID == "" {
+		return
+	}
+
+	mc := TeamMessageContext(team)
+	originalDate, newDate, newTime := when(mc.Locale)
+	message := renderWhatsApp(mc, "game_rescheduled", gameRescheduledData(team.Name, original.OpposingTeam,
+		originalDate, newDate, newTime, venue, reason, team.ID.String()))
+
+	if err := notifications.NotifyTeam(team, Message{Type: TypeGameRescheduled, Text: message}.ForGame(original.ID)); err != nil {
+		log.Printf("GameNotifications Error: Failed to send WhatsApp postponement notice for game %s: %v", original.ID, err)
+	}
+}
+
+// NotifyScoreUpdated tells the active members of the game's team the final
+// score. For a league game it also tells the opposing team, whose copy of the
+// game was updated with the mirrored score. Teams no longer in the league are
+// skipped.
+func NotifyScoreUpdated(notifications *NotificationService, game models.Game) {
+	games := []models.Game{game}
+	if game.MatchupID != nil {
+		var twins []models.Game
+		if err := database.DB.Where("matchup_id = ? AND id <> ?", *game.MatchupID, game.ID).Find(&twins).Error; err != nil {
+			log.Printf("GameNotifications Error: Failed to load matchup of game %s: %v", game.ID, err)
+		}
+		games = append(games, twins...)
+	}
+
+	for _, g := range games {
+		if g.FinalScore == nil || g.OpponentScore == nil {
+			continue
+		}
+		if confirmed, err := leagueTeamConfirmed(g); err != nil || !confirmed {
+			continue
+		}
+
+		var team models.Team
+		if err := database.DB.First(&team, "id = ?", g.TeamID).Error; err != nil {
+			log.Printf("GameNotifications Error: Could not load team for game %s: %v", g.ID, err)
+			continue
+		}
+
+		var members []models.TeamMember
+		if err := database.DB.Preload("User").Where("team_id = ? AND is_active = ?", team.ID, true).Find(&members).Error; err != nil {
+			log.Printf("GameNotifications Error: Failed to fetch members for team %s: %v", team.Name, err)
+			continue
+		}
+
+		for _, member := range members {
+			mc := UserMessageContext(member.User, &team)
+			gameDate, _ := formatGameWhen(g, mc.Locale)
+			err := notifications.Notify(UserRecipient(member.User, &team), ScoreUpdateMessage(
+				mc,
+				team.Name,
+				g.OpposingTeam,
+				gameDate,
+				*g.FinalScore,
+				*g.OpponentScore,
+				team.ID.String(),
+			).ForGame(g.ID))
+			if err != nil {
+				log.Printf("GameNotifications Error: Failed to send score update to %s: %v", member.User.Email, err)
+			}
+		}
+	}
+}
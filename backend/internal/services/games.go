@@ -0,0 +1,66 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/liam/screaming-toller/backend/internal/models"
+)
+
+// GameTimezone returns the timezone the game is played in: the venue's
+// timezone when one is configured, otherwise fallback.
+func GameTimezone(game models.Game, fallback *time.Location) *time.Location {
+	if game.Venue != nil && game.Venue.Timezone != "" {
+		if loc, err := time.LoadLocation(game.Venue.Timezone); err == nil {
+			return loc
+		}
+		log.Printf("Warning: Invalid timezone '%s' on venue %s", game.Venue.Timezone, game.Venue.ID)
+	}
+	return fallback
+}
+
+// GameStartTime combines the game's date and "HH:MM" time in the game's timezone.
+// game.Date is stored as YYYY-MM-DD 00:00:00 UTC, so only its calendar day is used.
+func GameStartTime(game models.Game, fallback *time.Location) (time.Time, error) {
+	var hour, min int
+	if n, _ := fmt.Sscanf(game.Time, "%d:%d", &hour, &min); n < 2 {
+		return time.Time{}, fmt.Errorf("invalid game time %q", game.Time)
+	}
+	return time.Date(game.Date.Year(), game.Date.Month(), game.Date.Day(), hour, min, 0, 0, GameTimezone(game, fallback)), nil
+}
+
+// defaultLocation is the timezone used for games without a venue timezone.
+func defaultLocation() *time.Location {
+	loc, err := time.LoadLocation("America/Vancouver")
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// formatGameWhen returns display strings for a game's date and time in the
+// locale, e.g. "Monday, Jan 2" and "6:30 PM". The raw time is returned if it
+// can't be parsed.
+func formatGameWhen(game models.Game, locale string) (string, string) {
+	start, err := GameStartTime(game, defaultLocation())
+	if err != nil {
+		return formatDay(game.Date, locale), game.Time
+	}
+	return formatDay(start, locale), formatClock(start, locale)
+}
+
+// gameVenue collects the location details for a game. Falls back to the
+// free-text Location when the game has no linked venue.
+func gameVenue(game models.Game) GameVenue {
+	if game.Venue == nil {
+		return GameVenue{Name: game.Location}
+	}
+	return GameVenue{
+		Name:          game.Venue.Name,
+		Address:       game.Venue.Address,
+		DiamondNumber: game.Venue.DiamondNumber,
+		ParkingNotes:  game.Venue.ParkingNotes,
+		MapsURL:       game.Venue.MapsURL(),
+	}
+}
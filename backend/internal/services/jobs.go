@@ -0,0 +1,434 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/liam/screaming-toller/backend/internal/database"
+	"github.com/liam/screaming-toller/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Scheduled job kinds.
+const (
+	JobReminder     = "reminder"      // One reminder rule for one game
+	JobRSVPDeadline = "rsvp_deadline" // A game's RSVP deadline passing
+	JobForfeitCheck = "forfeit_check" // One forfeit alert stage for a game
+	JobSpareInvites = "spare_invites" // A spare invite expiring
+	JobDigest       = "digest"        // One user's weekly digest
+	JobReconcile    = "reconcile"     // Daily catch-up for games without jobs
+)
+
+const (
+	// jobMaxAttempts is how many times a failing job is run before it is
+	// marked failed.
+	jobMaxAttempts = 5
+	// jobRetryBase is the wait after a job's first failure; it doubles with
+	// each attempt.
+	jobRetryBase = time.Minute
+	// jobLease is how long a claimed job is hidden from other workers. It is
+	// renewed while the job runs; a worker that dies mid-job leaves it to be
+	// run again once it lapses.
+	jobLease = 5 * time.Minute
+	// jobMaxWait caps how long an idle worker sleeps, so jobs added by
+	// another replica are picked up without a wake-up.
+	jobMaxWait = time.Minute
+)
+
+const reconcileJobKey = "reconcile"
+
+// jobWorkerID identifies this process on the jobs it holds, so a replica
+// only records the outcome of jobs it still holds the lease on.
+var jobWorkerID = func() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s:%d", host, os.Getpid())
+}()
+
+// jobWake tells the worker a job was scheduled, in case it is due before the
+// worker would next wake.
+var jobWake = make(chan struct{}, 1)
+
+// ScheduleJob creates the job with the given key or moves it to fire at
+// runAt. A job moved to a new fire time starts over, running again even if
+// it has already run or failed. Scheduling a job for the fire time it
+// already has changes nothing, so a job being retried keeps its attempts and
+// a failed one stays failed.
+func ScheduleJob(kind, key string, gameID *uuid.UUID, payload string, runAt time.Time) error {
+	job := models.ScheduledJob{
+		Kind:    kind,
+		Key:     key,
+		GameID:  gameID,
+		Payload: payload,
+		FireAt:  runAt,
+		RunAt:   runAt,
+		Status:  "pending",
+	}
+	err := database.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "key"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"fire_at":    runAt,
+			"run_at":     runAt,
+			"payload":    payload,
+			"status":     "pending",
+			"attempts":   0,
+			"last_error": "",
+			"updated_at": time.Now(),
+		}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			gorm.Expr("scheduled_jobs.fire_at IS DISTINCT FROM excluded.fire_at"),
+		}},
+	}).Create(&job).Error
+	if err != nil {
+		return err
+	}
+
+	select {
+	case jobWake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// ScheduleGameJobs schedules the game's reminders, RSVP deadline and forfeit
+// checks from its start time and the team's current settings. Call it
+// whenever a game is created or moved, or the team's rules change.
+func ScheduleGameJobs(gameID uuid.UUID) error {
+	var game models.Game
+	if err := database.DB.Preload("Team").Preload("Venue").First(&game, "id = ?", gameID).Error; err != nil {
+		return err
+	}
+	if game.Status == "cancelled" || game.Status == "postponed" || game.Status == "completed" {
+		return nil
+	}
+	if confirmed, err := leagueTeamConfirmed(game); err != nil || !confirmed {
+		return err
+	}
+
+	start, err := GameStartTime(game, defaultLocation())
+	if err != nil {
+		return err
+	}
+	if !start.After(time.Now()) {
+		return nil
+	}
+
+	rules, err := TeamReminderRules(game.TeamID)
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		ruleKey := reminderRuleKey(rule)
+		runAt := start.Add(-time.Duration(rule.OffsetHours) * time.Hour)
+		if err := ScheduleJob(JobReminder, fmt.Sprintf("%s:%s:%s", JobReminder, game.ID, ruleKey), &game.ID, ruleKey, runAt); err != nil {
+			return err
+		}
+	}
+
+	if deadline := RSVPDeadline(game, game.Team); deadline != nil && game.RSVPResolvedAt == nil {
+		if err := ScheduleJob(JobRSVPDeadline, fmt.Sprintf("%s:%s", JobRSVPDeadline, game.ID), &game.ID, "", *deadline); err != nil {
+			return err
+		}
+	}
+
+	for _, stage := range forfeitAlertStages {
+		runAt := start.Add(-stage.Within)
+		if err := ScheduleJob(JobForfeitCheck, fmt.Sprintf("%s:%s:%d", JobForfeitCheck, game.ID, stage.Level), &game.ID, "", runAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// leagueTeamConfirmed reports whether the game's team is still an active
+// member of the game's league. Games outside a league always are; a team that
+// was only invited, or has left, gets no jobs or notifications for the
+// league's games.
+func leagueTeamConfirmed(game models.Game) (bool, error) {
+	if game.LeagueID == nil {
+		return true, nil
+	}
+	var count int64
+	err := database.DB.Model(&models.LeagueTeam{}).
+		Where("league_id = ? AND team_id = ? AND status = ?", *game.LeagueID, game.TeamID, "active").
+		Count(&count).Error
+	return count > 0, err
+}
+
+// ScheduleTeamGames reschedules every upcoming game on the team, e.g. after
+// its reminder rules or RSVP deadline change.
+func ScheduleTeamGames(teamID uuid.UUID) error {
+	return scheduleUpcomingGames(database.DB.Where("team_id = ?", teamID))
+}
+
+// ScheduleLeagueGames schedules every upcoming game in the league, e.g. after
+// a season schedule or tournament bracket is generated.
+func ScheduleLeagueGames(leagueID uuid.UUID) error {
+	return scheduleUpcomingGames(database.DB.Where("league_id = ?", leagueID))
+}
+
+func scheduleUpcomingGames(query *gorm.DB) error {
+	var gameIDs []uuid.UUID
+	if err := query.Model(&models.Game{}).
+		Where("date >= ? AND status NOT IN ?", time.Now().AddDate(0, 0, -1), []string{"cancelled", "postponed", "completed"}).
+		Pluck("id", &gameIDs).Error; err != nil {
+		return err
+	}
+
+	for _, gameID := range gameIDs {
+		if err := ScheduleGameJobs(gameID); err != nil {
+			return fmt.Errorf("game %s: %w", gameID, err)
+		}
+	}
+	return nil
+}
+
+// scheduleSpareInviteExpiry expires the invite when its time is up, so the
+// next spare is asked straight away.
+func scheduleSpareInviteExpiry(invite models.SpareInvite) error {
+	return ScheduleJob(JobSpareInvites, fmt.Sprintf("%s:%s", JobSpareInvites, invite.ID), nil, "", invite.ExpiresAt)
+}
+
+// reconcileJobs schedules every game that could have a job due in the next
+// two weeks, every outstanding spare invite and every weekly digest. Jobs are normally scheduled
+// as games change; this catches games created before the scheduler existed
+// or changed directly in the database.
+func reconcileJobs() error {
+	now := time.Now()
+	var gameIDs []uuid.UUID
+	if err := database.DB.Model(&models.Game{}).
+		Where("date >= ? AND date <= ? AND status NOT IN ?", now.AddDate(0, 0, -1), now.Add(MaxReminderOffsetHours*time.Hour).AddDate(0, 0, 1), []string{"cancelled", "postponed", "completed"}).
+		Pluck("id", &gameIDs).Error; err != nil {
+		return err
+	}
+	for _, gameID := range gameIDs {
+		if err := ScheduleGameJobs(gameID); err != nil {
+			log.Printf("Jobs Error: Failed to schedule jobs for game %s: %v", gameID, err)
+		}
+	}
+
+	var invites []models.SpareInvite
+	if err := database.DB.Where("status = ?", "pending").Find(&invites).Error; err != nil {
+		return err
+	}
+	for _, invite := range invites {
+		if err := scheduleSpareInviteExpiry(invite); err != nil {
+			log.Printf("Jobs Error: Failed to schedule expiry of spare invite %s: %v", invite.ID, err)
+		}
+	}
+
+	var subscriptions []models.DigestSubscription
+	if err := database.DB.Where("enabled = ?", true).Find(&subscriptions).Error; err != nil {
+		return err
+	}
+	for _, subscription := range subscriptions {
+		if err := ScheduleDigest(subscription); err != nil {
+			log.Printf("Jobs Error: Failed to schedule digest for user %s: %v", subscription.UserID, err)
+		}
+	}
+
+	log.Printf("Jobs: Reconciled %d upcoming games, %d spare invites and %d digests", len(gameIDs), len(invites), len(subscriptions))
+	return nil
+}
+
+// Run executes scheduled jobs as they fall due until ctx is cancelled. A job
+// already running when ctx is cancelled is finished first.
+func (s *ReminderService) Run(ctx context.Context) {
+	// Catch up on anything missed while no worker was running
+	if err := ScheduleJob(JobReconcile, reconcileJobKey, nil, "", time.Now()); err != nil {
+		log.Printf("Jobs Error: Failed to schedule reconcile: %v", err)
+	}
+	log.Printf("Jobs: Worker %s started", jobWorkerID)
+
+	for {
+		if ctx.Err() != nil {
+			log.Println("Jobs: Worker stopped")
+			return
+		}
+
+		job, err := claimJob()
+		if err == nil {
+			s.runJob(job)
+			continue
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("Jobs Error: Failed to claim job: %v", err)
+		}
+
+		timer := time.NewTimer(nextJobWait())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+		case <-jobWake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// claimJob takes the next due job and leases it to this worker. SKIP LOCKED
+// lets replicas claim at the same time without waiting on each other or
+// taking the same job.
+func claimJob() (models.ScheduledJob, error) {
+	var job models.ScheduledJob
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND run_at <= ? AND (locked_until IS NULL OR locked_until < ?)", "pending", now, now).
+			Order("run_at asc").
+			First(&job).Error; err != nil {
+			return err
+		}
+		return tx.Model(&job).Updates(map[string]interface{}{
+			"locked_until": now.Add(jobLease),
+			"locked_by":    jobWorkerID,
+		}).Error
+	})
+	return job, err
+}
+
+// nextJobWait is how long until the next pending job is due, between a second
+// and jobMaxWait.
+func nextJobWait() time.Duration {
+	var job models.ScheduledJob
+	now := time.Now()
+	err := database.DB.Where("status = ? AND (locked_until IS NULL OR locked_until < ?)", "pending", now).
+		Order("run_at asc").
+		First(&job).Error
+	if err != nil {
+		return jobMaxWait
+	}
+
+	wait := job.RunAt.Sub(now)
+	if wait < time.Second {
+		wait = time.Second
+	}
+	if wait > jobMaxWait {
+		wait = jobMaxWait
+	}
+	return wait
+}
+
+// runJob runs one job and records the outcome. A job rescheduled while it was
+// running is left pending for its new time.
+func (s *ReminderService) runJob(job models.ScheduledJob) {
+	stopRenewing := renewJobLease(job)
+	err := s.executeJob(job)
+	stopRenewing()
+
+	attempts := job.Attempts + 1
+	updates := map[string]interface{}{"attempts": attempts, "locked_until": nil, "locked_by": ""}
+	switch {
+	case err == nil:
+		updates["status"] = "done"
+		updates["last_error"] = ""
+	case attempts >= jobMaxAttempts:
+		updates["status"] = "failed"
+		updates["last_error"] = err.Error()
+		log.Printf("Jobs Error: Giving up on %s after %d attempts: %v", job.Key, attempts, err)
+	default:
+		updates["run_at"] = time.Now().Add(jobRetryBase << (attempts - 1))
+		updates["last_error"] = err.Error()
+		log.Printf("Jobs: Attempt %d of %s failed, retrying: %v", attempts, job.Key, err)
+	}
+
+	result := database.DB.Model(&models.ScheduledJob{}).
+		Where("id = ? AND fire_at = ? AND locked_by = ?", job.ID, job.FireAt, jobWorkerID).
+		Updates(updates)
+	if result.Error != nil {
+		log.Printf("Jobs Error: Failed to record outcome of %s: %v", job.Key, result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		database.DB.Model(&models.ScheduledJob{}).
+			Where("id = ? AND locked_by = ?", job.ID, jobWorkerID).
+			Updates(map[string]interface{}{"locked_until": nil, "locked_by": ""})
+	}
+}
+
+// renewJobLease extends this worker's lease on the job every half lease until
+// the returned func is called, so a slow job isn't claimed by another replica
+// while it is still running.
+func renewJobLease(job models.ScheduledJob) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(jobLease / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := database.DB.Model(&models.ScheduledJob{}).
+					Where("id = ? AND locked_by = ?", job.ID, jobWorkerID).
+					Update("locked_until", time.Now().Add(jobLease)).Error; err != nil {
+					log.Printf("Jobs Error: Failed to renew lease on %s: %v", job.Key, err)
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+func (s *ReminderService) executeJob(job models.ScheduledJob) error {
+	if job.GameID == nil && (job.Kind == JobReminder || job.Kind == JobRSVPDeadline || job.Kind == JobForfeitCheck) {
+		return fmt.Errorf("%s job has no game", job.Kind)
+	}
+	if job.GameID != nil {
+		var game models.Game
+		if err := database.DB.First(&game, "id = ?", *job.GameID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+		// The team has left the game's league since the job was scheduled
+		if confirmed, err := leagueTeamConfirmed(game); err != nil || !confirmed {
+			return err
+		}
+	}
+
+	var err error
+	switch job.Kind {
+	case JobReminder:
+		err = s.runReminderJob(*job.GameID, job.Payload)
+	case JobRSVPDeadline:
+		if err = resolveRSVPDeadline(*job.GameID); err == nil {
+			// Resolving "maybe" RSVPs can leave the game short
+			err = s.checkForfeitRisk(*job.GameID)
+		}
+	case JobForfeitCheck:
+		err = s.checkForfeitRisk(*job.GameID)
+	case JobSpareInvites:
+		ExpireSpareInvites(s.notifications)
+	case JobDigest:
+		var userID uuid.UUID
+		if userID, err = uuid.Parse(job.Payload); err == nil {
+			err = s.sendDigest(userID)
+		}
+	case JobReconcile:
+		if err = reconcileJobs(); err == nil {
+			err = s.scheduleReconcile()
+		}
+	default:
+		err = fmt.Errorf("unknown job kind %q", job.Kind)
+	}
+
+	// A deleted game has nothing left to do
+	if errors.Is(err, gorm.ErrRecordNotFound) && job.GameID != nil {
+		return nil
+	}
+	return err
+}
+
+// scheduleReconcile moves the reconcile job to the coming midnight.
+func (s *ReminderService) scheduleReconcile() error {
+	now := time.Now().In(s.location)
+	nextMidnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, s.location)
+	return ScheduleJob(JobReconcile, reconcileJobKey, nil, "", nextMidnight)
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/liam/screaming-toller/backend/internal/database"
+	"github.com/liam/screaming-toller/backend/internal/models"
+)
+
+// setupJobsDB connects to the Postgres database in TEST_DB_URL, migrating it
+// as the server does. Tests that need it are skipped when it isn't set.
+func setupJobsDB(t *testing.T) {
+	t.Helper()
+	url := os.Getenv("TEST_DB_URL")
+	if url == "" {
+		t.Skip("TEST_DB_URL is not set")
+	}
+	t.Setenv("DB_URL", url)
+	database.InitDB()
+}
+
+// createJobsTestGame adds a team with a game in three days, within the reach
+// of the reconcile, and its jobs.
+func createJobsTestGame(t *testing.T) models.Game {
+	t.Helper()
+	team := models.Team{Name: "Jobs Test Team", Status: "active"}
+	if err := database.DB.Create(&team).Error; err != nil {
+		t.Fatalf("create team: %v", err)
+	}
+	game := models.Game{
+		TeamID:       team.ID,
+		Date:         time.Now().AddDate(0, 0, 3).Truncate(24 * time.Hour),
+		Time:         "18:30",
+		OpposingTeam: "Opponents",
+		Status:       "scheduled",
+	}
+	if err := database.DB.Create(&game).Error; err != nil {
+		t.Fatalf("create game: %v", err)
+	}
+	t.Cleanup(func() {
+		database.DB.Where("game_id = ?", game.ID).Delete(&models.ScheduledJob{})
+		database.DB.Delete(&game)
+		database.DB.Delete(&team)
+	})
+
+	if err := ScheduleGameJobs(game.ID); err != nil {
+		t.Fatalf("schedule game jobs: %v", err)
+	}
+	return game
+}
+
+func gameJobs(t *testing.T, game models.Game) []models.ScheduledJob {
+	t.Helper()
+	var jobs []models.ScheduledJob
+	if err := database.DB.Where("game_id = ?", game.ID).Order("key").Find(&jobs).Error; err != nil {
+		t.Fatalf("load jobs: %v", err)
+	}
+	if len(jobs) == 0 {
+		t.Fatal("the game has no jobs")
+	}
+	return jobs
+}
+
+func TestReconcileKeepsFailedJobsFailed(t *testing.T) {
+	setupJobsDB(t)
+	game := createJobsTestGame(t)
+
+	if err := database.DB.Model(&models.ScheduledJob{}).Where("game_id = ?", game.ID).Updates(map[string]interface{}{
+		"status":     "failed",
+		"attempts":   jobMaxAttempts,
+		"last_error": "gateway down",
+	}).Error; err != nil {
+		t.Fatalf("fail jobs: %v", err)
+	}
+
+	if err := reconcileJobs(); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	for _, job := range gameJobs(t, game) {
+		if job.Status != "failed" || job.Attempts != jobMaxAttempts || job.LastError != "gateway down" {
+			t.Errorf("%s: got status %q, %d attempts, error %q after reconcile; want it left failed",
+				job.Key, job.Status, job.Attempts, job.LastError)
+		}
+	}
+}
+
+func TestReconcileKeepsRetryingJobsBackoff(t *testing.T) {
+	setupJobsDB(t)
+	game := createJobsTestGame(t)
+
+	retryAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	if err := database.DB.Model(&models.ScheduledJob{}).Where("game_id = ?", game.ID).Updates(map[string]interface{}{
+		"run_at":   retryAt,
+		"attempts": 2,
+	}).Error; err != nil {
+		t.Fatalf("retry jobs: %v", err)
+	}
+
+	if err := reconcileJobs(); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	for _, job := range gameJobs(t, game) {
+		if job.Status != "pending" || job.Attempts != 2 || !job.RunAt.Equal(retryAt) {
+			t.Errorf("%s: got status %q, %d attempts, run at %v after reconcile; want pending, 2 attempts, run at %v",
+				job.Key, job.Status, job.Attempts, job.RunAt, retryAt)
+		}
+	}
+}
+
+func TestMovedJobStartsOver(t *testing.T) {
+	setupJobsDB(t)
+	game := createJobsTestGame(t)
+	job := gameJobs(t, game)[0]
+
+	if err := database.DB.Model(&job).Updates(map[string]interface{}{
+		"status":   "failed",
+		"attempts": jobMaxAttempts,
+	}).Error; err != nil {
+		t.Fatalf("fail job: %v", err)
+	}
+
+	fireAt := job.FireAt.Add(2 * time.Hour)
+	if err := ScheduleJob(job.Kind, job.Key, job.GameID, job.Payload, fireAt); err != nil {
+		t.Fatalf("schedule job: %v", err)
+	}
+
+	var moved models.ScheduledJob
+	if err := database.DB.First(&moved, "id = ?", job.ID).Error; err != nil {
+		t.Fatalf("load job: %v", err)
+	}
+	if moved.Status != "pending" || moved.Attempts != 0 || !moved.FireAt.Equal(fireAt) || !moved.RunAt.Equal(fireAt) {
+		t.Errorf("got status %q, %d attempts, fire at %v, run at %v; want a pending job at %v",
+			moved.Status, moved.Attempts, moved.FireAt, moved.RunAt, fireAt)
+	}
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/liam/screaming-toller/backend/internal/database"
+	"github.com/liam/screaming-toller/backend/internal/models"
+)
+
+// NotifyLineupPublished sends every player marked going their batting slot
+// and inning-by-inning positions, and posts the full lineup to the team's
+// WhatsApp group.
+func NotifyLineupPublished(notifications *NotificationService, game models.Game) {
+	var team models.Team
+	if err := database.DB.First(&team, "id = ?", game.TeamID).Error; err != nil {
+		log.Printf("LineupNotifications Error: Could not load team for game %s: %v", game.ID, err)
+		return
+	}
+
+	var battingOrder []models.BattingOrder
+	if err := database.DB.Preload("TeamMember.User").Where("game_id = ?", game.ID).Order("batting_position").Find(&battingOrder).Error; err != nil {
+		log.Printf("LineupNotifications Error: Failed to fetch batting order for game %s: %v", game.ID, err)
+		return
+	}
+	var minorityPool []models.BattingOrderPool
+	if err := database.DB.Preload("TeamMember.User").Where("game_id = ?", game.ID).Order("pool_position").Find(&minorityPool).Error; err != nil {
+		log.Printf("LineupNotifications Error: Failed to fetch minority pool for game %s: %v", game.ID, err)
+		return
+	}
+	var fielding []models.FieldingLineup
+	if err := database.DB.Preload("TeamMember.User").Where("game_id = ?", game.ID).Order("inning, position").Find(&fielding).Error; err != nil {
+		log.Printf("LineupNotifications Error: Failed to fetch fielding lineup for game %s: %v", game.ID, err)
+		return
+	}
+
+	innings := lineupInnings(fielding)
+
+	var attendance []models.Attendance
+	if err := database.DB.Preload("TeamMember.User").Where("game_id = ? AND status = ?", game.ID, "going").Find(&attendance).Error; err != nil {
+		log.Printf("LineupNotifications Error: Failed to fetch attendance for game %s: %v", game.ID, err)
+	}
+
+	for _, att := range attendance {
+		user := att.TeamMember.User
+		mc := UserMessageContext(user, &team)
+		gameDate, gameTime := formatGameWhen(game, mc.Locale)
+
+		err := notifications.Notify(UserRecipient(user, &team), LineupPublishedMessage(mc, user.Name, team.Name, game.OpposingTeam, gameDate, gameTime,
+			gameVenue(game), battingSlot(att.TeamMemberID, battingOrder, minorityPool),
+			playerPositions(att.TeamMemberID, fielding, innings), team.ID.String()).ForGame(game.ID))
+		if err != nil {
+			log.Printf("LineupNotifications Error: Failed to send lineup to %s: %v", user.Email, err)
+		}
+	}
+
+	if team.WhatsAppGroupID == "" {
+		return
+	}
+
+	message := lineupSummary(team, game, battingOrder, minorityPool, fielding, innings)
+	if err := notifications.NotifyTeam(team, Message{Type: TypeLineupPublished, Text: message}.ForGame(game.ID)); err != nil {
+		log.Printf("LineupNotifications Error: Failed to post lineup for game %s: %v", game.ID, err)
+	}
+}
+
+// LineupSlot is where a player bats: a fixed position in the order, or a
+// turn in the minority gender pool that rotates through the placeholder
+// slots. It is zero for players not in the batting order.
+type LineupSlot struct {
+	BattingPosition int
+	PoolGender      string
+	PoolPosition    int
+}
+
+// InningPosition is where a player fields in one inning. Position is empty
+// when they sit out.
+type InningPosition struct {
+	Inning   int
+	Position string
+}
+
+// battingSlot finds where the player bats.
+func battingSlot(memberID uuid.UUID, battingOrder []models.BattingOrder, minorityPool []models.BattingOrderPool) LineupSlot {
+	for _, slot := range battingOrder {
+		if slot.TeamMemberID != nil && *slot.TeamMemberID == memberID {
+			return LineupSlot{BattingPosition: slot.BattingPosition}
+		}
+	}
+	for _, pool := range minorityPool {
+		if pool.TeamMemberID == memberID {
+			return LineupSlot{PoolGender: pool.TeamMember.Gender, PoolPosition: pool.PoolPosition}
+		}
+	}
+	return LineupSlot{}
+}
+
+// playerPositions lists the player's position in each inning.
+func playerPositions(memberID uuid.UUID, fielding []models.FieldingLineup, innings []int) []InningPosition {
+	positions := make(map[int]string)
+	for _, f := range fielding {
+		if f.TeamMemberID == memberID && f.Position != "Bench" {
+			positions[f.Inning] = f.Position
+		}
+	}
+
+	lines := make([]InningPosition, 0, len(innings))
+	for _, inning := range innings {
+		lines = append(lines, InningPosition{Inning: inning, Position: positions[inning]})
+	}
+	return lines
+}
+
+func lineupInnings(fielding []models.FieldingLineup) []int {
+	seen := make(map[int]bool)
+	var innings []int
+	for _, f := range fielding {
+		if !seen[f.Inning] {
+			seen[f.Inning] = true
+			innings = append(innings, f.Inning)
+		}
+	}
+	sort.Ints(innings)
+	return innings
+}
+
+// lineupSummarySlot is one line of the batting order in the group post.
+// PlaceholderGender is set for the pool's placeholder slots.
+type lineupSummarySlot struct {
+	Position          int
+	Name              string
+	PlaceholderGender string
+}
+
+// lineupSummaryInning lists who fields where in one inning, e.g. "SS Alex".
+type lineupSummaryInning struct {
+	Inning int
+	Spots  []string
+}
+
+// lineupSummary words the full lineup for the team's WhatsApp group.
+func lineupSummary(team models.Team, game models.Game, battingOrder []models.BattingOrder,
+	minorityPool []models.BattingOrderPool, fielding []models.FieldingLineup, innings []int) string {
+	slots := make([]lineupSummarySlot, len(battingOrder))
+	for i, slot := range battingOrder {
+		slots[i] = lineupSummarySlot{Position: slot.BattingPosition, Name: slot.TeamMember.User.Name}
+		if slot.IsPlaceholder {
+			slots[i] = lineupSummarySlot{Position: slot.BattingPosition, PlaceholderGender: slot.PlaceholderGender}
+		}
+	}
+
+	var poolGender string
+	pool := make([]string, len(minorityPool))
+	for i, p := range minorityPool {
+		poolGender = p.TeamMember.Gender
+		pool[i] = p.TeamMember.User.Name
+	}
+
+	summaryInnings := make([]lineupSummaryInning, 0, len(innings))
+	for _, inning := range innings {
+		var spots []string
+		for _, f := range fielding {
+			if f.Inning == inning && f.Position != "Bench" {
+				spots = append(spots, fmt.Sprintf("%s %s", f.Position, f.TeamMember.User.Name))
+			}
+		}
+		summaryInnings = append(summaryInnings, lineupSummaryInning{Inning: inning, Spots: spots})
+	}
+
+	mc := TeamMessageContext(team)
+	gameDate, gameTime := formatGameWhen(game, mc.Locale)
+	return renderWhatsApp(mc, "lineup_summary", map[string]interface{}{
+		"TeamName":     team.Name,
+		"Opponent":     game.OpposingTeam,
+		"Date":         gameDate,
+		"Time":         gameTime,
+		"Venue":        gameVenue(game),
+		"BattingOrder": slots,
+		"PoolGender":   poolGender,
+		"Pool":         pool,
+		"Fielding":     summaryInnings,
+		"GamesURL":     TeamGamesURL(team.ID),
+	})
+}
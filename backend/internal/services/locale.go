@@ -0,0 +1,112 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/liam/screaming-toller/backend/internal/models"
+)
+
+// DefaultLocale is used when neither the user nor their team has picked a
+// language.
+const DefaultLocale = "en"
+
+// Locales are the languages messages can be sent in.
+var Locales = []string{"en", "fr"}
+
+// SupportedLocale reports whether messages can be sent in the locale.
+func SupportedLocale(locale string) bool {
+	for _, l := range Locales {
+		if l == locale {
+			return true
+		}
+	}
+	return false
+}
+
+// MessageContext says how to word a message: the locale to write it in, and
+// the team whose wording overrides apply, if any.
+type MessageContext struct {
+	Locale string
+	TeamID *uuid.UUID
+}
+
+// UserMessageContext words a message for a user, in their own locale or else
+// their team's. team may be nil for messages that aren't about a team.
+func UserMessageContext(user models.User, team *models.Team) MessageContext {
+	mc := MessageContext{Locale: DefaultLocale}
+	if team != nil {
+		mc = TeamMessageContext(*team)
+	}
+	if SupportedLocale(user.Locale) {
+		mc.Locale = user.Locale
+	}
+	return mc
+}
+
+// TeamMessageContext words a message in the team's locale, for its WhatsApp
+// group and for people who aren't users yet.
+func TeamMessageContext(team models.Team) MessageContext {
+	mc := MessageContext{Locale: DefaultLocale, TeamID: &team.ID}
+	if SupportedLocale(team.Locale) {
+		mc.Locale = team.Locale
+	}
+	return mc
+}
+
+var (
+	frenchDays        = [...]string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"}
+	frenchShortDays   = [...]string{"dim.", "lun.", "mar.", "mer.", "jeu.", "ven.", "sam."}
+	frenchShortMonths = [...]string{"janv.", "févr.", "mars", "avr.", "mai", "juin", "juil.", "août", "sept.", "oct.", "nov.", "déc."}
+)
+
+// frenchDayOfMonth writes the first of the month as "1er", as French does.
+func frenchDayOfMonth(t time.Time) string {
+	if t.Day() == 1 {
+		return "1er"
+	}
+	return fmt.Sprint(t.Day())
+}
+
+// formatDay formats a date for game details: "Monday, Jan 2" or
+// "lundi 2 janv.".
+func formatDay(t time.Time, locale string) string {
+	if locale == "fr" {
+		return fmt.Sprintf("%s %s %s", frenchDays[t.Weekday()], frenchDayOfMonth(t), frenchShortMonths[t.Month()-1])
+	}
+	return t.Format("Monday, Jan 2")
+}
+
+// formatShortDay formats a date compactly: "Mon Jan 2" or "lun. 2 janv.".
+func formatShortDay(t time.Time, locale string) string {
+	if locale == "fr" {
+		return fmt.Sprintf("%s %s %s", frenchShortDays[t.Weekday()], frenchDayOfMonth(t), frenchShortMonths[t.Month()-1])
+	}
+	return t.Format("Mon Jan 2")
+}
+
+// formatWeekday names the day of the week: "Monday" or "lundi".
+func formatWeekday(t time.Time, locale string) string {
+	if locale == "fr" {
+		return frenchDays[t.Weekday()]
+	}
+	return t.Format("Monday")
+}
+
+// formatClock formats a time of day: "6:30 PM" or "18 h 30".
+func formatClock(t time.Time, locale string) string {
+	if locale == "fr" {
+		return t.Format("15 h 04")
+	}
+	return t.Format("3:04 PM")
+}
+
+// formatDayTime formats a deadline: "Mon Jan 2 at 6:30 PM" or
+// "lun. 2 janv. à 18 h 30".
+func formatDayTime(t time.Time, locale string) string {
+	if locale == "fr" {
+		return formatShortDay(t, locale) + " à " + formatClock(t, locale)
+	}
+	return formatShortDay(t, locale) + " at " + formatClock(t, locale)
+}
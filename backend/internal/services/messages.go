@@ -0,0 +1,200 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/liam/screaming-toller/backend/internal/algorithms"
+)
+
+// Each builder below words one message with its template in templates/ (see
+// templates.go), in the locale and with the team overrides mc picks.
+
+// InvitationMessage invites a new member to join the team
+func InvitationMessage(mc MessageContext, teamName, inviterName, token string) Message {
+	return renderMessage(mc, TypeInvitation, "invitation", map[string]interface{}{
+		"TeamName":      teamName,
+		"InviterName":   inviterName,
+		"InvitationURL": fmt.Sprintf("%s/accept-invitation/%s", getAppURL(), token),
+	})
+}
+
+// TeamRequestMessage tells a super admin about a new team creation request
+func TeamRequestMessage(mc MessageContext, requesterName, teamName string) Message {
+	return renderMessage(mc, TypeTeamRequest, "team_request", map[string]interface{}{
+		"RequesterName": requesterName,
+		"TeamName":      teamName,
+	})
+}
+
+// TeamApprovedMessage tells the requester their team was approved
+func TeamApprovedMessage(mc MessageContext, teamName string) Message {
+	return renderMessage(mc, TypeTeamApproved, "team_approved", map[string]interface{}{
+		"TeamName": teamName,
+	})
+}
+
+// TeamRejectedMessage tells the requester their team was declined
+func TeamRejectedMessage(mc MessageContext, teamName string) Message {
+	return renderMessage(mc, TypeTeamRejected, "team_rejected", map[string]interface{}{
+		"TeamName": teamName,
+	})
+}
+
+// GameVenue holds the location details shown in game notifications. The
+// templates render it with the venue_html, venue_text and venue_whatsapp
+// partials.
+type GameVenue struct {
+	Name          string
+	Address       string
+	DiamondNumber string
+	ParkingNotes  string
+	MapsURL       string
+}
+
+// AttendanceReminderMessage reminds a user about an upcoming game and the RSVP
+// they currently have (status). when says when the game is relative to now,
+// e.g. "tomorrow" (see reminderWhen). rsvpDeadline is empty when the game has
+// no RSVP deadline. When rsvpToken is set the message carries one-click
+// "Going" / "Not going" links that answer without logging in.
+func AttendanceReminderMessage(mc MessageContext, teamName, opponent, gameDate, gameTime string, venue GameVenue, when, status, rsvpDeadline, rsvpToken, teamID string) Message {
+	var rsvpURL string
+	if rsvpToken != "" {
+		rsvpURL = fmt.Sprintf("%s/api/rsvp/%s", getAppURL(), rsvpToken)
+	}
+
+	return renderMessage(mc, TypeAttendanceReminder, "attendance_reminder", map[string]interface{}{
+		"TeamName":     teamName,
+		"Opponent":     opponent,
+		"Date":         gameDate,
+		"Time":         gameTime,
+		"Venue":        venue,
+		"When":         when,
+		"Status":       status,
+		"RSVPDeadline": rsvpDeadline,
+		"RSVPURL":      rsvpURL,
+		"GamesURL":     fmt.Sprintf("%s/teams/%s/games", getAppURL(), teamID),
+	})
+}
+
+// GameRescheduledMessage tells a member that a game was postponed. newDate and
+// newTime are empty when the make-up date hasn't been set yet.
+func GameRescheduledMessage(mc MessageContext, teamName, opponent, originalDate, newDate, newTime string, venue GameVenue, reason, teamID string) Message {
+	return renderMessage(mc, TypeGameRescheduled, "game_rescheduled",
+		gameRescheduledData(teamName, opponent, originalDate, newDate, newTime, venue, reason, teamID))
+}
+
+func gameRescheduledData(teamName, opponent, originalDate, newDate, newTime string, venue GameVenue, reason, teamID string) map[string]interface{} {
+	return map[string]interface{}{
+		"TeamName":     teamName,
+		"Opponent":     opponent,
+		"OriginalDate": originalDate,
+		"NewDate":      newDate,
+		"NewTime":      newTime,
+		"Venue":        venue,
+		"Reason":       reason,
+		"GamesURL":     fmt.Sprintf("%s/teams/%s/games", getAppURL(), teamID),
+	}
+}
+
+// SpareRequestMessage asks a spare to fill in for one game. The links lead
+// to a page where they can accept or decline without logging in.
+func SpareRequestMessage(mc MessageContext, spareName, teamName, opponent, gameDate, gameTime string, venue GameVenue, token, expiresAt string) Message {
+	return renderMessage(mc, TypeSpareRequest, "spare_request", map[string]interface{}{
+		"SpareName":  spareName,
+		"TeamName":   teamName,
+		"Opponent":   opponent,
+		"Date":       gameDate,
+		"Time":       gameTime,
+		"Venue":      venue,
+		"ExpiresAt":  expiresAt,
+		"RespondURL": fmt.Sprintf("%s/api/spare-invites/%s", getAppURL(), token),
+	})
+}
+
+// SpareRequestUpdateMessage tells the admin who asked for a spare how the
+// request turned out: acceptedBy is the spare who said yes, or empty when
+// every spare of the gender has been asked.
+func SpareRequestUpdateMessage(mc MessageContext, teamName, opponent, gameDate, acceptedBy, gender, teamID string) Message {
+	return renderMessage(mc, TypeSpareRequestUpdate, "spare_request_update", map[string]interface{}{
+		"TeamName":  teamName,
+		"Opponent":  opponent,
+		"Date":      gameDate,
+		"SpareName": acceptedBy,
+		"Gender":    gender,
+		"GamesURL":  fmt.Sprintf("%s/teams/%s/games", getAppURL(), teamID),
+	})
+}
+
+// ForfeitRiskAlertMessage warns a team admin that a game can't field a
+// legal lineup from the players confirmed so far. urgent is set for the final
+// alert before the game.
+func ForfeitRiskAlertMessage(mc MessageContext, teamName, opponent, gameDate, gameTime string, risk algorithms.ForfeitRisk, urgent bool, teamID string) Message {
+	return renderMessage(mc, TypeForfeitAlert, "forfeit_alert", forfeitAlertData(teamName, opponent, gameDate, gameTime, risk, urgent, teamID))
+}
+
+func forfeitAlertData(teamName, opponent, gameDate, gameTime string, risk algorithms.ForfeitRisk, urgent bool, teamID string) map[string]interface{} {
+	return map[string]interface{}{
+		"TeamName":     teamName,
+		"Opponent":     opponent,
+		"Date":         gameDate,
+		"Time":         gameTime,
+		"Risk":         risk,
+		"FieldPlayers": algorithms.FieldPlayers,
+		"Urgent":       urgent,
+		"GamesURL":     fmt.Sprintf("%s/teams/%s/games", getAppURL(), teamID),
+	}
+}
+
+// ShortHandedReminderMessage asks a player who hasn't confirmed to respond
+// because the team is short players of their gender ("M" or "F").
+func ShortHandedReminderMessage(mc MessageContext, teamName, opponent, gameDate, gameTime string, venue GameVenue, gender, teamID string) Message {
+	return renderMessage(mc, TypeShortHanded, "short_handed", map[string]interface{}{
+		"TeamName": teamName,
+		"Opponent": opponent,
+		"Date":     gameDate,
+		"Time":     gameTime,
+		"Venue":    venue,
+		"Gender":   gender,
+		"GamesURL": fmt.Sprintf("%s/teams/%s/games", getAppURL(), teamID),
+	})
+}
+
+// LineupPublishedMessage tells a player where they bat and play in each
+// inning once the admin publishes the lineup.
+func LineupPublishedMessage(mc MessageContext, playerName, teamName, opponent, gameDate, gameTime string, venue GameVenue, slot LineupSlot, positions []InningPosition, teamID string) Message {
+	return renderMessage(mc, TypeLineupPublished, "lineup_published", map[string]interface{}{
+		"PlayerName":  playerName,
+		"TeamName":    teamName,
+		"Opponent":    opponent,
+		"Date":        gameDate,
+		"Time":        gameTime,
+		"Venue":       venue,
+		"BattingSlot": slot,
+		"Positions":   positions,
+		"GamesURL":    fmt.Sprintf("%s/teams/%s/games", getAppURL(), teamID),
+	})
+}
+
+// ScoreUpdateMessage tells a member the final score of one of their team's
+// games once it is entered.
+func ScoreUpdateMessage(mc MessageContext, teamName, opponent, gameDate string, teamScore, opponentScore int, teamID string) Message {
+	return renderMessage(mc, TypeScoreUpdate, "score_update", map[string]interface{}{
+		"TeamName":      teamName,
+		"Opponent":      opponent,
+		"Date":          gameDate,
+		"TeamScore":     teamScore,
+		"OpponentScore": opponentScore,
+		"GamesURL":      fmt.Sprintf("%s/teams/%s/games", getAppURL(), teamID),
+	})
+}
+
+// WeeklyDigestMessage summarizes a player's week: the games coming up with
+// their RSVP, who's confirmed and any lineup they're in, and last week's
+// results.
+func WeeklyDigestMessage(mc MessageContext, playerName string, games []DigestGame, results []DigestResult) Message {
+	return renderMessage(mc, TypeWeeklyDigest, "weekly_digest", map[string]interface{}{
+		"PlayerName": playerName,
+		"Games":      games,
+		"Results":    results,
+	})
+}
@@ -0,0 +1,91 @@
+package services
+
+import "sort"
+
+// Notification types. Each message carries one so the NotificationService
+// knows which channels to deliver it on.
+const (
+	TypeInvitation         = "invitation"
+	TypeTeamRequest        = "team_request"
+	TypeTeamApproved       = "team_approved"
+	TypeTeamRejected       = "team_rejected"
+	TypeAttendanceReminder = "attendance_reminder"
+	TypeGameRescheduled    = "game_rescheduled"
+	TypeSpareRequest       = "spare_request"
+	TypeSpareRequestUpdate = "spare_request_update"
+	TypeForfeitAlert       = "forfeit_alert"
+	TypeShortHanded        = "short_handed"
+	TypeLineupPublished    = "lineup_published"
+	TypeWeeklyDigest       = "weekly_digest"
+	TypeScoreUpdate        = "score_update"
+)
+
+// NotificationType describes a kind of notification and how it is delivered
+// unless the user says otherwise.
+type NotificationType struct {
+	Key             string   `json:"type"`
+	Description     string   `json:"description"`
+	DefaultChannels []string `json:"defaultChannels"`
+	Reminder        bool     `json:"reminder"`      // Not sent to users who opted out of reminders
+	Transactional   bool     `json:"transactional"` // Always sent on the default channels; users can't change them
+	SMS             bool     `json:"sms"`           // Can be sent by SMS to verified numbers
+	Push            bool     `json:"push"`          // Can be sent as a push notification to subscribed devices
+}
+
+// AllowsChannel reports whether the type can be sent on the channel. SMS and
+// push are kept to the few types worth interrupting someone for.
+func (t NotificationType) AllowsChannel(channel string) bool {
+	switch channel {
+	case ChannelSMS:
+		return t.SMS
+	case ChannelPush:
+		return t.Push
+	}
+	return true
+}
+
+var notificationTypes = map[string]NotificationType{}
+
+// RegisterNotificationType adds a notification type, or replaces one with the
+// same key. New kinds of notification only need to register here and build a
+// Message with their key.
+func RegisterNotificationType(t NotificationType) {
+	notificationTypes[t.Key] = t
+}
+
+// LookupNotificationType returns the registered type with the given key.
+func LookupNotificationType(key string) (NotificationType, bool) {
+	t, ok := notificationTypes[key]
+	return t, ok
+}
+
+// NotificationTypes returns every registered type ordered by key.
+func NotificationTypes() []NotificationType {
+	types := make([]NotificationType, 0, len(notificationTypes))
+	for _, t := range notificationTypes {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i].Key < types[j].Key })
+	return types
+}
+
+func init() {
+	email := []string{ChannelEmail}
+	emailSMSAndPush := []string{ChannelEmail, ChannelSMS, ChannelPush}
+	emailAndPush := []string{ChannelEmail, ChannelPush}
+	push := []string{ChannelPush}
+
+	RegisterNotificationType(NotificationType{Key: TypeInvitation, Description: "Invitation to join a team", DefaultChannels: email, Transactional: true})
+	RegisterNotificationType(NotificationType{Key: TypeTeamRequest, Description: "New team requests (super admins)", DefaultChannels: email})
+	RegisterNotificationType(NotificationType{Key: TypeTeamApproved, Description: "Your team request was approved", DefaultChannels: email, Transactional: true})
+	RegisterNotificationType(NotificationType{Key: TypeTeamRejected, Description: "Your team request was declined", DefaultChannels: email, Transactional: true})
+	RegisterNotificationType(NotificationType{Key: TypeAttendanceReminder, Description: "Reminders to RSVP for an upcoming game", DefaultChannels: emailSMSAndPush, Reminder: true, SMS: true, Push: true})
+	RegisterNotificationType(NotificationType{Key: TypeGameRescheduled, Description: "A game was rescheduled", DefaultChannels: emailAndPush, Push: true})
+	RegisterNotificationType(NotificationType{Key: TypeSpareRequest, Description: "Requests to play as a spare", DefaultChannels: email, Transactional: true})
+	RegisterNotificationType(NotificationType{Key: TypeSpareRequestUpdate, Description: "Updates on spare requests you made", DefaultChannels: email})
+	RegisterNotificationType(NotificationType{Key: TypeForfeitAlert, Description: "Forfeit risk alerts (team admins)", DefaultChannels: email})
+	RegisterNotificationType(NotificationType{Key: TypeShortHanded, Description: "Your team is short players for a game", DefaultChannels: emailSMSAndPush, Reminder: true, SMS: true, Push: true})
+	RegisterNotificationType(NotificationType{Key: TypeLineupPublished, Description: "The lineup for a game was published", DefaultChannels: emailSMSAndPush, SMS: true, Push: true})
+	RegisterNotificationType(NotificationType{Key: TypeWeeklyDigest, Description: "Weekly digest of your games, RSVPs, results and lineups", DefaultChannels: email})
+	RegisterNotificationType(NotificationType{Key: TypeScoreUpdate, Description: "A score was entered for one of your games", DefaultChannels: push, Push: true})
+}
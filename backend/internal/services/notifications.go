@@ -0,0 +1,355 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/liam/screaming-toller/backend/internal/database"
+	"github.com/liam/screaming-toller/backend/internal/models"
+	"gorm.io/gorm/clause"
+)
+
+// Channels a notification can be delivered on.
+const (
+	ChannelEmail    = "email"
+	ChannelWhatsApp = "whatsapp"
+	ChannelSMS      = "sms"  // Only for types that allow it, and only to verified numbers that haven't opted out
+	ChannelPush     = "push" // Web Push to the user's subscribed devices, for types that allow it
+	ChannelLog      = "log"  // Local development; receives every notification when enabled
+)
+
+// UserChannels are the channels users can turn on or off per notification type.
+var UserChannels = []string{ChannelEmail, ChannelWhatsApp, ChannelSMS, ChannelPush}
+
+// ErrNoAddress is returned by a channel that has no way to reach the
+// recipient, e.g. WhatsApp for a team without a Whapi token. The delivery is
+// marked skipped rather than retried.
+var ErrNoAddress = errors.New("recipient has no address on this channel")
+
+// Message is the content of one notification. HTML is used by email; chat
+// channels and the log use Text. SMS and Push are the shorter texts sent by
+// SMS and push notification, when the message has them; URL is the page a
+// push notification opens.
+type Message struct {
+	Type    string
+	Subject string
+	HTML    string
+	Text    string
+	SMS     string
+	Push    string
+	URL     string
+	GameID  *uuid.UUID // The game the message is about, so admins can see its deliveries
+}
+
+// ForGame ties the message to a game.
+func (m Message) ForGame(gameID uuid.UUID) Message {
+	m.GameID = &gameID
+	return m
+}
+
+// Recipient is who a notification goes to. UserID is nil for people without
+// an account (invitees, spares), who always get the type's default channels.
+type Recipient struct {
+	UserID          *uuid.UUID
+	Name            string
+	Email           string
+	Phone           string
+	OptOutReminders bool
+	PhoneVerified   bool
+	Team            *models.Team // The team the message is about; chat channels send with its credentials
+}
+
+// UserRecipient addresses a notification to a user about one of their teams.
+func UserRecipient(user models.User, team *models.Team) Recipient {
+	return Recipient{
+		UserID:          &user.ID,
+		Name:            user.Name,
+		Email:           user.Email,
+		Phone:           user.Phone,
+		OptOutReminders: user.OptOutReminders,
+		PhoneVerified:   user.PhoneVerifiedAt != nil,
+		Team:            team,
+	}
+}
+
+// Notifier delivers messages on one channel. Address picks the recipient's
+// address on the channel ("" when they can't be reached there); Send delivers
+// to it and returns the provider's message ID. team is the team the message is
+// about, if any.
+type Notifier interface {
+	Channel() string
+	SendInterval() time.Duration // Minimum gap between sends, to respect the provider's rate limit
+	Address(to Recipient) string
+	Send(address string, team *models.Team, msg Message) (string, error)
+}
+
+// TeamNotifier is implemented by channels that can also post to a team's
+// shared chat, such as its WhatsApp group.
+type TeamNotifier interface {
+	TeamAddress(team models.Team) string
+}
+
+// NotificationService routes each message to the channels its type and the
+// recipient's preferences call for. Messages are queued in the outbox and
+// delivered by the outbox worker.
+type NotificationService struct {
+	notifiers []Notifier
+}
+
+// NewNotificationService sets up every channel that is configured: email when
+// EMAIL_TRANSPORT or RESEND_API_KEY is set, WhatsApp (which sends with each team's own token),
+// SMS when SMS_GATEWAY or TWILIO_ACCOUNT_SID is set, push when the VAPID keys
+// are set, and the log channel when NOTIFICATION_LOG is "stdout" or a file
+// path.
+func NewNotificationService() *NotificationService {
+	n := &NotificationService{}
+
+	if emailService, err := NewEmailService(); err == nil {
+		n.notifiers = append(n.notifiers, &EmailNotifier{service: emailService})
+	}
+	if whatsAppService, err := NewWhatsAppService(); err == nil {
+		n.notifiers = append(n.notifiers, &WhatsAppNotifier{service: whatsAppService})
+	} else {
+		log.Printf("Warning: WhatsApp channel not configured: %v", err)
+	}
+
+	if gateway, err := NewSMSGateway(); err != nil {
+		log.Printf("Warning: SMS channel not configured: %v", err)
+	} else if gateway != nil {
+		n.notifiers = append(n.notifiers, &SMSNotifier{gateway: gateway})
+	}
+
+	if sender, err := NewWebPushSender(); err != nil {
+		log.Printf("Warning: Push channel not configured: %v", err)
+	} else if sender != nil {
+		n.notifiers = append(n.notifiers, &PushNotifier{sender: sender})
+	}
+
+	if dest := os.Getenv("NOTIFICATION_LOG"); dest != "" {
+		logNotifier, err := NewLogNotifier(dest)
+		if err != nil {
+			log.Printf("Warning: Notification log not enabled: %v", err)
+		} else {
+			n.notifiers = append(n.notifiers, logNotifier)
+		}
+	}
+	return n
+}
+
+// Close releases what the channels hold open, such as log files. Call it
+// once the outbox worker has stopped.
+func (n *NotificationService) Close() {
+	for _, notifier := range n.notifiers {
+		if closer, ok := notifier.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				log.Printf("Warning: Failed to close %s channel: %v", notifier.Channel(), err)
+			}
+		}
+	}
+}
+
+// smsGateway is the SMS channel's gateway, or nil when SMS isn't configured.
+func (n *NotificationService) smsGateway() SMSGateway {
+	for _, notifier := range n.notifiers {
+		if sms, ok := notifier.(*SMSNotifier); ok {
+			return sms.gateway
+		}
+	}
+	return nil
+}
+
+// HasChannel reports whether the channel is configured.
+func (n *NotificationService) HasChannel(channel string) bool {
+	for _, notifier := range n.notifiers {
+		if notifier.Channel() == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// Notify queues the message for the recipient on each channel enabled for
+// the message type. Reminder types are skipped for users who opted out of
+// reminders, and channels that can't reach the recipient are skipped.
+func (n *NotificationService) Notify(to Recipient, msg Message) error {
+	notificationType, ok := LookupNotificationType(msg.Type)
+	if !ok {
+		return fmt.Errorf("unknown notification type %q", msg.Type)
+	}
+	if notificationType.Reminder && to.OptOutReminders {
+		return nil
+	}
+
+	channels, err := recipientChannels(to, notificationType)
+	if err != nil {
+		return err
+	}
+	return n.enqueueFor(to, msg, notificationType, channels)
+}
+
+// NotifyVia is Notify with the channels chosen by the caller instead of the
+// type's defaults, e.g. by a team's reminder rule. Channels the user has
+// turned off for the type are still skipped.
+func (n *NotificationService) NotifyVia(to Recipient, msg Message, channels []string) error {
+	notificationType, ok := LookupNotificationType(msg.Type)
+	if !ok {
+		return fmt.Errorf("unknown notification type %q", msg.Type)
+	}
+	if notificationType.Reminder && to.OptOutReminders {
+		return nil
+	}
+
+	enabled := make(map[string]bool)
+	for _, channel := range channels {
+		enabled[channel] = true
+	}
+	if to.UserID != nil && !notificationType.Transactional {
+		preferences, err := userPreferences(*to.UserID, notificationType.Key)
+		if err != nil {
+			return err
+		}
+		for channel, on := range preferences {
+			if !on {
+				enabled[channel] = false
+			}
+		}
+	}
+	return n.enqueueFor(to, msg, notificationType, enabled)
+}
+
+func (n *NotificationService) enqueueFor(to Recipient, msg Message, notificationType NotificationType, channels map[string]bool) error {
+	var queued []models.OutboxMessage
+	for _, notifier := range n.notifiers {
+		if notifier.Channel() != ChannelLog && !channels[notifier.Channel()] {
+			continue
+		}
+		if !notificationType.AllowsChannel(notifier.Channel()) {
+			continue
+		}
+		address := notifier.Address(to)
+		if address == "" {
+			continue
+		}
+		outbox := newOutboxMessage(notifier.Channel(), address, to.Team, msg)
+		outbox.RecipientUserID = to.UserID
+		queued = append(queued, outbox)
+	}
+	return enqueue(queued)
+}
+
+// NotifyTeam queues the message for the team's shared chat on every channel
+// that has one.
+func (n *NotificationService) NotifyTeam(team models.Team, msg Message) error {
+	var queued []models.OutboxMessage
+	for _, notifier := range n.notifiers {
+		teamNotifier, ok := notifier.(TeamNotifier)
+		if !ok {
+			continue
+		}
+		if address := teamNotifier.TeamAddress(team); address != "" {
+			queued = append(queued, newOutboxMessage(notifier.Channel(), address, &team, msg))
+		}
+	}
+	return enqueue(queued)
+}
+
+// recipientChannels starts from the type's default channels and applies the
+// user's saved preferences. Transactional types ignore preferences.
+func recipientChannels(to Recipient, notificationType NotificationType) (map[string]bool, error) {
+	channels := make(map[string]bool)
+	for _, channel := range notificationType.DefaultChannels {
+		channels[channel] = true
+	}
+	if to.UserID == nil || notificationType.Transactional {
+		return channels, nil
+	}
+
+	preferences, err := userPreferences(*to.UserID, notificationType.Key)
+	if err != nil {
+		return nil, err
+	}
+	for channel, enabled := range preferences {
+		channels[channel] = enabled
+	}
+	return channels, nil
+}
+
+// userPreferences returns the channels the user has explicitly turned on or
+// off for the notification type.
+func userPreferences(userID uuid.UUID, notificationType string) (map[string]bool, error) {
+	var preferences []models.NotificationPreference
+	if err := database.DB.Where("user_id = ? AND type = ?", userID, notificationType).Find(&preferences).Error; err != nil {
+		return nil, fmt.Errorf("failed to load notification preferences: %w", err)
+	}
+
+	channels := make(map[string]bool, len(preferences))
+	for _, preference := range preferences {
+		channels[preference.Channel] = preference.Enabled
+	}
+	return channels, nil
+}
+
+// TypePreferences is a user's effective channels for one notification type.
+type TypePreferences struct {
+	NotificationType
+	Channels map[string]bool `json:"channels"`
+}
+
+// ChannelPreferences lists every notification type a user can configure with
+// the channels currently enabled for them.
+func ChannelPreferences(userID uuid.UUID) ([]TypePreferences, error) {
+	var result []TypePreferences
+	for _, notificationType := range NotificationTypes() {
+		if notificationType.Transactional {
+			continue
+		}
+		channels, err := recipientChannels(Recipient{UserID: &userID}, notificationType)
+		if err != nil {
+			return nil, err
+		}
+		preferences := TypePreferences{NotificationType: notificationType, Channels: make(map[string]bool)}
+		for _, channel := range UserChannels {
+			if notificationType.AllowsChannel(channel) {
+				preferences.Channels[channel] = channels[channel]
+			}
+		}
+		result = append(result, preferences)
+	}
+	return result, nil
+}
+
+// SetChannelPreference turns a channel on or off for one notification type.
+func SetChannelPreference(userID uuid.UUID, notificationType, channel string, enabled bool) error {
+	t, ok := LookupNotificationType(notificationType)
+	if !ok || t.Transactional {
+		return fmt.Errorf("unknown notification type %q", notificationType)
+	}
+	if !isUserChannel(channel) || !t.AllowsChannel(channel) {
+		return fmt.Errorf("unknown channel %q", channel)
+	}
+
+	preference := models.NotificationPreference{
+		UserID:  userID,
+		Type:    notificationType,
+		Channel: channel,
+		Enabled: enabled,
+	}
+	return database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "type"}, {Name: "channel"}},
+		DoUpdates: clause.AssignmentColumns([]string{"enabled", "updated_at"}),
+	}).Create(&preference).Error
+}
+
+func isUserChannel(channel string) bool {
+	for _, c := range UserChannels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
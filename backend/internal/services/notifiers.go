@@ -0,0 +1,251 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/liam/screaming-toller/backend/internal/database"
+	"github.com/liam/screaming-toller/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// EmailNotifier delivers notifications by email.
+type EmailNotifier struct {
+	service *EmailService
+}
+
+func (n *EmailNotifier) Channel() string { return ChannelEmail }
+
+func (n *EmailNotifier) Close() error { return n.service.Close() }
+
+// SendInterval is the email transport's, e.g. Resend's limit of 5 emails per
+// second.
+func (n *EmailNotifier) SendInterval() time.Duration { return n.service.SendInterval() }
+
+func (n *EmailNotifier) Address(to Recipient) string { return to.Email }
+
+func (n *EmailNotifier) Send(address string, team *models.Team, msg Message) (string, error) {
+	return n.service.Send(address, msg)
+}
+
+// WhatsAppNotifier delivers notifications as WhatsApp messages, sent with the
+// Whapi token of the team the message is about.
+type WhatsAppNotifier struct {
+	service *WhatsAppService
+}
+
+func (n *WhatsAppNotifier) Channel() string { return ChannelWhatsApp }
+
+// SendInterval spaces messages out so a burst of reminders doesn't look like
+// spam to WhatsApp.
+func (n *WhatsAppNotifier) SendInterval() time.Duration { return 2 * time.Second }
+
+// Address is the recipient's phone number. Messages not tied to a team can't
+// be sent, since the team provides the Whapi token.
+func (n *WhatsAppNotifier) Address(to Recipient) string {
+	if to.Team == nil {
+		return ""
+	}
+	return to.Phone
+}
+
+// TeamAddress is the team's WhatsApp group.
+func (n *WhatsAppNotifier) TeamAddress(team models.Team) string { return team.WhatsAppGroupID }
+
+// Send messages a phone number or group with the team's token. Teams without
+// a token are reported as unreachable rather than failing. Each send is
+// recorded as the team's WhatsApp health.
+func (n *WhatsAppNotifier) Send(address string, team *models.Team, msg Message) (string, error) {
+	if team == nil {
+		return "", ErrNoAddress
+	}
+	token, err := teamWhapiToken(*team)
+	if err != nil {
+		recordWhatsAppResult(team.ID, err)
+		return "", fmt.Errorf("%w: %v", ErrNoAddress, err)
+	}
+
+	var id string
+	if address == team.WhatsAppGroupID {
+		id, err = n.service.SendGroupMessage(token, address, msg.Text)
+	} else {
+		id, err = n.service.SendDirectMessage(token, address, whatsAppText(msg))
+	}
+	recordWhatsAppResult(team.ID, err)
+	return id, err
+}
+
+// whatsAppText puts the subject in bold above the body, since chat messages
+// have no subject line.
+func whatsAppText(msg Message) string {
+	if msg.Subject == "" {
+		return msg.Text
+	}
+	return fmt.Sprintf("*%s*\n\n%s", msg.Subject, msg.Text)
+}
+
+// SMSNotifier delivers notifications as text messages to users who verified
+// their number and haven't opted out of reminders (which replying STOP does).
+type SMSNotifier struct {
+	gateway SMSGateway
+}
+
+func (n *SMSNotifier) Channel() string { return ChannelSMS }
+
+// SendInterval keeps under the one message per second a single long code
+// number can send.
+func (n *SMSNotifier) SendInterval() time.Duration { return time.Second }
+
+// Address is the recipient's phone number, if they can be texted.
+func (n *SMSNotifier) Address(to Recipient) string {
+	if !to.PhoneVerified || to.OptOutReminders {
+		return ""
+	}
+	return to.Phone
+}
+
+// Send texts the message. The number's owner is checked again first, since
+// they may have replied STOP or changed their number after it was queued. A
+// number that replied STOP at the gateway is opted out here too, so it isn't
+// tried again.
+func (n *SMSNotifier) Send(address string, team *models.Team, msg Message) (string, error) {
+	var user models.User
+	if err := database.DB.Where("phone = ? AND phone_verified_at IS NOT NULL", address).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrNoAddress
+		}
+		return "", err
+	}
+	if user.OptOutReminders {
+		return "", ErrNoAddress
+	}
+
+	id, err := n.gateway.SendSMS(address, msg.Text)
+	if errors.Is(err, ErrNoAddress) {
+		setSMSOptOut(address, true)
+	}
+	return id, err
+}
+
+// smsText is the message's own SMS wording, or else its subject and text
+// body.
+func smsText(msg Message) string {
+	if msg.SMS != "" {
+		return msg.SMS
+	}
+	if msg.Subject == "" {
+		return msg.Text
+	}
+	return msg.Subject + "\n\n" + msg.Text
+}
+
+// PushNotifier delivers notifications as Web Push messages to every device
+// the user subscribed.
+type PushNotifier struct {
+	sender *WebPushSender
+}
+
+func (n *PushNotifier) Channel() string { return ChannelPush }
+
+// SendInterval is short: each message goes to the push service of the
+// user's own browser, not one shared provider.
+func (n *PushNotifier) SendInterval() time.Duration { return 100 * time.Millisecond }
+
+// Address is the user's ID, if they have subscribed at least one device.
+func (n *PushNotifier) Address(to Recipient) string {
+	if to.UserID == nil {
+		return ""
+	}
+	var count int64
+	if err := database.DB.Model(&models.PushSubscription{}).Where("user_id = ?", *to.UserID).Count(&count).Error; err != nil || count == 0 {
+		return ""
+	}
+	return to.UserID.String()
+}
+
+// Send pushes the message (already a JSON payload, see pushPayload) to each
+// of the user's devices. Subscriptions the push service says are gone are
+// deleted. It succeeds if any device got the message.
+func (n *PushNotifier) Send(address string, team *models.Team, msg Message) (string, error) {
+	var subscriptions []models.PushSubscription
+	if err := database.DB.Where("user_id = ?", address).Find(&subscriptions).Error; err != nil {
+		return "", fmt.Errorf("push: failed to load subscriptions: %w", err)
+	}
+
+	var lastErr error
+	delivered := 0
+	for _, subscription := range subscriptions {
+		err := n.sender.Send(subscription, []byte(msg.Text))
+		switch {
+		case errors.Is(err, ErrPushSubscriptionGone):
+			database.DB.Delete(&subscription)
+		case err != nil:
+			lastErr = err
+		default:
+			delivered++
+			database.DB.Model(&subscription).Update("last_used_at", time.Now())
+		}
+	}
+
+	switch {
+	case delivered > 0:
+		return "", nil
+	case lastErr != nil:
+		return "", lastErr
+	}
+	return "", ErrNoAddress
+}
+
+// LogNotifier writes every notification to stdout or a file instead of
+// delivering it, so flows can be followed in local development.
+type LogNotifier struct {
+	mu   sync.Mutex
+	out  io.Writer
+	file *os.File // nil when writing to stdout
+}
+
+// NewLogNotifier writes to stdout when dest is "stdout", otherwise appends to
+// the file at dest.
+func NewLogNotifier(dest string) (*LogNotifier, error) {
+	if dest == "stdout" {
+		return &LogNotifier{out: os.Stdout}, nil
+	}
+	f, err := os.OpenFile(dest, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open notification log: %w", err)
+	}
+	return &LogNotifier{out: f, file: f}, nil
+}
+
+// Close closes the log file, if there is one.
+func (n *LogNotifier) Close() error {
+	if n.file == nil {
+		return nil
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.file.Close()
+}
+
+func (n *LogNotifier) Channel() string { return ChannelLog }
+
+func (n *LogNotifier) SendInterval() time.Duration { return 0 }
+
+func (n *LogNotifier) Address(to Recipient) string {
+	return strings.TrimSpace(fmt.Sprintf("%s <%s> %s", to.Name, to.Email, to.Phone))
+}
+
+func (n *LogNotifier) TeamAddress(team models.Team) string { return "team " + team.Name }
+
+func (n *LogNotifier) Send(address string, team *models.Team, msg Message) (string, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	_, err := fmt.Fprintf(n.out, "=== %s [%s]\nTo: %s\nSubject: %s\n\n%s\n\n",
+		time.Now().Format(time.RFC3339), msg.Type, address, msg.Subject, msg.Text)
+	return "", err
+}
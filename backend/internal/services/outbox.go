@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/liam/screaming-toller/backend/internal/database"
+	"github.com/liam/screaming-toller/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// outboxMaxAttempts is how many times a delivery is tried before it is
+	// marked failed.
+	outboxMaxAttempts = 6
+	// outboxRetryBase is the wait after the first failure; it doubles with
+	// each attempt up to outboxRetryMax.
+	outboxRetryBase = 30 * time.Second
+	outboxRetryMax  = time.Hour
+	// outboxLease is how long a claimed message is hidden from other workers.
+	// A worker that dies mid-send leaves it to be retried once this passes.
+	outboxLease = 5 * time.Minute
+	// outboxPollInterval is how often an idle worker checks for new messages.
+	outboxPollInterval = 5 * time.Second
+)
+
+func newOutboxMessage(channel, address string, team *models.Team, msg Message) models.OutboxMessage {
+	switch channel {
+	case ChannelSMS:
+		msg.Text = smsText(msg)
+	case ChannelPush:
+		msg.Text = pushPayload(msg)
+	}
+	outbox := models.OutboxMessage{
+		Type:          msg.Type,
+		Channel:       channel,
+		Address:       address,
+		GameID:        msg.GameID,
+		Subject:       msg.Subject,
+		HTML:          msg.HTML,
+		Text:          msg.Text,
+		Status:        "pending",
+		NextAttemptAt: time.Now(),
+	}
+	if team != nil {
+		outbox.TeamID = &team.ID
+	}
+	return outbox
+}
+
+func enqueue(messages []models.OutboxMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	return database.DB.Create(&messages).Error
+}
+
+// RunOutbox runs one sender per configured channel until ctx is cancelled, so
+// each provider is rate limited on its own and a slow one doesn't hold up the
+// others. It returns once every sender has finished the message it was on.
+func (n *NotificationService) RunOutbox(ctx context.Context) {
+	var senders sync.WaitGroup
+	for _, notifier := range n.notifiers {
+		senders.Add(1)
+		go func(notifier Notifier) {
+			defer senders.Done()
+			n.runOutbox(ctx, notifier)
+		}(notifier)
+	}
+	log.Printf("Outbox: Worker started for %d channels", len(n.notifiers))
+
+	senders.Wait()
+	log.Println("Outbox: Worker stopped")
+}
+
+func (n *NotificationService) runOutbox(ctx context.Context, notifier Notifier) {
+	for {
+		wait := notifier.SendInterval()
+		message, err := claimOutboxMessage(notifier.Channel())
+		if err == nil {
+			deliver(notifier, message)
+		} else {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				log.Printf("Outbox Error: Failed to claim %s message: %v", notifier.Channel(), err)
+			}
+			wait = outboxPollInterval
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// claimOutboxMessage takes the next due message on the channel and pushes
+// its next attempt out by the lease, so no other worker picks it up while it
+// is being sent.
+func claimOutboxMessage(channel string) (models.OutboxMessage, error) {
+	var message models.OutboxMessage
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("channel = ? AND status = ? AND next_attempt_at <= ?", channel, "pending", time.Now()).
+			Order("next_attempt_at asc").
+			First(&message).Error; err != nil {
+			return err
+		}
+		return tx.Model(&message).Update("next_attempt_at", time.Now().Add(outboxLease)).Error
+	})
+	return message, err
+}
+
+// deliver sends one message and records the outcome: sent with the provider's
+// message ID, skipped when the channel can't reach the address, or another
+// attempt scheduled with exponential backoff until outboxMaxAttempts.
+func deliver(notifier Notifier, message models.OutboxMessage) {
+	var team *models.Team
+	if message.TeamID != nil {
+		team = &models.Team{}
+		if err := database.DB.First(team, "id = ?", *message.TeamID).Error; err != nil {
+			team = nil
+		}
+	}
+
+	msg := Message{
+		Type:    message.Type,
+		Subject: message.Subject,
+		HTML:    message.HTML,
+		Text:    message.Text,
+		GameID:  message.GameID,
+	}
+	providerID, err := notifier.Send(message.Address, team, msg)
+
+	attempts := message.Attempts + 1
+	updates := map[string]interface{}{"attempts": attempts}
+	switch {
+	case err == nil:
+		now := time.Now()
+		updates["status"] = "sent"
+		updates["sent_at"] = &now
+		updates["provider_message_id"] = providerID
+		updates["last_error"] = ""
+	case errors.Is(err, ErrNoAddress):
+		updates["status"] = "skipped"
+		updates["last_error"] = err.Error()
+	case errors.Is(err, ErrWhatsAppUnauthorized):
+		// The token won't start working on its own; an admin retries once it's fixed
+		updates["status"] = "failed"
+		updates["last_error"] = err.Error()
+		log.Printf("Outbox Error: %s %s to %s was refused, not retrying: %v", message.Channel, message.Type, message.Address, err)
+	case attempts >= outboxMaxAttempts:
+		updates["status"] = "failed"
+		updates["last_error"] = err.Error()
+		log.Printf("Outbox Error: Giving up on %s %s to %s after %d attempts: %v", message.Channel, message.Type, message.Address, attempts, err)
+	default:
+		updates["next_attempt_at"] = time.Now().Add(retryWait(err, attempts))
+		updates["last_error"] = err.Error()
+		log.Printf("Outbox: Attempt %d of %s %s to %s failed, retrying: %v", attempts, message.Channel, message.Type, message.Address, err)
+	}
+
+	if err := database.DB.Model(&message).Updates(updates).Error; err != nil {
+		log.Printf("Outbox Error: Failed to record delivery of message %s: %v", message.ID, err)
+	}
+}
+
+// retryWait is how long to wait before the next attempt: the backoff, or
+// longer if the provider rate limited us and said for how long.
+func retryWait(err error, attempts int) time.Duration {
+	wait := outboxBackoff(attempts)
+	var apiErr *WhatsAppAPIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > wait {
+		wait = apiErr.RetryAfter
+	}
+	return wait
+}
+
+func outboxBackoff(attempts int) time.Duration {
+	wait := outboxRetryBase
+	for i := 1; i < attempts && wait < outboxRetryMax; i++ {
+		wait *= 2
+	}
+	if wait > outboxRetryMax {
+		wait = outboxRetryMax
+	}
+	return wait
+}
+
+// GameDeliveries lists the notifications queued for a game, newest first,
+// optionally only those with the given status.
+func GameDeliveries(gameID uuid.UUID, status string) ([]models.OutboxMessage, error) {
+	query := database.DB.Where("game_id = ?", gameID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var messages []models.OutboxMessage
+	err := query.Order("created_at desc").Find(&messages).Error
+	return messages, err
+}
+
+// RetryDelivery queues a failed or skipped message for the game again with a
+// fresh set of attempts. It returns gorm.ErrRecordNotFound if there is no such
+// message or it hasn't failed.
+func RetryDelivery(gameID, messageID uuid.UUID) error {
+	result := database.DB.Model(&models.OutboxMessage{}).
+		Where("id = ? AND game_id = ? AND status IN ?", messageID, gameID, []string{"failed", "skipped"}).
+		Updates(map[string]interface{}{
+			"status":          "pending",
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
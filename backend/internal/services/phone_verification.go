@@ -0,0 +1,178 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/liam/screaming-toller/backend/internal/database"
+	"github.com/liam/screaming-toller/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// phoneCodeTTL is how long a texted code can be entered.
+	phoneCodeTTL = 10 * time.Minute
+	// phoneCodeResendWait is the minimum gap between codes sent to a user,
+	// whatever the number.
+	phoneCodeResendWait = time.Minute
+	// phoneCodeDailyLimit is how many codes a user can be sent in a day, so an
+	// account can't be used to text codes to number after number.
+	phoneCodeDailyLimit = 5
+	// phoneCodeMaxAttempts is how many wrong codes are allowed before a new
+	// one must be requested.
+	phoneCodeMaxAttempts = 5
+)
+
+// Errors returned while verifying a phone number.
+var (
+	ErrSMSNotConfigured  = errors.New("text messages are not configured")
+	ErrNoPhone           = errors.New("no phone number to verify")
+	ErrPhoneTaken        = errors.New("the number is verified on another account")
+	ErrPhoneCodeTooSoon  = errors.New("a code was sent less than a minute ago")
+	ErrPhoneCodeLimit    = errors.New("too many codes have been sent today")
+	ErrPhoneCodeInvalid  = errors.New("the code is incorrect")
+	ErrPhoneCodeExpired  = errors.New("the code has expired")
+	ErrPhoneCodeNotFound = errors.New("no code has been sent to this number")
+)
+
+// SendPhoneVerification texts a new code to the user's phone number. It is
+// sent straight through the gateway, since the outbox only texts verified
+// numbers.
+func SendPhoneVerification(notifications *NotificationService, user models.User) error {
+	if user.Phone == "" {
+		return ErrNoPhone
+	}
+	gateway := notifications.smsGateway()
+	if gateway == nil {
+		return ErrSMSNotConfigured
+	}
+	if taken, err := phoneVerifiedElsewhere(database.DB, user.ID, user.Phone); err != nil || taken {
+		if err == nil {
+			err = ErrPhoneTaken
+		}
+		return err
+	}
+
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return err
+	}
+	code := fmt.Sprintf("%06d", n.Int64())
+
+	// The user's one row counts their sends across every number they've tried
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		verification := models.PhoneVerification{UserID: user.ID, SendsSince: now}
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", user.ID).First(&verification).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err == nil {
+			if now.Sub(verification.UpdatedAt) < phoneCodeResendWait {
+				return ErrPhoneCodeTooSoon
+			}
+			if now.Sub(verification.SendsSince) >= 24*time.Hour {
+				verification.Sends = 0
+				verification.SendsSince = now
+			}
+			if verification.Sends >= phoneCodeDailyLimit {
+				return ErrPhoneCodeLimit
+			}
+		}
+
+		verification.Phone = user.Phone
+		verification.CodeHash = phoneCodeHash(user.ID, user.Phone, code)
+		verification.ExpiresAt = now.Add(phoneCodeTTL)
+		verification.Attempts = 0
+		verification.Sends++
+		verification.UpdatedAt = now
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"phone", "code_hash", "expires_at", "attempts", "sends", "sends_since", "updated_at"}),
+		}).Create(&verification).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = gateway.SendSMS(user.Phone, fmt.Sprintf("Your verification code is %s. It expires in %d minutes.", code, int(phoneCodeTTL.Minutes())))
+	return err
+}
+
+// ConfirmPhoneVerification checks the code the user entered and, if it
+// matches the last one texted to their current number, marks the number
+// verified. Other accounts that entered the number without verifying it lose
+// it.
+func ConfirmPhoneVerification(userID uuid.UUID, code string) error {
+	code = strings.TrimSpace(code)
+	wrongCode := false
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.First(&user, "id = ?", userID).Error; err != nil {
+			return err
+		}
+
+		var verification models.PhoneVerification
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND phone = ?", userID, user.Phone).
+			First(&verification).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrPhoneCodeNotFound
+			}
+			return err
+		}
+		if verification.CodeHash == "" || time.Now().After(verification.ExpiresAt) || verification.Attempts >= phoneCodeMaxAttempts {
+			return ErrPhoneCodeExpired
+		}
+
+		expected := phoneCodeHash(userID, user.Phone, code)
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(verification.CodeHash)) != 1 {
+			// The attempt is committed; the error is returned afterwards
+			wrongCode = true
+			return tx.Model(&verification).Update("attempts", gorm.Expr("attempts + 1")).Error
+		}
+
+		if taken, err := phoneVerifiedElsewhere(tx, userID, user.Phone); err != nil || taken {
+			if err == nil {
+				err = ErrPhoneTaken
+			}
+			return err
+		}
+		if err := tx.Model(&models.User{}).
+			Where("phone = ? AND id <> ? AND phone_verified_at IS NULL", user.Phone, userID).
+			Update("phone", "").Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&user).Update("phone_verified_at", time.Now()).Error; err != nil {
+			return err
+		}
+		// The row is kept, with the code spent, so its sends still count
+		return tx.Model(&verification).Update("code_hash", "").Error
+	})
+	if err == nil && wrongCode {
+		return ErrPhoneCodeInvalid
+	}
+	return err
+}
+
+// phoneVerifiedElsewhere reports whether another account has verified the
+// number.
+func phoneVerifiedElsewhere(db *gorm.DB, userID uuid.UUID, phone string) (bool, error) {
+	var count int64
+	err := db.Model(&models.User{}).Where("phone = ? AND id <> ? AND phone_verified_at IS NOT NULL", phone, userID).Count(&count).Error
+	return count > 0, err
+}
+
+func phoneCodeHash(userID uuid.UUID, phone, code string) string {
+	sum := sha256.Sum256([]byte(userID.String() + ":" + phone + ":" + code))
+	return hex.EncodeToString(sum[:])
+}
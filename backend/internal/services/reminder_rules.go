@@ -0,0 +1,113 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/liam/screaming-toller/backend/internal/database"
+	"github.com/liam/screaming-toller/backend/internal/models"
+)
+
+// Reminder rule channels. ReminderChannelPreferred follows each member's
+// notification preferences for attendance reminders; ReminderChannelGroup
+// posts one message listing the players in the team's WhatsApp group.
+const (
+	ReminderChannelPreferred = "preferred"
+	ReminderChannelGroup     = "whatsapp_group"
+)
+
+// MaxReminderOffsetHours is how far ahead of a game a reminder can be set.
+const MaxReminderOffsetHours = 24 * 14
+
+const defaultReminderRuleKey = "default"
+
+// DefaultReminderRule is used by teams that haven't set up their own rules:
+// remind "maybe" players a day before, on their preferred channels and in the
+// team's WhatsApp group.
+func DefaultReminderRule(teamID uuid.UUID) models.ReminderRule {
+	return models.ReminderRule{
+		TeamID:      teamID,
+		OffsetHours: 24,
+		Statuses:    []string{"maybe"},
+		Channels:    []string{ReminderChannelPreferred, ReminderChannelGroup},
+	}
+}
+
+// TeamReminderRules returns the team's reminder rules, longest offset first,
+// or the default rule if it has none.
+func TeamReminderRules(teamID uuid.UUID) ([]models.ReminderRule, error) {
+	var rules []models.ReminderRule
+	if err := database.DB.Where("team_id = ?", teamID).Order("offset_hours desc").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		rules = append(rules, DefaultReminderRule(teamID))
+	}
+	return rules, nil
+}
+
+// ValidateReminderRule checks the offset, statuses and channels, removing
+// duplicates from the lists.
+func ValidateReminderRule(rule *models.ReminderRule) error {
+	if rule.OffsetHours < 1 || rule.OffsetHours > MaxReminderOffsetHours {
+		return fmt.Errorf("offset must be between 1 and %d hours before the game", MaxReminderOffsetHours)
+	}
+
+	validStatuses := map[string]bool{"maybe": true, "going": true, "not_going": true}
+	statuses, err := uniqueValues(rule.Statuses, validStatuses, "status")
+	if err != nil {
+		return err
+	}
+	if len(statuses) == 0 {
+		return fmt.Errorf("at least one status is required")
+	}
+
+	validChannels := map[string]bool{ReminderChannelPreferred: true, ReminderChannelGroup: true}
+	for _, channel := range UserChannels {
+		validChannels[channel] = true
+	}
+	channels, err := uniqueValues(rule.Channels, validChannels, "channel")
+	if err != nil {
+		return err
+	}
+	if len(channels) == 0 {
+		return fmt.Errorf("at least one channel is required")
+	}
+
+	rule.Statuses = statuses
+	rule.Channels = channels
+	return nil
+}
+
+func uniqueValues(values []string, valid map[string]bool, name string) ([]string, error) {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, v := range values {
+		if !valid[v] {
+			return nil, fmt.Errorf("unknown %s %q", name, v)
+		}
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+	sort.Strings(unique)
+	return unique, nil
+}
+
+func reminderRuleKey(rule models.ReminderRule) string {
+	if rule.ID == uuid.Nil {
+		return defaultReminderRuleKey
+	}
+	return rule.ID.String()
+}
+
+func hasChannel(channels []string, channel string) bool {
+	for _, c := range channels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
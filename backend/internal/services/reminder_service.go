@@ -4,21 +4,20 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/liam/screaming-toller/backend/internal/database"
 	"github.com/liam/screaming-toller/backend/internal/models"
-	"github.com/liam/screaming-toller/backend/internal/utils"
+	"gorm.io/gorm/clause"
 )
 
 type ReminderService struct {
-	emailService    *EmailService
-	whatsAppService *WhatsAppService // nil = disabled
-	location        *time.Location
+	notifications *NotificationService
+	location      *time.Location
 }
 
-func NewReminderService(emailService *EmailService, whatsAppService *WhatsAppService) (*ReminderService, error) {
+func NewReminderService(notifications *NotificationService) (*ReminderService, error) {
 	loc, err := time.LoadLocation("America/Vancouver")
 	if err != nil {
 		log.Printf("Warning: Failed to load America/Vancouver timezone, falling back to local: %v", err)
@@ -26,206 +25,202 @@ func NewReminderService(emailService *EmailService, whatsAppService *WhatsAppSer
 	}
 
 	return &ReminderService{
-		emailService:    emailService,
-		whatsAppService: whatsAppService,
-		location:        loc,
+		notifications: notifications,
+		location:      loc,
 	}, nil
 }
 
-// StartScheduler starts the hybrid background scheduler
-func (s *ReminderService) StartScheduler() {
-	// 1. Initial check on startup
-	go s.ProcessUpcomingReminders()
-
-	// 2. Daily sweep ticker (every 24 hours) at a reasonable time (e.g., midnight PDT)
-	go func() {
-		for {
-			// Calculate time until next midnight PDT
-			now := time.Now().In(s.location)
-			nextMidnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, s.location)
-			duration := nextMidnight.Sub(now)
-
-			log.Printf("ReminderService: Next daily sweep in %v", duration)
-			time.Sleep(duration)
-			s.ProcessUpcomingReminders()
-		}
-	}()
-
-	// 3. Periodic check for safety (e.g., every 4 hours) to catch new games or missed windows
-	go func() {
-		const interval = 4 * time.Hour
-		ticker := time.NewTicker(interval)
-		log.Printf("ReminderService: Periodic check started. Next run in %v at %v", interval, time.Now().Add(interval).In(s.location).Format("15:04:05"))
-		
-		for t := range ticker.C {
-			s.ProcessUpcomingReminders()
-			log.Printf("ReminderService: Periodic check finished. Next run in %v at %v", interval, t.Add(interval).In(s.location).Format("15:04:05"))
-		}
-	}()
-}
-
-// ProcessUpcomingReminders finds games in the reminder window and sends emails
-func (s *ReminderService) ProcessUpcomingReminders() {
-	log.Println("ReminderService: Starting process...")
+// runReminderJob sends the game's reminders for the rule with the given key,
+// if it is still the rule due. A rule that was deleted, or superseded by a
+// shorter one that is also due, sends nothing.
+func (s *ReminderService) runReminderJob(gameID uuid.UUID, ruleKey string) error {
+	var game models.Game
+	if err := database.DB.Preload("Venue").First(&game, "id = ?", gameID).Error; err != nil {
+		return err
+	}
+	if game.Status == "cancelled" || game.Status == "postponed" {
+		return nil
+	}
 
-	// Find games occurring in the next 48 hours to be safe
+	// Calculate actual game time in PDT
+	// game.Date is YYYY-MM-DD 00:00:00 UTC
+	// game.Time is "HH:MM"
+	gameTimePDT, err := GameStartTime(game, s.location)
+	if err != nil {
+		log.Printf("ReminderService Warning: Failed to parse time '%s' for game %s", game.Time, game.ID)
+		return nil
+	}
 	now := time.Now().In(s.location)
+	if !now.Before(gameTimePDT) {
+		return nil
+	}
 
-	var games []models.Game
-	// We query by date primarily
-	if err := database.DB.Where("date >= ? AND date <= ?", now.AddDate(0, 0, -1), now.AddDate(0, 0, 2)).Find(&games).Error; err != nil {
-		log.Printf("ReminderService Error: Failed to fetch games: %v", err)
-		return
+	rules, err := TeamReminderRules(game.TeamID)
+	if err != nil {
+		return fmt.Errorf("failed to load reminder rules: %w", err)
+	}
+	if rule, ok := dueReminderRule(rules, gameTimePDT, now); ok && reminderRuleKey(rule) == ruleKey {
+		s.sendRemindersForGame(game, gameTimePDT, rule)
 	}
+	return nil
+}
 
-	for _, game := range games {
-		// Calculate actual game time in PDT
-		// game.Date is YYYY-MM-DD 00:00:00 UTC
-		// game.Time is "HH:MM"
-		var hour, min int
-		n, _ := fmt.Sscanf(game.Time, "%d:%d", &hour, &min)
-		if n < 2 {
-			log.Printf("ReminderService Warning: Failed to parse time '%s' for game %s", game.Time, game.ID)
+// dueReminderRule returns the rule that applies now: the one with the shortest
+// offset whose time has come. An earlier rule that was missed, e.g. because
+// the game was added late, is superseded rather than sent alongside it.
+func dueReminderRule(rules []models.ReminderRule, gameTime, now time.Time) (models.ReminderRule, bool) {
+	var due models.ReminderRule
+	found := false
+	for _, rule := range rules {
+		if now.Before(gameTime.Add(-time.Duration(rule.OffsetHours) * time.Hour)) {
 			continue
 		}
-
-		gameTimePDT := time.Date(game.Date.Year(), game.Date.Month(), game.Date.Day(), hour, min, 0, 0, s.location)
-		
-		// Target reminder window: 26 hours before to 2 hours before game time
-		// This handles the 4-hour ticker frequency much better than a tight window.
-		reminderWindowStart := gameTimePDT.Add(-26 * time.Hour)
-		reminderWindowEnd := gameTimePDT.Add(-2 * time.Hour)
-		log.Printf("ReminderService: Game %s (Time: %v, Window: %v to %v, Now: %v)", game.ID, gameTimePDT, reminderWindowStart, reminderWindowEnd, now)
-
-		if now.After(reminderWindowStart) && now.Before(reminderWindowEnd) {
-			s.sendRemindersForGame(game, gameTimePDT)
-		} else {
-			// Subtle log for debugging if needed, but maybe too noisy for production if there are many games
-			// log.Printf("ReminderService: Game %s skipped (Time: %v, Window: %v to %v, Now: %v)", game.ID, gameTimePDT, reminderWindowStart, reminderWindowEnd, now)
+		if !found || rule.OffsetHours < due.OffsetHours {
+			due = rule
+			found = true
 		}
 	}
+	return due, found
 }
 
-func (s *ReminderService) sendRemindersForGame(game models.Game, gameTime time.Time) {
-	log.Printf("ReminderService: Processing reminders for game: %s vs %s", game.ID, game.OpposingTeam)
+func (s *ReminderService) sendRemindersForGame(game models.Game, gameTime time.Time, rule models.ReminderRule) {
+	log.Printf("ReminderService: Processing %dh reminders for game: %s vs %s", rule.OffsetHours, game.ID, game.OpposingTeam)
+
+	var team models.Team
+	if err := database.DB.First(&team, "id = ?", game.TeamID).Error; err != nil {
+		log.Printf("ReminderService Error: Could not load team for game %s: %v", game.ID, err)
+		return
+	}
+	if RSVPLocked(game, team, time.Now()) {
+		log.Printf("ReminderService: RSVP deadline passed for game %s, skipping reminders", game.ID)
+		return
+	}
+	ruleKey := reminderRuleKey(rule)
+
+	query := database.DB.Preload("TeamMember.User").
+		Where("game_id = ? AND status IN ?", game.ID, rule.Statuses).
+		Where("id NOT IN (?)", database.DB.Model(&models.ReminderSend{}).Select("attendance_id").Where("rule_key = ? AND game_id = ?", ruleKey, game.ID))
+	if ruleKey == defaultReminderRuleKey {
+		// Reminders sent before rules existed count for the default rule
+		query = query.Where("reminder_sent_at IS NULL")
+	}
 
 	var attendances []models.Attendance
-	if err := database.DB.Preload("TeamMember.User").Preload("TeamMember.Team").
-		Where("game_id = ? AND status = ? AND reminder_sent_at IS NULL", game.ID, "maybe").
-		Find(&attendances).Error; err != nil {
+	if err := query.Find(&attendances).Error; err != nil {
 		log.Printf("ReminderService Error: Failed to fetch attendances for game %s: %v", game.ID, err)
 		return
 	}
 
 	if len(attendances) > 0 {
-		log.Printf("ReminderService: Found %d 'maybe' players to remind for game %s", len(attendances), game.ID)
+		log.Printf("ReminderService: Found %d players to remind for game %s", len(attendances), game.ID)
 	} else {
-		log.Printf("ReminderService: No 'maybe' players to remind (or reminders already sent) for game %s", game.ID)
+		log.Printf("ReminderService: No players to remind (or reminders already sent) for game %s", game.ID)
 	}
 
-	for _, att := range attendances {
-		// Throttling: Always sleep before each request attempt (even if the previous one failed)
-		// to stay reliably under the 5 emails/second rate limit.
-		time.Sleep(250 * time.Millisecond)
-
-		user := att.TeamMember.User
-		team := att.TeamMember.Team
-
-		if user.OptOutReminders {
-			log.Printf("ReminderService: Skipping user %s (opted out)", user.Email)
-			continue
-		}
-
-		// Send email
-		gameDateStr := gameTime.Format("Monday, Jan 2")
-		gameTimeStr := gameTime.Format("3:04 PM")
-		
-		err := s.emailService.SendAttendanceReminderEmail(
-			user.Email,
-			team.Name,
-			game.OpposingTeam,
-			gameDateStr,
-			gameTimeStr,
-			game.Location,
-			team.ID.String(),
-		)
-
-		if err != nil {
-			log.Printf("ReminderService Error: Failed to send email to %s: %v", user.Email, err)
-			continue
+	// A rule that only posts to the group has no individual reminders
+	if len(rule.Channels) > 1 || !hasChannel(rule.Channels, ReminderChannelGroup) {
+		for _, att := range attendances {
+			s.sendReminder(game, gameTime, team, rule, att)
 		}
-
-		// Mark as sent
-		now := time.Now()
-		att.ReminderSentAt = &now
-		database.DB.Save(&att)
-		log.Printf("ReminderService: Reminder sent to %s", user.Email)
 	}
 
-	// After all individual emails, send a single WhatsApp group reminder
-	s.sendWhatsAppGroupReminder(game, gameTime)
+	// After all individual reminders, send a single WhatsApp group reminder
+	if hasChannel(rule.Channels, ReminderChannelGroup) {
+		s.sendWhatsAppGroupReminder(game, gameTime, team, rule)
+	}
 }
 
-// sendWhatsAppGroupReminder posts one message to the team's WhatsApp group listing
-// all 'maybe' players (by name) for the given game. Idempotent — skips if already sent.
-func (s *ReminderService) sendWhatsAppGroupReminder(game models.Game, gameTime time.Time) {
-	if s.whatsAppService == nil {
-		return
-	}
+// sendReminder queues one player's reminder on the rule's channels, recording
+// the send against the rule first so it is never queued twice.
+func (s *ReminderService) sendReminder(game models.Game, gameTime time.Time, team models.Team, rule models.ReminderRule, att models.Attendance) {
+	ruleKey := reminderRuleKey(rule)
+	reminderType, _ := LookupNotificationType(TypeAttendanceReminder)
+	user := att.TeamMember.User
 
-	// Already sent for this game?
-	if game.WhatsAppReminderSentAt != nil {
-		log.Printf("ReminderService: WhatsApp reminder already sent for game %s, skipping", game.ID)
+	if user.OptOutReminders {
+		log.Printf("ReminderService: Skipping user %s (opted out)", user.Email)
 		return
 	}
 
-	// Load the team to get its WhatsApp group ID
-	var team models.Team
-	if err := database.DB.First(&team, "id = ?", game.TeamID).Error; err != nil {
-		log.Printf("ReminderService Error: Could not load team for game %s: %v", game.ID, err)
+	recipient := UserRecipient(user, &team)
+	channels, err := ruleChannels(rule, recipient, reminderType)
+	if err != nil {
+		log.Printf("ReminderService Error: Failed to resolve reminder channels for %s: %v", user.Email, err)
 		return
 	}
 
-	if team.WhatsAppGroupID == "" {
-		log.Printf("ReminderService: No WhatsApp group configured for team %s, skipping", team.Name)
+	// Claim the send first so a reminder is never queued twice
+	claim := models.ReminderSend{RuleKey: ruleKey, GameID: game.ID, AttendanceID: att.ID, SentAt: time.Now()}
+	result := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&claim)
+	if result.Error != nil || result.RowsAffected == 0 {
 		return
 	}
 
-	if team.WhapiTokenSourceUserID == nil {
-		log.Printf("ReminderService: No WhatsApp API key source configured for team %s, skipping", team.Name)
-		return
+	// One-click links work until the RSVP deadline, or the game starts if
+	// there's none; without them the email still links to the app
+	linkExpiry := gameTime
+	if deadline := RSVPDeadline(game, team); deadline != nil && deadline.Before(linkExpiry) {
+		linkExpiry = *deadline
+	}
+	rsvpToken, err := IssueRSVPToken(att.ID, linkExpiry)
+	if err != nil {
+		log.Printf("ReminderService Warning: No one-click RSVP links for %s: %v", user.Email, err)
+		rsvpToken = ""
 	}
 
-	// Load the source user to get the encrypted token
-	var sourceUser models.User
-	if err := database.DB.First(&sourceUser, "id = ?", team.WhapiTokenSourceUserID).Error; err != nil {
-		log.Printf("ReminderService Error: Could not load token source user for team %s: %v", team.Name, err)
+	mc := UserMessageContext(user, &team)
+	msg := AttendanceReminderMessage(
+		mc,
+		team.Name,
+		game.OpposingTeam,
+		formatDay(gameTime, mc.Locale),
+		formatClock(gameTime, mc.Locale),
+		gameVenue(game),
+		reminderWhen(gameTime, time.Now().In(gameTime.Location()), mc.Locale),
+		att.Status,
+		formatRSVPDeadline(game, team, mc.Locale),
+		rsvpToken,
+		team.ID.String(),
+	).ForGame(game.ID)
+
+	if err := s.notifications.NotifyVia(recipient, msg, channels); err != nil {
+		log.Printf("ReminderService Error: Failed to queue reminder to %s: %v", user.Email, err)
+		database.DB.Delete(&claim)
 		return
 	}
 
-	if sourceUser.WhapiToken == "" {
-		log.Printf("ReminderService: Token source user %s has no Whapi token set, skipping", sourceUser.Name)
+	// Mark as sent
+	database.DB.Model(&att).Update("reminder_sent_at", time.Now())
+	log.Printf("ReminderService: Reminder queued for %s", user.Email)
+}
+
+// sendWhatsAppGroupReminder posts one message to the team's WhatsApp group listing
+// the players (by name) the rule targets. Idempotent — skips if already sent.
+func (s *ReminderService) sendWhatsAppGroupReminder(game models.Game, gameTime time.Time, team models.Team, rule models.ReminderRule) {
+	ruleKey := reminderRuleKey(rule)
+
+	// Already sent for this game?
+	if ruleKey == defaultReminderRuleKey && game.WhatsAppReminderSentAt != nil {
+		log.Printf("ReminderService: WhatsApp reminder already sent for game %s, skipping", game.ID)
 		return
 	}
 
-	// Decrypt the token
-	token, err := utils.Decrypt(sourceUser.WhapiToken)
-	if err != nil {
-		log.Printf("ReminderService Error: Failed to decrypt Whapi token for team %s: %v", team.Name, err)
+	if team.WhatsAppGroupID == "" {
+		log.Printf("ReminderService: No WhatsApp group configured for team %s, skipping", team.Name)
 		return
 	}
 
-	// Collect names of all still-unconfirmed players
+	// Collect names of the players the rule targets
 	var attendances []models.Attendance
 	if err := database.DB.Preload("TeamMember.User").
-		Where("game_id = ? AND status = ?", game.ID, "maybe").
+		Where("game_id = ? AND status IN ?", game.ID, rule.Statuses).
 		Find(&attendances).Error; err != nil {
 		log.Printf("ReminderService Error: Failed to fetch attendances for WA reminder (game %s): %v", game.ID, err)
 		return
 	}
 
 	if len(attendances) == 0 {
-		log.Printf("ReminderService: No 'maybe' players for WA reminder (game %s)", game.ID)
+		log.Printf("ReminderService: No players for WA reminder (game %s)", game.ID)
 		return
 	}
 
@@ -238,35 +233,103 @@ func (s *ReminderService) sendWhatsAppGroupReminder(game models.Game, gameTime t
 	}
 
 	if len(names) == 0 {
-		log.Printf("ReminderService: All 'maybe' players opted out, skipping WA reminder (game %s)", game.ID)
+		log.Printf("ReminderService: All targeted players opted out, skipping WA reminder (game %s)", game.ID)
 		return
 	}
 
-	gameDateStr := gameTime.Format("Monday, Jan 2")
-	gameTimeStr := gameTime.Format("3:04 PM")
-	attendanceURL := fmt.Sprintf("%s/teams/%s/games", getAppURL(), team.ID.String())
+	// The group post is tracked with a nil attendance ID
+	claim := models.ReminderSend{RuleKey: ruleKey, GameID: game.ID, AttendanceID: uuid.Nil, SentAt: time.Now()}
+	result := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&claim)
+	if result.Error != nil || result.RowsAffected == 0 {
+		log.Printf("ReminderService: WhatsApp reminder already sent for game %s, skipping", game.ID)
+		return
+	}
 
-	message := fmt.Sprintf(
-		"🥎 *Attendance Reminder — %s vs %s*\n📅 %s at %s\n📍 %s\n\nThe following players haven't confirmed yet:\n%s\n\nPlease update your attendance: %s",
-		team.Name,
-		game.OpposingTeam,
-		gameDateStr,
-		gameTimeStr,
-		game.Location,
-		"• "+strings.Join(names, "\n• "),
-		attendanceURL,
-	)
-
-	if err := s.whatsAppService.SendGroupMessage(token, team.WhatsAppGroupID, message); err != nil {
-		log.Printf("ReminderService Error: Failed to send WhatsApp group reminder for game %s: %v", game.ID, err)
+	mc := TeamMessageContext(team)
+	message := renderWhatsApp(mc, "group_reminder", map[string]interface{}{
+		"TeamName":     team.Name,
+		"Opponent":     game.OpposingTeam,
+		"Date":         formatDay(gameTime, mc.Locale),
+		"Time":         formatClock(gameTime, mc.Locale),
+		"Venue":        gameVenue(game),
+		"Statuses":     rule.Statuses,
+		"Unconfirmed":  len(rule.Statuses) == 1 && rule.Statuses[0] == "maybe",
+		"Names":        names,
+		"RSVPDeadline": formatRSVPDeadline(game, team, mc.Locale),
+		"GamesURL":     TeamGamesURL(team.ID),
+	})
+
+	if err := s.notifications.NotifyTeam(team, Message{Type: TypeAttendanceReminder, Text: message}.ForGame(game.ID)); err != nil {
+		log.Printf("ReminderService Error: Failed to queue WhatsApp group reminder for game %s: %v", game.ID, err)
+		database.DB.Delete(&claim)
 		return
 	}
 
 	// Mark game as WA-reminded
-	now := time.Now()
-	game.WhatsAppReminderSentAt = &now
-	database.DB.Save(&game)
-	log.Printf("ReminderService: WhatsApp group reminder sent for game %s to group %s (%d players)", game.ID, team.WhatsAppGroupID, len(names))
+	database.DB.Model(&game).Update("whats_app_reminder_sent_at", time.Now())
+	log.Printf("ReminderService: WhatsApp group reminder queued for game %s to group %s (%d players)", game.ID, team.WhatsAppGroupID, len(names))
+}
+
+// ruleChannels lists the channels a rule sends a member's reminder on, with
+// "preferred" expanded to the member's own choices for attendance reminders.
+func ruleChannels(rule models.ReminderRule, to Recipient, reminderType NotificationType) ([]string, error) {
+	var channels []string
+	for _, channel := range rule.Channels {
+		switch channel {
+		case ReminderChannelGroup:
+		case ReminderChannelPreferred:
+			preferred, err := recipientChannels(to, reminderType)
+			if err != nil {
+				return nil, err
+			}
+			for channel, enabled := range preferred {
+				if enabled && !hasChannel(channels, channel) {
+					channels = append(channels, channel)
+				}
+			}
+		default:
+			if !hasChannel(channels, channel) {
+				channels = append(channels, channel)
+			}
+		}
+	}
+	return channels, nil
+}
+
+// reminderWhen says when the game is relative to now: "today", "tomorrow",
+// "on Saturday" within the week, otherwise "on Sat Jan 2"; in French
+// "aujourd'hui", "demain", "samedi" or "le sam. 2 janv.".
+func reminderWhen(gameTime, now time.Time, locale string) string {
+	gameDay := time.Date(gameTime.Year(), gameTime.Month(), gameTime.Day(), 0, 0, 0, 0, gameTime.Location())
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, gameTime.Location())
+	days := int(gameDay.Sub(today).Hours() / 24)
+	if locale == "fr" {
+		switch {
+		case days <= 0:
+			return "aujourd'hui"
+		case days == 1:
+			return "demain"
+		case days < 7:
+			return formatWeekday(gameTime, locale)
+		default:
+			return "le " + formatShortDay(gameTime, locale)
+		}
+	}
+	switch {
+	case days <= 0:
+		return "today"
+	case days == 1:
+		return "tomorrow"
+	case days < 7:
+		return "on " + formatWeekday(gameTime, locale)
+	default:
+		return "on " + formatShortDay(gameTime, locale)
+	}
+}
+
+// TeamGamesURL links to the team's games page in the app.
+func TeamGamesURL(teamID uuid.UUID) string {
+	return fmt.Sprintf("%s/teams/%s/games", getAppURL(), teamID)
 }
 
 // getAppURL returns the APP_URL env var with a safe fallback.
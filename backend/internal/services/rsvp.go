@@ -0,0 +1,236 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/liam/screaming-toller/backend/internal/database"
+	"github.com/liam/screaming-toller/backend/internal/models"
+	"github.com/liam/screaming-toller/backend/internal/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Errors returned when a one-click RSVP link can't be used.
+var (
+	ErrRSVPTokenInvalid = errors.New("this RSVP link is invalid")
+	ErrRSVPTokenExpired = errors.New("this RSVP link has expired")
+	ErrRSVPTokenUsed    = errors.New("this RSVP link has already been used")
+	ErrRSVPGameClosed   = errors.New("the game is no longer being played as scheduled")
+	ErrRSVPLocked       = errors.New("the RSVP deadline for this game has passed")
+)
+
+// RSVPDeadline returns when RSVPs for the game close: the game's own deadline
+// if set, otherwise the team's default offset before the start time. Returns
+// nil when there is no deadline.
+func RSVPDeadline(game models.Game, team models.Team) *time.Time {
+	if game.RSVPDeadline != nil {
+		return game.RSVPDeadline
+	}
+	if team.RSVPDeadlineHours <= 0 {
+		return nil
+	}
+	start, err := GameStartTime(game, defaultLocation())
+	if err != nil {
+		return nil
+	}
+	deadline := start.Add(-time.Duration(team.RSVPDeadlineHours) * time.Hour)
+	return &deadline
+}
+
+// RSVPLocked reports whether players can no longer change their own RSVP.
+// Admins can always update attendance.
+func RSVPLocked(game models.Game, team models.Team, now time.Time) bool {
+	if team.RSVPDeadlineAction != "lock" {
+		return false
+	}
+	deadline := RSVPDeadline(game, team)
+	return deadline != nil && now.After(*deadline)
+}
+
+// formatRSVPDeadline renders the deadline in the game's timezone, or "" when
+// there is none.
+func formatRSVPDeadline(game models.Game, team models.Team, locale string) string {
+	deadline := RSVPDeadline(game, team)
+	if deadline == nil {
+		return ""
+	}
+	return formatDayTime(deadline.In(GameTimezone(game, defaultLocation())), locale)
+}
+
+// resolveRSVPDeadline applies the team's deadline action to the game once its
+// deadline has passed. With "convert", every "maybe" still outstanding becomes
+// the team's configured status. Each game is resolved once; changing its date,
+// time or deadline re-opens it.
+func resolveRSVPDeadline(gameID uuid.UUID) error {
+	now := time.Now()
+
+	var game models.Game
+	if err := database.DB.Preload("Team").First(&game, "id = ?", gameID).Error; err != nil {
+		return err
+	}
+	if game.RSVPResolvedAt != nil || game.Status == "cancelled" || game.Status == "postponed" || game.Status == "completed" {
+		return nil
+	}
+
+	deadline := RSVPDeadline(game, game.Team)
+	if deadline == nil || now.Before(*deadline) {
+		return nil
+	}
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		// Marking the game resolved is the claim: only one replica gets to
+		result := tx.Model(&models.Game{}).
+			Where("id = ? AND rsvp_resolved_at IS NULL", game.ID).
+			Update("rsvp_resolved_at", now)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		if game.Team.RSVPDeadlineAction != "convert" {
+			log.Printf("RSVPDeadlines: Game %s deadline passed, RSVPs locked", game.ID)
+			return nil
+		}
+
+		status := game.Team.RSVPMaybeStatus
+		if status != "going" {
+			status = "not_going"
+		}
+		result = tx.Model(&models.Attendance{}).
+			Where("game_id = ? AND status = ?", game.ID, "maybe").
+			Update("status", status)
+		if result.Error != nil {
+			return fmt.Errorf("failed to resolve RSVPs: %w", result.Error)
+		}
+		log.Printf("RSVPDeadlines: Game %s deadline passed, %d 'maybe' RSVPs set to %s", game.ID, result.RowsAffected, status)
+		return nil
+	})
+}
+
+// IssueRSVPToken creates a single-use RSVP link token for the attendance
+// record, valid until expiresAt. The token is "<id>.<signature>".
+func IssueRSVPToken(attendanceID uuid.UUID, expiresAt time.Time) (string, error) {
+	token := models.RSVPToken{
+		ID:           uuid.New(),
+		AttendanceID: attendanceID,
+		ExpiresAt:    expiresAt,
+	}
+	signature, err := utils.Sign("rsvp:" + token.ID.String())
+	if err != nil {
+		return "", err
+	}
+	if err := database.DB.Create(&token).Error; err != nil {
+		return "", err
+	}
+	return token.ID.String() + "." + signature, nil
+}
+
+// rsvpTokenID checks the token's signature and returns its ID.
+func rsvpTokenID(rawToken string) (uuid.UUID, error) {
+	id, signature, ok := strings.Cut(rawToken, ".")
+	tokenID, err := uuid.Parse(id)
+	if !ok || err != nil || !utils.VerifySignature("rsvp:"+id, signature) {
+		return uuid.Nil, ErrRSVPTokenInvalid
+	}
+	return tokenID, nil
+}
+
+// LookupRSVPToken returns the attendance record (with its game and team) a
+// link token answers for, without changing anything. It fails as UseRSVPToken
+// would for a used or expired token or a game that is no longer on.
+func LookupRSVPToken(rawToken string) (*models.Attendance, error) {
+	tokenID, err := rsvpTokenID(rawToken)
+	if err != nil {
+		return nil, err
+	}
+
+	var token models.RSVPToken
+	if err := database.DB.First(&token, "id = ?", tokenID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRSVPTokenInvalid
+		}
+		return nil, err
+	}
+	if err := checkRSVPToken(token); err != nil {
+		return nil, err
+	}
+
+	var attendance models.Attendance
+	if err := database.DB.Preload("Game.Team").First(&attendance, "id = ?", token.AttendanceID).Error; err != nil {
+		return nil, err
+	}
+	if rsvpGameClosed(attendance.Game) {
+		return nil, ErrRSVPGameClosed
+	}
+	return &attendance, nil
+}
+
+// UseRSVPToken records status on the token's attendance record and spends
+// the token. The team's RSVP deadline lock applies as it does in the app.
+func UseRSVPToken(rawToken, status string) (*models.Attendance, error) {
+	tokenID, err := rsvpTokenID(rawToken)
+	if err != nil {
+		return nil, err
+	}
+
+	var attendance models.Attendance
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var token models.RSVPToken
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&token, "id = ?", tokenID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrRSVPTokenInvalid
+			}
+			return err
+		}
+		if err := checkRSVPToken(token); err != nil {
+			return err
+		}
+
+		if err := tx.Preload("Game.Team").First(&attendance, "id = ?", token.AttendanceID).Error; err != nil {
+			return err
+		}
+		if rsvpGameClosed(attendance.Game) {
+			return ErrRSVPGameClosed
+		}
+		if attendance.Status != status && RSVPLocked(attendance.Game, attendance.Game.Team, time.Now()) {
+			return ErrRSVPLocked
+		}
+
+		now := time.Now()
+		if err := tx.Model(&attendance).Updates(map[string]interface{}{
+			"status":     status,
+			"updated_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&token).Updates(map[string]interface{}{
+			"used_at":     now,
+			"used_status": status,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &attendance, nil
+}
+
+// checkRSVPToken reports why a token can no longer be used, if it can't.
+func checkRSVPToken(token models.RSVPToken) error {
+	if token.UsedAt != nil {
+		return ErrRSVPTokenUsed
+	}
+	if time.Now().After(token.ExpiresAt) {
+		return ErrRSVPTokenExpired
+	}
+	return nil
+}
+
+// rsvpGameClosed reports whether the game has been cancelled, postponed or
+// played, so there is nothing left to answer.
+func rsvpGameClosed(game models.Game) bool {
+	return game.Status == "cancelled" || game.Status == "postponed" || game.Status == "completed"
+}
@@ -0,0 +1,216 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/liam/screaming-toller/backend/internal/database"
+	"github.com/liam/screaming-toller/backend/internal/models"
+)
+
+// SMS gateways, chosen with SMS_GATEWAY.
+const (
+	SMSGatewayTwilio = "twilio"
+	SMSGatewayFake   = "fake" // Records messages in memory and logs them instead of sending
+)
+
+// twilioUnsubscribed is Twilio's error code for a number that replied STOP.
+const twilioUnsubscribed = 21610
+
+// SMSGateway sends a text message to a phone number (digits only, with
+// country code) and returns the gateway's message ID.
+type SMSGateway interface {
+	SendSMS(phone, body string) (string, error)
+}
+
+// NewSMSGateway returns the gateway in SMS_GATEWAY, or Twilio when it isn't
+// set but TWILIO_ACCOUNT_SID is. It returns nil, nil when SMS isn't
+// configured at all.
+func NewSMSGateway() (SMSGateway, error) {
+	name := os.Getenv("SMS_GATEWAY")
+	if name == "" && os.Getenv("TWILIO_ACCOUNT_SID") != "" {
+		name = SMSGatewayTwilio
+	}
+
+	switch name {
+	case "":
+		return nil, nil
+	case SMSGatewayTwilio:
+		baseURL := os.Getenv("TWILIO_BASE_URL")
+		if baseURL == "" {
+			baseURL = "https://api.twilio.com"
+		}
+		return NewTwilioGateway(baseURL, os.Getenv("TWILIO_ACCOUNT_SID"), os.Getenv("TWILIO_AUTH_TOKEN"), os.Getenv("TWILIO_FROM_NUMBER"))
+	case SMSGatewayFake:
+		return FakeSMS, nil
+	default:
+		return nil, fmt.Errorf("unknown SMS_GATEWAY %q (want %s or %s)", name, SMSGatewayTwilio, SMSGatewayFake)
+	}
+}
+
+// TwilioGateway sends through Twilio's Messages API, or any gateway that
+// speaks it.
+type TwilioGateway struct {
+	baseURL    string
+	accountSID string
+	authToken  string
+	from       string
+	httpClient *http.Client
+}
+
+func NewTwilioGateway(baseURL, accountSID, authToken, from string) (*TwilioGateway, error) {
+	if accountSID == "" || authToken == "" || from == "" {
+		return nil, fmt.Errorf("TWILIO_ACCOUNT_SID, TWILIO_AUTH_TOKEN and TWILIO_FROM_NUMBER must all be set")
+	}
+	return &TwilioGateway{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		accountSID: accountSID,
+		authToken:  authToken,
+		from:       from,
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+	}, nil
+}
+
+// twilioResponse is the part of Twilio's reply we keep, for both sent
+// messages and errors.
+type twilioResponse struct {
+	SID     string `json:"sid"`
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// SendSMS sends the message. Numbers that have replied STOP are reported as
+// unreachable rather than failing.
+func (g *TwilioGateway) SendSMS(phone, body string) (string, error) {
+	form := url.Values{}
+	form.Set("To", "+"+phone)
+	form.Set("From", g.from)
+	form.Set("Body", body)
+
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", g.baseURL, url.PathEscape(g.accountSID))
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("sms: failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(g.accountSID, g.authToken)
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("sms: HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	var reply twilioResponse
+	json.Unmarshal(raw, &reply)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		if reply.Code == twilioUnsubscribed {
+			return "", fmt.Errorf("%w: %s", ErrNoAddress, reply.Message)
+		}
+		return "", fmt.Errorf("sms: API returned status %d: %s", resp.StatusCode, string(raw))
+	}
+	return reply.SID, nil
+}
+
+// FakeSMS is the gateway used when SMS_GATEWAY is "fake".
+var FakeSMS = &RecordingSMSGateway{}
+
+// RecordedSMS is a message a RecordingSMSGateway was asked to send.
+type RecordedSMS struct {
+	ID     string
+	Phone  string
+	Body   string
+	SentAt time.Time
+}
+
+// RecordingSMSGateway keeps every message in memory and logs it instead of
+// sending it. Err, when set, is returned by every send.
+type RecordingSMSGateway struct {
+	mu   sync.Mutex
+	sent []RecordedSMS
+	Err  error
+}
+
+func (g *RecordingSMSGateway) SendSMS(phone, body string) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.Err != nil {
+		return "", g.Err
+	}
+
+	id := fmt.Sprintf("fake-sms-%d", len(g.sent)+1)
+	g.sent = append(g.sent, RecordedSMS{ID: id, Phone: phone, Body: body, SentAt: time.Now()})
+	log.Printf("SMS Fake: %s to %s:\n%s", id, phone, body)
+	return id, nil
+}
+
+// Sent returns a copy of the messages recorded so far.
+func (g *RecordingSMSGateway) Sent() []RecordedSMS {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]RecordedSMS(nil), g.sent...)
+}
+
+// Reset forgets the recorded messages.
+func (g *RecordingSMSGateway) Reset() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.sent = nil
+}
+
+// smsKeywords are the carrier-standard replies that turn text messages off
+// and back on.
+var (
+	smsStopKeywords  = map[string]bool{"STOP": true, "STOPALL": true, "UNSUBSCRIBE": true, "CANCEL": true, "END": true, "QUIT": true, "ARRET": true}
+	smsStartKeywords = map[string]bool{"START": true, "UNSTOP": true, "YES": true}
+	smsHelpKeywords  = map[string]bool{"HELP": true, "INFO": true, "AIDE": true}
+)
+
+// HandleInboundSMS applies a reply sent to our SMS number: STOP opts the
+// account that verified the sender's number out of reminders, which also
+// stops every text; START opts it back in, and HELP explains. It returns the
+// reply to send back, if any.
+func HandleInboundSMS(from, body string) string {
+	phone := NormalizePhone(from)
+	keyword := strings.ToUpper(strings.Trim(strings.TrimSpace(body), ".!"))
+	if phone == "" {
+		return ""
+	}
+
+	switch {
+	case smsStopKeywords[keyword]:
+		if err := setSMSOptOut(phone, true); err != nil {
+			log.Printf("SMS Inbound Error: Failed to opt out %s: %v", phone, err)
+		}
+		// The gateway confirms STOP itself
+		return ""
+	case smsStartKeywords[keyword]:
+		if err := setSMSOptOut(phone, false); err != nil {
+			log.Printf("SMS Inbound Error: Failed to opt in %s: %v", phone, err)
+			return ""
+		}
+		return "You'll get game reminders and lineups by text again. Reply STOP to opt out."
+	case smsHelpKeywords[keyword]:
+		return "Game reminders and lineups from your team. Reply STOP to opt out, START to opt back in. Manage notifications at " + getAppURL()
+	}
+	return ""
+}
+
+// setSMSOptOut sets the reminder opt-out of the user who verified the phone
+// number. Only one account can verify a number, and accounts that merely
+// entered it are never texted, so they are left alone.
+func setSMSOptOut(phone string, optOut bool) error {
+	return database.DB.Model(&models.User{}).
+		Where("phone = ? AND phone_verified_at IS NOT NULL", phone).
+		Update("opt_out_reminders", optOut).Error
+}
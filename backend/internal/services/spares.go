@@ -0,0 +1,314 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/liam/screaming-toller/backend/internal/database"
+	"github.com/liam/screaming-toller/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrSpareInviteClosed is returned when a spare answers an invite that has
+// expired, was already answered, or whose request is no longer open.
+var ErrSpareInviteClosed = errors.New("this spare request is no longer open")
+
+// DefaultSpareResponseHours is how long each spare has to answer when the
+// request doesn't say.
+const DefaultSpareResponseHours = 6
+
+// InviteNextSpare asks the next spare in priority order for an open request.
+// Team spares and spares shared by the team's leagues are considered; anyone
+// already asked for this request, or already playing in the game, is skipped.
+// When nobody is left the request is marked exhausted and the admin who made
+// it is told. notifications may be nil, in which case invites are recorded but
+// not sent.
+func InviteNextSpare(notifications *NotificationService, requestID uuid.UUID) error {
+	var request models.SpareRequest
+	var game models.Game
+	var invite *models.SpareInvite
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&request, "id = ?", requestID).Error; err != nil {
+			return err
+		}
+		if request.Status != "open" {
+			return nil
+		}
+
+		// Only one outstanding invite per request
+		var pending int64
+		if err := tx.Model(&models.SpareInvite{}).
+			Where("spare_request_id = ? AND status = ?", request.ID, "pending").
+			Count(&pending).Error; err != nil {
+			return err
+		}
+		if pending > 0 {
+			return nil
+		}
+
+		if err := tx.Preload("Venue").First(&game, "id = ?", request.GameID).Error; err != nil {
+			return err
+		}
+		start, startErr := GameStartTime(game, defaultLocation())
+		if startErr == nil && time.Now().After(start) {
+			// Too late to find anyone
+			return tx.Model(&request).Update("status", "cancelled").Error
+		}
+
+		var spare models.Spare
+		err := tx.
+			Where("is_active = ? AND gender = ?", true, request.Gender).
+			Where("team_id = ? OR league_id IN (?)", request.TeamID,
+				tx.Model(&models.LeagueTeam{}).Select("league_id").Where("team_id = ? AND status = ?", request.TeamID, "active")).
+			Where("id NOT IN (?)", tx.Model(&models.SpareInvite{}).Select("spare_id").Where("spare_request_id = ?", request.ID)).
+			Where("id NOT IN (?)", tx.Model(&models.SpareInvite{}).Select("spare_invites.spare_id").
+				Joins("JOIN spare_requests ON spare_requests.id = spare_invites.spare_request_id").
+				Where("spare_requests.game_id = ? AND spare_invites.status = ?", request.GameID, "accepted")).
+			Order("priority asc, name asc").
+			First(&spare).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			request.Status = "exhausted"
+			return tx.Model(&request).Update("status", request.Status).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		// Give the spare until the deadline, but never past the start of the game
+		hours := request.ResponseHours
+		if hours <= 0 {
+			hours = DefaultSpareResponseHours
+		}
+		expiresAt := time.Now().Add(time.Duration(hours) * time.Hour)
+		if startErr == nil && start.Before(expiresAt) {
+			expiresAt = start
+		}
+
+		invite = &models.SpareInvite{
+			SpareRequestID: request.ID,
+			SpareID:        spare.ID,
+			Token:          uuid.New().String(),
+			Status:         "pending",
+			ExpiresAt:      expiresAt,
+			Spare:          spare,
+		}
+		return tx.Create(invite).Error
+	})
+	if err != nil {
+		return err
+	}
+	if invite != nil {
+		if err := scheduleSpareInviteExpiry(*invite); err != nil {
+			log.Printf("Spares Error: Failed to schedule expiry of invite %s: %v", invite.ID, err)
+		}
+	}
+
+	if request.Status == "exhausted" {
+		notifySpareRequester(notifications, request, game, "")
+		return nil
+	}
+	if invite == nil || notifications == nil {
+		return nil
+	}
+
+	var team models.Team
+	if err := database.DB.First(&team, "id = ?", request.TeamID).Error; err != nil {
+		return err
+	}
+	// Spares hear from the team in its language
+	mc := TeamMessageContext(team)
+	gameDate, gameTime := formatGameWhen(game, mc.Locale)
+	expires := formatDayTime(invite.ExpiresAt.In(GameTimezone(game, defaultLocation())), mc.Locale)
+	spare := Recipient{
+		UserID: invite.Spare.UserID,
+		Name:   invite.Spare.Name,
+		Email:  invite.Spare.Email,
+		Phone:  NormalizePhone(invite.Spare.Phone),
+		Team:   &team,
+	}
+	return notifications.Notify(spare, SpareRequestMessage(mc, invite.Spare.Name, team.Name, game.OpposingTeam,
+		gameDate, gameTime, gameVenue(game), invite.Token, expires).ForGame(game.ID))
+}
+
+// RespondToSpareInvite records a spare's answer. Accepting fills the request
+// and adds the spare to the game's attendance as going, creating a user and a
+// spare team membership for them if needed. Declining moves on to the next
+// spare.
+func RespondToSpareInvite(notifications *NotificationService, token string, accept bool) (*models.SpareInvite, error) {
+	var invite models.SpareInvite
+	var request models.SpareRequest
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Spare").
+			Where("token = ?", token).First(&invite).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&request, "id = ?", invite.SpareRequestID).Error; err != nil {
+			return err
+		}
+		if invite.Status != "pending" || request.Status != "open" || time.Now().After(invite.ExpiresAt) {
+			return ErrSpareInviteClosed
+		}
+
+		now := time.Now()
+		invite.RespondedAt = &now
+		if !accept {
+			invite.Status = "declined"
+			return tx.Save(&invite).Error
+		}
+
+		invite.Status = "accepted"
+		if err := tx.Save(&invite).Error; err != nil {
+			return err
+		}
+		request.Status = "filled"
+		request.FilledBySpareID = &invite.SpareID
+		if err := tx.Save(&request).Error; err != nil {
+			return err
+		}
+		return addSpareToGame(tx, &invite.Spare, request)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !accept {
+		if err := InviteNextSpare(notifications, request.ID); err != nil {
+			log.Printf("Spares Error: Failed to invite the next spare for request %s: %v", request.ID, err)
+		}
+		return &invite, nil
+	}
+
+	var game models.Game
+	if err := database.DB.First(&game, "id = ?", request.GameID).Error; err == nil {
+		notifySpareRequester(notifications, request, game, invite.Spare.Name)
+	}
+	return &invite, nil
+}
+
+// ExpireSpareInvites closes invites nobody answered in time and asks the next
+// spare for each request that is still open.
+func ExpireSpareInvites(notifications *NotificationService) {
+	var invites []models.SpareInvite
+	if err := database.DB.Where("status = ? AND expires_at < ?", "pending", time.Now()).Find(&invites).Error; err != nil {
+		log.Printf("Spares Error: Failed to fetch expired invites: %v", err)
+		return
+	}
+
+	for _, invite := range invites {
+		result := database.DB.Model(&models.SpareInvite{}).
+			Where("id = ? AND status = ?", invite.ID, "pending").
+			Update("status", "expired")
+		if result.Error != nil {
+			log.Printf("Spares Error: Failed to expire invite %s: %v", invite.ID, result.Error)
+			continue
+		}
+		if result.RowsAffected == 0 {
+			continue // answered in the meantime
+		}
+		if err := InviteNextSpare(notifications, invite.SpareRequestID); err != nil {
+			log.Printf("Spares Error: Failed to invite the next spare for request %s: %v", invite.SpareRequestID, err)
+		}
+	}
+}
+
+// addSpareToGame links the spare to a user account (matched by email so a
+// later Auth0 sign-up picks it up), gives them a spare membership on the
+// team, and marks them going.
+func addSpareToGame(tx *gorm.DB, spare *models.Spare, request models.SpareRequest) error {
+	var user models.User
+	switch {
+	case spare.UserID != nil:
+		if err := tx.First(&user, "id = ?", *spare.UserID).Error; err != nil {
+			return err
+		}
+	default:
+		err := tx.Where("LOWER(email) = ?", strings.ToLower(spare.Email)).First(&user).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			user = models.User{
+				Auth0ID: "spare|" + uuid.New().String(), // replaced when they sign in
+				Name:    spare.Name,
+				Email:   spare.Email,
+			}
+			err = tx.Create(&user).Error
+		}
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(spare).Update("user_id", user.ID).Error; err != nil {
+			return err
+		}
+	}
+
+	var member models.TeamMember
+	err := tx.Where("team_id = ? AND user_id = ?", request.TeamID, user.ID).First(&member).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		member = models.TeamMember{
+			TeamID:  request.TeamID,
+			UserID:  user.ID,
+			Gender:  spare.Gender,
+			Role:    "player",
+			IsSpare: true,
+		}
+		if err := tx.Create(&member).Error; err != nil {
+			return err
+		}
+		// IsActive defaults to true on create
+		if err := tx.Model(&member).Update("is_active", false).Error; err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	case !member.IsActive:
+		// A former member filling in keeps their history but joins as a spare
+		if err := tx.Model(&member).Updates(map[string]interface{}{"is_spare": true, "gender": spare.Gender}).Error; err != nil {
+			return err
+		}
+	}
+
+	var attendance models.Attendance
+	err = tx.Where("team_member_id = ? AND game_id = ?", member.ID, request.GameID).First(&attendance).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return tx.Create(&models.Attendance{
+			TeamMemberID: member.ID,
+			GameID:       request.GameID,
+			Status:       "going",
+		}).Error
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Model(&attendance).Update("status", "going").Error
+}
+
+// notifySpareRequester tells the admin who asked for a spare that acceptedBy
+// said yes, or, when it is empty, that every spare has been asked.
+func notifySpareRequester(notifications *NotificationService, request models.SpareRequest, game models.Game, acceptedBy string) {
+	if notifications == nil {
+		return
+	}
+
+	var requester models.User
+	if err := database.DB.First(&requester, "id = ?", request.RequestedBy).Error; err != nil {
+		log.Printf("Spares Error: Could not load requester for spare request %s: %v", request.ID, err)
+		return
+	}
+	var team models.Team
+	if err := database.DB.First(&team, "id = ?", request.TeamID).Error; err != nil {
+		log.Printf("Spares Error: Could not load team for spare request %s: %v", request.ID, err)
+		return
+	}
+
+	mc := UserMessageContext(requester, &team)
+	gameDate, _ := formatGameWhen(game, mc.Locale)
+	msg := SpareRequestUpdateMessage(mc, team.Name, game.OpposingTeam, gameDate, acceptedBy, request.Gender, team.ID.String())
+	if err := notifications.Notify(UserRecipient(requester, &team), msg.ForGame(game.ID)); err != nil {
+		log.Printf("Spares Error: Failed to notify %s about spare request %s: %v", requester.Email, request.ID, err)
+	}
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/liam/screaming-toller/backend/internal/database"
+	"github.com/liam/screaming-toller/backend/internal/models"
+	"github.com/liam/screaming-toller/backend/internal/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// IntegrationWhatsApp is the TeamIntegration service for the team's Whapi
+// token.
+const IntegrationWhatsApp = "whatsapp"
+
+// Where a team's WhatsApp token comes from.
+const (
+	TokenSourceTeam   = "team"   // The team's own token, set by its admins
+	TokenSourceMember = "member" // Borrowed from an admin's profile (Team.WhapiTokenSourceUserID)
+)
+
+// maxIntegrationError caps the stored error, which may hold a provider's
+// whole reply.
+const maxIntegrationError = 500
+
+// TeamIntegration returns the team's row for the service, or an empty one
+// (with no ID) when nothing has been recorded yet.
+func TeamIntegration(teamID uuid.UUID, service string) (models.TeamIntegration, error) {
+	integration := models.TeamIntegration{TeamID: teamID, Service: service}
+	err := database.DB.Where("team_id = ? AND service = ?", teamID, service).First(&integration).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return integration, nil
+	}
+	return integration, err
+}
+
+// SetTeamWhapiToken encrypts and stores the team's own Whapi token, or
+// removes it when token is empty so the team falls back to its token source
+// user. The health so far is kept.
+func SetTeamWhapiToken(teamID, userID uuid.UUID, token string) error {
+	encrypted := ""
+	if token != "" {
+		var err error
+		if encrypted, err = utils.Encrypt(token); err != nil {
+			return fmt.Errorf("failed to encrypt token: %w", err)
+		}
+	}
+
+	integration := models.TeamIntegration{TeamID: teamID, Service: IntegrationWhatsApp, Token: encrypted, UpdatedByUserID: &userID}
+	return database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "team_id"}, {Name: "service"}},
+		DoUpdates: clause.AssignmentColumns([]string{"token", "updated_by_user_id", "updated_at"}),
+	}).Create(&integration).Error
+}
+
+// WhapiTokenSource says where teamWhapiToken would take the team's token
+// from: TokenSourceTeam, TokenSourceMember, or "" when it has none.
+func WhapiTokenSource(team models.Team) string {
+	if integration, err := TeamIntegration(team.ID, IntegrationWhatsApp); err == nil && integration.Token != "" {
+		return TokenSourceTeam
+	}
+	if team.WhapiTokenSourceUserID != nil {
+		return TokenSourceMember
+	}
+	return ""
+}
+
+// teamWhapiToken returns the decrypted Whapi token used to post to the team's
+// WhatsApp group: the team's own token, or else the one borrowed from the
+// team's configured source user.
+func teamWhapiToken(team models.Team) (string, error) {
+	integration, err := TeamIntegration(team.ID, IntegrationWhatsApp)
+	if err != nil {
+		return "", fmt.Errorf("could not load team WhatsApp settings: %w", err)
+	}
+	if integration.Token != "" {
+		token, err := utils.Decrypt(integration.Token)
+		if err != nil {
+			return "", fmt.Errorf("failed to decrypt team Whapi token: %w", err)
+		}
+		return token, nil
+	}
+
+	if team.WhapiTokenSourceUserID == nil {
+		return "", fmt.Errorf("no WhatsApp token configured for the team")
+	}
+
+	var sourceUser models.User
+	if err := database.DB.First(&sourceUser, "id = ?", team.WhapiTokenSourceUserID).Error; err != nil {
+		return "", fmt.Errorf("could not load token source user: %w", err)
+	}
+
+	if sourceUser.WhapiToken == "" {
+		return "", fmt.Errorf("token source user %s has no Whapi token set", sourceUser.Name)
+	}
+
+	token, err := utils.Decrypt(sourceUser.WhapiToken)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt Whapi token: %w", err)
+	}
+	return token, nil
+}
+
+// TestTeamWhatsApp checks the team's token with the WhatsApp provider and
+// records the result as the team's WhatsApp health.
+func TestTeamWhatsApp(team models.Team) error {
+	token, err := teamWhapiToken(team)
+	if err == nil {
+		var service *WhatsAppService
+		if service, err = NewWhatsAppService(); err == nil {
+			err = service.CheckToken(token)
+		}
+	}
+	if !errors.Is(err, errors.ErrUnsupported) {
+		recordWhatsAppResult(team.ID, err)
+	}
+	return err
+}
+
+// recordWhatsAppResult notes a send (or test) with the team's token as its
+// last success or failure, so admins can see when group messages stopped.
+func recordWhatsAppResult(teamID uuid.UUID, sendErr error) {
+	now := time.Now()
+	integration := models.TeamIntegration{TeamID: teamID, Service: IntegrationWhatsApp}
+	columns := []string{"updated_at"}
+	if sendErr == nil {
+		integration.LastSuccessAt = &now
+		columns = append(columns, "last_success_at")
+	} else {
+		integration.LastFailureAt = &now
+		integration.LastError = sendErr.Error()
+		if runes := []rune(integration.LastError); len(runes) > maxIntegrationError {
+			integration.LastError = string(runes[:maxIntegrationError])
+		}
+		columns = append(columns, "last_failure_at", "last_error")
+	}
+
+	err := database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "team_id"}, {Name: "service"}},
+		DoUpdates: clause.AssignmentColumns(columns),
+	}).Create(&integration).Error
+	if err != nil {
+		log.Printf("Warning: Failed to record WhatsApp health for team %s: %v", teamID, err)
+	}
+}
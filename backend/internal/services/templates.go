@@ -0,0 +1,257 @@
+package services
+
+import (
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/google/uuid"
+	"github.com/liam/screaming-toller/backend/internal/database"
+	"github.com/liam/screaming-toller/backend/internal/models"
+)
+
+// Every outbound message is worded by a template in templates/<locale>/,
+// one file per message. A file defines the parts the message has: the email
+// "subject", "text" and "html" bodies, the shorter "sms" text message and
+// "push" notification, and the "whatsapp" text of posts to a team's group. partials.tmpl holds the pieces they share, such as the venue
+// details. Parts are parsed with text/template, except "html" which is parsed
+// with html/template so the values it is filled with are escaped.
+//
+//go:embed templates
+var templateFiles embed.FS
+
+// MessageTemplateParts are the parts a message template can define.
+var MessageTemplateParts = []string{"subject", "text", "html", "whatsapp", "sms", "push"}
+
+var templateFuncs = map[string]interface{}{
+	"join": strings.Join,
+}
+
+// messageTemplate is one message's parts in one locale.
+type messageTemplate struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// messageTemplates holds the built-in templates by locale, then message
+// name. They are only ever cloned, never executed, so that a team's override
+// can be parsed into a copy.
+var messageTemplates = loadMessageTemplates()
+
+func loadMessageTemplates() map[string]map[string]messageTemplate {
+	templates := make(map[string]map[string]messageTemplate)
+	for _, locale := range Locales {
+		dir := "templates/" + locale
+		files, err := fs.Glob(templateFiles, dir+"/*.tmpl")
+		if err != nil {
+			panic(err)
+		}
+
+		templates[locale] = make(map[string]messageTemplate)
+		for _, file := range files {
+			name := strings.TrimSuffix(path.Base(file), ".tmpl")
+			if name == "partials" {
+				continue
+			}
+			partials := dir + "/partials.tmpl"
+			templates[locale][name] = messageTemplate{
+				text: texttemplate.Must(texttemplate.New(name).Funcs(templateFuncs).Option("missingkey=error").ParseFS(templateFiles, partials, file)),
+				html: htmltemplate.Must(htmltemplate.New(name).Funcs(templateFuncs).Option("missingkey=error").ParseFS(templateFiles, partials, file)),
+			}
+		}
+	}
+	return templates
+}
+
+// defines reports whether the message has the part.
+func (t messageTemplate) defines(part string) bool {
+	if part == "html" {
+		return t.html.Lookup(part) != nil
+	}
+	return t.text.Lookup(part) != nil
+}
+
+// withOverride returns a copy of the template with the part's wording
+// replaced by body. An empty body keeps the built-in wording.
+func (t messageTemplate) withOverride(part, body string) (messageTemplate, error) {
+	text, err := t.text.Clone()
+	if err != nil {
+		return t, err
+	}
+	html, err := t.html.Clone()
+	if err != nil {
+		return t, err
+	}
+	if body != "" {
+		if part == "html" {
+			_, err = html.New(part).Parse(body)
+		} else {
+			_, err = text.New(part).Parse(body)
+		}
+	}
+	return messageTemplate{text: text, html: html}, err
+}
+
+// execute renders one part, trimmed of surrounding whitespace. Parts the
+// message doesn't define render empty.
+func (t messageTemplate) execute(part string, data map[string]interface{}) (string, error) {
+	var b strings.Builder
+	var err error
+	switch {
+	case !t.defines(part):
+	case part == "html":
+		err = t.html.ExecuteTemplate(&b, part, data)
+	default:
+		err = t.text.ExecuteTemplate(&b, part, data)
+	}
+	return strings.TrimSpace(b.String()), err
+}
+
+// renderMessage words the named message for an email or direct chat
+// message: its subject, its text and HTML bodies, and its SMS and push
+// texts. A push notification opens the message's GamesURL, if it has one.
+func renderMessage(mc MessageContext, msgType, name string, data map[string]interface{}) Message {
+	parts := renderParts(mc, name, data, "subject", "text", "html", "sms", "push")
+	url, _ := data["GamesURL"].(string)
+	return Message{
+		Type:    msgType,
+		Subject: parts["subject"],
+		HTML:    parts["html"],
+		Text:    parts["text"],
+		SMS:     parts["sms"],
+		Push:    parts["push"],
+		URL:     url,
+	}
+}
+
+// renderWhatsApp words the named message for a post to a team's WhatsApp
+// group.
+func renderWhatsApp(mc MessageContext, name string, data map[string]interface{}) string {
+	return renderParts(mc, name, data, "whatsapp")["whatsapp"]
+}
+
+// renderParts renders the message's parts in the context's locale, falling
+// back to English for messages that haven't been translated. The team's
+// overrides replace the built-in wording; one that fails to render is logged
+// and the built-in wording used instead. data gets AppURL added.
+func renderParts(mc MessageContext, name string, data map[string]interface{}, parts ...string) map[string]string {
+	locale := mc.Locale
+	tmpl, ok := messageTemplates[locale][name]
+	if !ok {
+		locale = DefaultLocale
+		tmpl, ok = messageTemplates[locale][name]
+	}
+	if !ok {
+		log.Printf("Templates Error: No template for message %q", name)
+		return nil
+	}
+	data["AppURL"] = getAppURL()
+
+	overrides := teamTemplateOverrides(mc.TeamID, name, locale)
+	rendered := make(map[string]string, len(parts))
+	for _, part := range parts {
+		if body, ok := overrides[part]; ok {
+			out, err := renderPart(tmpl, part, body, data)
+			if err == nil {
+				rendered[part] = out
+				continue
+			}
+			log.Printf("Templates Warning: Team %s's %s %s (%s) failed, using the default: %v", *mc.TeamID, name, part, locale, err)
+		}
+
+		out, err := renderPart(tmpl, part, "", data)
+		if err != nil {
+			log.Printf("Templates Error: Failed to render %s %s (%s): %v", name, part, locale, err)
+		}
+		rendered[part] = out
+	}
+	return rendered
+}
+
+func renderPart(tmpl messageTemplate, part, override string, data map[string]interface{}) (string, error) {
+	tmpl, err := tmpl.withOverride(part, override)
+	if err != nil {
+		return "", err
+	}
+	return tmpl.execute(part, data)
+}
+
+// teamTemplateOverrides returns the team's wording for the message's parts
+// in the locale, by part.
+func teamTemplateOverrides(teamID *uuid.UUID, name, locale string) map[string]string {
+	if teamID == nil {
+		return nil
+	}
+
+	var overrides []models.MessageTemplate
+	if err := database.DB.Where("team_id = ? AND name = ? AND locale = ?", *teamID, name, locale).Find(&overrides).Error; err != nil {
+		log.Printf("Templates Error: Failed to load overrides for team %s: %v", *teamID, err)
+		return nil
+	}
+
+	bodies := make(map[string]string, len(overrides))
+	for _, override := range overrides {
+		bodies[override.Part] = override.Body
+	}
+	return bodies
+}
+
+// ValidateMessageTemplate checks that an override replaces a part of a
+// built-in message, in a supported locale, and that it parses.
+func ValidateMessageTemplate(override models.MessageTemplate) error {
+	if !SupportedLocale(override.Locale) {
+		return fmt.Errorf("locale must be one of: %s", strings.Join(Locales, ", "))
+	}
+	tmpl, ok := messageTemplates[override.Locale][override.Name]
+	if !ok {
+		return fmt.Errorf("unknown message %q", override.Name)
+	}
+	if !tmpl.defines(override.Part) {
+		return fmt.Errorf("message %q has no %q part", override.Name, override.Part)
+	}
+	if strings.TrimSpace(override.Body) == "" {
+		return fmt.Errorf("body is required")
+	}
+	if _, err := tmpl.withOverride(override.Part, override.Body); err != nil {
+		return fmt.Errorf("invalid template: %w", err)
+	}
+	return nil
+}
+
+// DefaultMessageTemplates lists the built-in wording of every message part in
+// the locale, as a starting point for a team's overrides.
+func DefaultMessageTemplates(locale string) []models.MessageTemplate {
+	var defaults []models.MessageTemplate
+	for name, tmpl := range messageTemplates[locale] {
+		for _, part := range MessageTemplateParts {
+			var body string
+			switch {
+			case !tmpl.defines(part):
+				continue
+			case part == "html":
+				body = tmpl.html.Lookup(part).Tree.Root.String()
+			default:
+				body = tmpl.text.Lookup(part).Tree.Root.String()
+			}
+			defaults = append(defaults, models.MessageTemplate{
+				Name:   name,
+				Locale: locale,
+				Part:   part,
+				Body:   strings.TrimSpace(body),
+			})
+		}
+	}
+	sort.Slice(defaults, func(i, j int) bool {
+		if defaults[i].Name != defaults[j].Name {
+			return defaults[i].Name < defaults[j].Name
+		}
+		return defaults[i].Part < defaults[j].Part
+	})
+	return defaults
+}
@@ -0,0 +1,266 @@
+package services
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/liam/screaming-toller/backend/internal/models"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	// pushRecordSize is the aes128gcm record size. Payloads are sent as one
+	// record, so they must fit in it with the padding delimiter and tag.
+	pushRecordSize = 4096
+	pushMaxPayload = pushRecordSize - 17
+	// pushTTL is how long the push service keeps a message for a device
+	// that is offline.
+	pushTTL = 24 * time.Hour
+	// pushBodyLimit caps the notification text shown on the device.
+	pushBodyLimit = 240
+)
+
+// ErrPushSubscriptionGone is returned when the push service says the
+// subscription has expired or been unsubscribed (404 or 410).
+var ErrPushSubscriptionGone = errors.New("push subscription is gone")
+
+// WebPushSender sends encrypted Web Push messages (RFC 8291) signed with the
+// server's VAPID key (RFC 8292).
+type WebPushSender struct {
+	publicKey  string // Base64url uncompressed P-256 point, as browsers expect
+	privateKey *ecdsa.PrivateKey
+	subject    string
+	httpClient *http.Client
+}
+
+// NewWebPushSender loads the VAPID key pair from VAPID_PUBLIC_KEY and
+// VAPID_PRIVATE_KEY (base64url, as printed by cmd/vapid-keys) and the contact
+// in VAPID_SUBJECT. It returns nil, nil when push isn't configured.
+func NewWebPushSender() (*WebPushSender, error) {
+	publicKey := os.Getenv("VAPID_PUBLIC_KEY")
+	privateKey := os.Getenv("VAPID_PRIVATE_KEY")
+	if publicKey == "" && privateKey == "" {
+		return nil, nil
+	}
+
+	raw, err := decodeBase64URL(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID_PRIVATE_KEY: %w", err)
+	}
+	key, err := ecdh.P256().NewPrivateKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID_PRIVATE_KEY: %w", err)
+	}
+	if public, err := decodeBase64URL(publicKey); err != nil || !bytes.Equal(public, key.PublicKey().Bytes()) {
+		return nil, fmt.Errorf("VAPID_PUBLIC_KEY doesn't match VAPID_PRIVATE_KEY")
+	}
+
+	// JWT signing needs the key as ECDSA; PKCS #8 converts between the two
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID_PRIVATE_KEY: %w", err)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID_PRIVATE_KEY: %w", err)
+	}
+
+	subject := os.Getenv("VAPID_SUBJECT")
+	if subject == "" {
+		subject = getAppURL()
+	}
+
+	return &WebPushSender{
+		publicKey:  publicKey,
+		privateKey: parsed.(*ecdsa.PrivateKey),
+		subject:    subject,
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+	}, nil
+}
+
+// PublicKey is the applicationServerKey browsers subscribe with.
+func (s *WebPushSender) PublicKey() string { return s.publicKey }
+
+// Send delivers an encrypted payload to one subscription.
+func (s *WebPushSender) Send(subscription models.PushSubscription, payload []byte) error {
+	if len(payload) > pushMaxPayload {
+		return fmt.Errorf("push: payload of %d bytes is too large", len(payload))
+	}
+	body, err := encryptPushPayload(subscription, payload)
+	if err != nil {
+		return err
+	}
+	token, err := s.vapidToken(subscription.Endpoint)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, subscription.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("push: failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", fmt.Sprint(int(pushTTL.Seconds())))
+	req.Header.Set("Authorization", fmt.Sprintf("vapid t=%s, k=%s", token, s.publicKey))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("push: HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrPushSubscriptionGone
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		reply, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("push: service returned status %d: %s", resp.StatusCode, string(reply))
+	}
+	return nil
+}
+
+// vapidToken signs the JWT that identifies us to the endpoint's push service.
+func (s *WebPushSender) vapidToken(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("push: invalid endpoint %q", endpoint)
+	}
+	claims := jwt.MapClaims{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": time.Now().Add(12 * time.Hour).Unix(),
+		"sub": s.subject,
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodES256, claims).SignedString(s.privateKey)
+}
+
+// encryptPushPayload encrypts the payload for the subscription's browser with
+// the aes128gcm content encoding (RFC 8188, RFC 8291), as a single record.
+func encryptPushPayload(subscription models.PushSubscription, payload []byte) ([]byte, error) {
+	uaPublic, err := decodeBase64URL(subscription.P256dh)
+	if err != nil {
+		return nil, fmt.Errorf("push: invalid p256dh key: %w", err)
+	}
+	authSecret, err := decodeBase64URL(subscription.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("push: invalid auth secret: %w", err)
+	}
+
+	curve := ecdh.P256()
+	uaKey, err := curve.NewPublicKey(uaPublic)
+	if err != nil {
+		return nil, fmt.Errorf("push: invalid p256dh key: %w", err)
+	}
+	asKey, err := curve.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	sharedSecret, err := asKey.ECDH(uaKey)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := asKey.PublicKey().Bytes()
+
+	keyInfo := append([]byte("WebPush: info\x00"), uaPublic...)
+	keyInfo = append(keyInfo, asPublic...)
+	ikm, err := hkdfExpand(sharedSecret, authSecret, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	cek, err := hkdfExpand(ikm, salt, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdfExpand(ikm, salt, []byte("Content-Encoding: nonce\x00"), 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	// 0x02 marks the last (and only) record
+	plaintext := append(append([]byte{}, payload...), 0x02)
+
+	var body bytes.Buffer
+	body.Write(salt)
+	binary.Write(&body, binary.BigEndian, uint32(pushRecordSize))
+	body.WriteByte(byte(len(asPublic)))
+	body.Write(asPublic)
+	body.Write(gcm.Seal(nil, nonce, plaintext, nil))
+	return body.Bytes(), nil
+}
+
+func hkdfExpand(secret, salt, info []byte, length int) ([]byte, error) {
+	out := make([]byte, length)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, info), out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// decodeBase64URL decodes base64url with or without padding, which is how
+// browsers and key generators variously write keys.
+func decodeBase64URL(s string) ([]byte, error) {
+	s = strings.TrimRight(strings.TrimSpace(s), "=")
+	s = strings.NewReplacer("+", "-", "/", "_").Replace(s)
+	return base64.RawURLEncoding.DecodeString(s)
+}
+
+// pushNotification is the JSON payload the app's service worker receives.
+type pushNotification struct {
+	Title  string `json:"title"`
+	Body   string `json:"body"`
+	URL    string `json:"url,omitempty"`
+	Type   string `json:"type"`
+	GameID string `json:"gameId,omitempty"`
+}
+
+// pushPayload is the message as the service worker's notification: its
+// subject as the title and its push wording (or the start of its text) as
+// the body.
+func pushPayload(msg Message) string {
+	body := msg.Push
+	if body == "" {
+		body = strings.Join(strings.Fields(msg.Text), " ")
+	}
+	if utf8.RuneCountInString(body) > pushBodyLimit {
+		body = string([]rune(body)[:pushBodyLimit-1]) + "…"
+	}
+
+	notification := pushNotification{Title: msg.Subject, Body: body, URL: msg.URL, Type: msg.Type}
+	if msg.GameID != nil {
+		notification.GameID = msg.GameID.String()
+	}
+	b, _ := json.Marshal(notification)
+	return string(b)
+}
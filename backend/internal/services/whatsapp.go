@@ -3,62 +3,338 @@ package services
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
-const whapiBaseURL = "https://gate.whapi.cloud"
+// WhatsApp providers, chosen with WHATSAPP_PROVIDER.
+const (
+	WhatsAppProviderWhapi   = "whapi"
+	WhatsAppProviderWebhook = "webhook" // POSTs each message to WHATSAPP_WEBHOOK_URL, e.g. a self-hosted gateway
+	WhatsAppProviderFake    = "fake"    // Records messages in memory and logs them instead of sending
+)
+
+const (
+	defaultWhapiBaseURL    = "https://gate.whapi.cloud"
+	defaultWhatsAppTimeout = 15 * time.Second
+)
+
+var (
+	// ErrWhatsAppUnauthorized is returned when the provider rejects the
+	// token. Retrying won't help until the token is fixed.
+	ErrWhatsAppUnauthorized = errors.New("whatsapp: token rejected")
+	// ErrWhatsAppRateLimited is returned when the provider asks us to slow
+	// down. The WhatsAppAPIError's RetryAfter says for how long, if it said.
+	ErrWhatsAppRateLimited = errors.New("whatsapp: rate limited")
+)
+
+// WhatsAppAPIError is a send the provider refused. It matches
+// ErrWhatsAppUnauthorized or ErrWhatsAppRateLimited with errors.Is when the
+// status says so.
+type WhatsAppAPIError struct {
+	Provider   string
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration // From the Retry-After header of a 429; 0 if not given
+}
+
+func (e *WhatsAppAPIError) Error() string {
+	return fmt.Sprintf("whatsapp: %s returned status %d: %s", e.Provider, e.StatusCode, e.Body)
+}
 
-// WhatsAppService sends messages via the Whapi.Cloud REST API.
+func (e *WhatsAppAPIError) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrWhatsAppUnauthorized
+	case http.StatusTooManyRequests:
+		return ErrWhatsAppRateLimited
+	}
+	return nil
+}
+
+// WhatsAppText is one outgoing text message.
+type WhatsAppText struct {
+	To       string // Group chat ID, e.g. "120363xxxxxx@g.us", or phone number, digits only
+	Body     string
+	QuotedID string // Message ID being replied to, if any
+}
+
+// WhatsAppProvider sends text messages with a team's token and returns the
+// provider's message ID.
+type WhatsAppProvider interface {
+	Name() string
+	SendText(token string, msg WhatsAppText) (string, error)
+}
+
+// WhatsAppTokenChecker is implemented by providers that can check a token
+// without sending a message, for testing a team's connection.
+type WhatsAppTokenChecker interface {
+	CheckToken(token string) error
+}
+
+// WhatsAppService sends messages through the configured WhatsAppProvider.
 type WhatsAppService struct {
-	httpClient *http.Client
+	provider WhatsAppProvider
+}
+
+// NewWhatsAppService creates a WhatsAppService with the provider in
+// WHATSAPP_PROVIDER (Whapi by default). WHAPI_BASE_URL points the Whapi
+// provider at another host, and WHATSAPP_TIMEOUT (e.g. "10s") bounds each
+// request.
+func NewWhatsAppService() (*WhatsAppService, error) {
+	timeout := defaultWhatsAppTimeout
+	if value := os.Getenv("WHATSAPP_TIMEOUT"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid WHATSAPP_TIMEOUT %q", value)
+		}
+		timeout = parsed
+	}
+	client := &http.Client{Timeout: timeout}
+
+	var provider WhatsAppProvider
+	switch name := os.Getenv("WHATSAPP_PROVIDER"); name {
+	case "", WhatsAppProviderWhapi:
+		baseURL := os.Getenv("WHAPI_BASE_URL")
+		if baseURL == "" {
+			baseURL = defaultWhapiBaseURL
+		}
+		provider = NewWhapiProvider(baseURL, client)
+	case WhatsAppProviderWebhook:
+		url := os.Getenv("WHATSAPP_WEBHOOK_URL")
+		if url == "" {
+			return nil, fmt.Errorf("WHATSAPP_WEBHOOK_URL environment variable is not set")
+		}
+		provider = NewWebhookWhatsAppProvider(url, client)
+	case WhatsAppProviderFake:
+		provider = FakeWhatsApp
+	default:
+		return nil, fmt.Errorf("unknown WHATSAPP_PROVIDER %q (want %s, %s or %s)",
+			name, WhatsAppProviderWhapi, WhatsAppProviderWebhook, WhatsAppProviderFake)
+	}
+
+	return NewWhatsAppServiceWith(provider), nil
 }
 
-// NewWhatsAppService creates a WhatsAppService.
-func NewWhatsAppService() *WhatsAppService {
-	return &WhatsAppService{
-		httpClient: &http.Client{},
+// NewWhatsAppServiceWith creates a WhatsAppService that sends through the
+// given provider.
+func NewWhatsAppServiceWith(provider WhatsAppProvider) *WhatsAppService {
+	return &WhatsAppService{provider: provider}
+}
+
+// CheckToken asks the provider whether the token works. It returns
+// errors.ErrUnsupported when the provider can't tell without sending.
+func (s *WhatsAppService) CheckToken(token string) error {
+	checker, ok := s.provider.(WhatsAppTokenChecker)
+	if !ok {
+		return fmt.Errorf("whatsapp: %s can't test a token: %w", s.provider.Name(), errors.ErrUnsupported)
 	}
+	return checker.CheckToken(token)
+}
+
+// SendGroupMessage sends a plain-text message to a WhatsApp group using the provided token
+// and returns the provider's message ID. groupID is the chat ID, e.g. "120363xxxxxx@g.us".
+func (s *WhatsAppService) SendGroupMessage(token, groupID, body string) (string, error) {
+	return s.provider.SendText(token, WhatsAppText{To: groupID, Body: body})
+}
+
+// SendGroupReply sends a message to a WhatsApp group as a reply quoting the
+// message with ID quotedID.
+func (s *WhatsAppService) SendGroupReply(token, groupID, quotedID, body string) (string, error) {
+	return s.provider.SendText(token, WhatsAppText{To: groupID, Body: body, QuotedID: quotedID})
 }
 
+// SendDirectMessage sends a plain-text message to one person. phone is the
+// recipient's number in international format, digits only.
+func (s *WhatsAppService) SendDirectMessage(token, phone, body string) (string, error) {
+	return s.provider.SendText(token, WhatsAppText{To: phone, Body: body})
+}
+
+// WhapiProvider sends through the Whapi.Cloud REST API.
+type WhapiProvider struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+func NewWhapiProvider(baseURL string, client *http.Client) *WhapiProvider {
+	return &WhapiProvider{baseURL: strings.TrimSuffix(baseURL, "/"), httpClient: client}
+}
+
+func (p *WhapiProvider) Name() string { return WhatsAppProviderWhapi }
+
 // sendTextMessageRequest is the Whapi POST /messages/text body.
 type sendTextMessageRequest struct {
-	To   string `json:"to"`
-	Body string `json:"body"`
+	To     string `json:"to"`
+	Body   string `json:"body"`
+	Quoted string `json:"quoted,omitempty"` // Message ID being replied to
 }
 
-// SendGroupMessage sends a plain-text message to a WhatsApp group using the provided token.
-// groupID is the Whapi chat ID, e.g. "120363xxxxxx@g.us".
-func (s *WhatsAppService) SendGroupMessage(token, groupID, body string) error {
-	payload := sendTextMessageRequest{
-		To:   groupID,
-		Body: body,
+// sendTextMessageResponse is the part of the Whapi reply we keep.
+type sendTextMessageResponse struct {
+	Message struct {
+		ID string `json:"id"`
+	} `json:"message"`
+}
+
+func (p *WhapiProvider) SendText(token string, msg WhatsAppText) (string, error) {
+	payload := sendTextMessageRequest{To: msg.To, Body: msg.Body, Quoted: msg.QuotedID}
+
+	var sent sendTextMessageResponse
+	if err := postWhatsApp(p.httpClient, p.Name(), p.baseURL+"/messages/text", token, payload, &sent); err != nil {
+		return "", err
 	}
+	return sent.Message.ID, nil
+}
 
+// CheckToken calls Whapi's channel health check, which needs a valid token.
+func (p *WhapiProvider) CheckToken(token string) error {
+	req, err := http.NewRequest(http.MethodGet, p.baseURL+"/health", nil)
+	if err != nil {
+		return fmt.Errorf("whatsapp: failed to build request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("whatsapp: HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return whatsAppAPIError(p.Name(), resp)
+	}
+	return nil
+}
+
+// WebhookWhatsAppProvider sends by POSTing each message as JSON
+// ({"to", "body", "quoted"}) to a URL, with the team's token as a bearer
+// token. The reply may carry the message ID as {"id": "..."}.
+type WebhookWhatsAppProvider struct {
+	url        string
+	httpClient *http.Client
+}
+
+func NewWebhookWhatsAppProvider(url string, client *http.Client) *WebhookWhatsAppProvider {
+	return &WebhookWhatsAppProvider{url: url, httpClient: client}
+}
+
+func (p *WebhookWhatsAppProvider) Name() string { return WhatsAppProviderWebhook }
+
+func (p *WebhookWhatsAppProvider) SendText(token string, msg WhatsAppText) (string, error) {
+	payload := sendTextMessageRequest{To: msg.To, Body: msg.Body, Quoted: msg.QuotedID}
+
+	var sent struct {
+		ID string `json:"id"`
+	}
+	if err := postWhatsApp(p.httpClient, p.Name(), p.url, token, payload, &sent); err != nil {
+		return "", err
+	}
+	return sent.ID, nil
+}
+
+// postWhatsApp POSTs payload as JSON and decodes the reply into out. Refusals
+// are returned as a *WhatsAppAPIError.
+func postWhatsApp(client *http.Client, provider, url, token string, payload, out interface{}) error {
 	b, err := json.Marshal(payload)
 	if err != nil {
 		return fmt.Errorf("whatsapp: failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequest(http.MethodPost, whapiBaseURL+"/messages/text", bytes.NewReader(b))
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(b))
 	if err != nil {
 		return fmt.Errorf("whatsapp: failed to build request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+token)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
 
-	resp, err := s.httpClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("whatsapp: HTTP request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("whatsapp: API returned status %d: %s", resp.StatusCode, string(body))
+		return whatsAppAPIError(provider, resp)
 	}
 
+	// The message was sent even if the reply can't be read, so only the ID is lost
+	json.NewDecoder(resp.Body).Decode(out)
 	return nil
 }
+
+// whatsAppAPIError reads a refusal from the provider's response.
+func whatsAppAPIError(provider string, resp *http.Response) *WhatsAppAPIError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	apiErr := &WhatsAppAPIError{Provider: provider, StatusCode: resp.StatusCode, Body: string(body)}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return apiErr
+}
+
+// FakeWhatsApp is the provider used when WHATSAPP_PROVIDER is "fake". It is
+// shared so every WhatsAppService records into the same log.
+var FakeWhatsApp = &RecordingWhatsAppProvider{}
+
+// RecordedWhatsApp is a message a RecordingWhatsAppProvider was asked to send.
+type RecordedWhatsApp struct {
+	ID    string
+	Token string
+	WhatsAppText
+	SentAt time.Time
+}
+
+// RecordingWhatsAppProvider keeps every message in memory and logs it instead
+// of sending it. Err, when set, is returned by every send.
+type RecordingWhatsAppProvider struct {
+	mu   sync.Mutex
+	sent []RecordedWhatsApp
+	Err  error
+}
+
+func (p *RecordingWhatsAppProvider) Name() string { return WhatsAppProviderFake }
+
+func (p *RecordingWhatsAppProvider) SendText(token string, msg WhatsAppText) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.Err != nil {
+		return "", p.Err
+	}
+
+	id := fmt.Sprintf("fake-%d", len(p.sent)+1)
+	p.sent = append(p.sent, RecordedWhatsApp{ID: id, Token: token, WhatsAppText: msg, SentAt: time.Now()})
+	log.Printf("WhatsApp Fake: %s to %s:\n%s", id, msg.To, msg.Body)
+	return id, nil
+}
+
+// CheckToken accepts any token unless Err is set.
+func (p *RecordingWhatsAppProvider) CheckToken(token string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.Err
+}
+
+// Sent returns a copy of the messages recorded so far.
+func (p *RecordingWhatsAppProvider) Sent() []RecordedWhatsApp {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]RecordedWhatsApp(nil), p.sent...)
+}
+
+// Reset forgets the recorded messages.
+func (p *RecordingWhatsAppProvider) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sent = nil
+}
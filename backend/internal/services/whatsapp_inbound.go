@@ -0,0 +1,198 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/liam/screaming-toller/backend/internal/database"
+	"github.com/liam/screaming-toller/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// WhapiWebhook is the body Whapi posts for message events.
+type WhapiWebhook struct {
+	Messages []WhapiMessage `json:"messages"`
+}
+
+// WhapiMessage is one incoming message. Only text messages are read.
+type WhapiMessage struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	ChatID   string `json:"chat_id"` // "120363xxxxxx@g.us" for groups
+	From     string `json:"from"`    // Sender's phone number, digits only
+	FromName string `json:"from_name"`
+	FromMe   bool   `json:"from_me"`
+	Text     struct {
+		Body string `json:"body"`
+	} `json:"text"`
+}
+
+// rsvpReplies maps the keywords players send in the group to an attendance
+// status. Everyday replies like "yes" or 👍 are left alone, since they are
+// often answers to something else; anything else is ordinary chat and ignored.
+var rsvpReplies = map[string]string{
+	"in":    "going",
+	"out":   "not_going",
+	"maybe": "maybe",
+}
+
+// ParseRSVPReply interprets a group message like "In!", "out" or "maybe".
+// ok is false when the message isn't an RSVP.
+func ParseRSVPReply(text string) (status string, ok bool) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	text = strings.TrimRightFunc(text, func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSpace(r)
+	})
+	status, ok = rsvpReplies[text]
+	return status, ok
+}
+
+// NormalizePhone strips everything but digits. Ten-digit numbers are taken
+// to be North American and get the "1" country code, matching how WhatsApp
+// reports senders.
+func NormalizePhone(phone string) string {
+	var b strings.Builder
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	digits := b.String()
+	if len(digits) == 10 {
+		digits = "1" + digits
+	}
+	return digits
+}
+
+// HandleInboundMessage records an RSVP sent as a reply in a team's WhatsApp
+// group. The sender is matched by phone number to a member of the team that
+// owns the group, the answer is applied to the team's next upcoming game, and
+// the bot confirms by replying to the message.
+func (s *WhatsAppService) HandleInboundMessage(msg WhapiMessage) {
+	if msg.FromMe || msg.Type != "text" || !strings.HasSuffix(msg.ChatID, "@g.us") {
+		return
+	}
+	status, ok := ParseRSVPReply(msg.Text.Body)
+	if !ok {
+		return
+	}
+
+	var teams []models.Team
+	if err := database.DB.Where("whats_app_group_id = ?", msg.ChatID).Find(&teams).Error; err != nil {
+		log.Printf("WhatsApp Inbound Error: Failed to look up group %s: %v", msg.ChatID, err)
+		return
+	}
+	if len(teams) == 0 {
+		return
+	}
+
+	var user models.User
+	phone := NormalizePhone(msg.From)
+	// Only a verified number identifies the sender; anyone can type in a number
+	if phone == "" || database.DB.Where("phone = ? AND phone_verified_at IS NOT NULL", phone).First(&user).Error != nil {
+		s.reply(teams[0], msg, rsvpReply(teams[0], "unknown_number", map[string]interface{}{"Name": msg.FromName}))
+		return
+	}
+
+	// A group may be shared by several teams; use the one the sender plays on
+	for _, team := range teams {
+		var member models.TeamMember
+		if err := database.DB.Where("team_id = ? AND user_id = ? AND is_active = ?", team.ID, user.ID, true).First(&member).Error; err != nil {
+			continue
+		}
+		s.reply(team, msg, recordGroupRSVP(team, member, user, status))
+		return
+	}
+	s.reply(teams[0], msg, rsvpReply(teams[0], "not_on_roster", map[string]interface{}{"Name": user.Name}))
+}
+
+// recordGroupRSVP applies the answer to the team's next game and returns the
+// confirmation to send back.
+func recordGroupRSVP(team models.Team, member models.TeamMember, user models.User, status string) string {
+	game, start, err := nextTeamGame(team.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return rsvpReply(team, "no_games", map[string]interface{}{"Name": user.Name})
+	}
+	if err != nil {
+		log.Printf("WhatsApp Inbound Error: Failed to find next game for team %s: %v", team.Name, err)
+		return rsvpReply(team, "failed", map[string]interface{}{})
+	}
+
+	var attendance models.Attendance
+	found := database.DB.Where("team_member_id = ? AND game_id = ?", member.ID, game.ID).First(&attendance).Error == nil
+	if (!found || attendance.Status != status) && RSVPLocked(game, team, time.Now()) {
+		return rsvpReply(team, "deadline_passed", map[string]interface{}{"Name": user.Name, "Opponent": game.OpposingTeam})
+	}
+
+	if found {
+		err = database.DB.Model(&attendance).Updates(map[string]interface{}{
+			"status":     status,
+			"updated_at": time.Now(),
+		}).Error
+	} else {
+		err = database.DB.Create(&models.Attendance{
+			TeamMemberID: member.ID,
+			GameID:       game.ID,
+			Status:       status,
+			UpdatedAt:    time.Now(),
+		}).Error
+	}
+	if err != nil {
+		log.Printf("WhatsApp Inbound Error: Failed to record RSVP for %s: %v", user.Email, err)
+		return rsvpReply(team, "failed", map[string]interface{}{})
+	}
+
+	locale := TeamMessageContext(team).Locale
+	return rsvpReply(team, "recorded", map[string]interface{}{
+		"Name":     user.Name,
+		"Status":   status,
+		"Opponent": game.OpposingTeam,
+		"Date":     formatDay(start, locale),
+		"Time":     formatClock(start, locale),
+	})
+}
+
+// rsvpReply words the bot's answer to a group RSVP, in the team's language.
+// event is what happened: "recorded", "unknown_number", "not_on_roster",
+// "no_games", "deadline_passed" or "failed".
+func rsvpReply(team models.Team, event string, data map[string]interface{}) string {
+	data["Event"] = event
+	return renderWhatsApp(TeamMessageContext(team), "rsvp_reply", data)
+}
+
+// nextTeamGame returns the team's next game that hasn't started yet.
+func nextTeamGame(teamID uuid.UUID) (models.Game, time.Time, error) {
+	var games []models.Game
+	if err := database.DB.Preload("Venue").
+		Where("team_id = ? AND date >= ? AND status NOT IN ?", teamID, time.Now().AddDate(0, 0, -1), []string{"cancelled", "postponed", "completed"}).
+		Order("date asc, time asc").
+		Find(&games).Error; err != nil {
+		return models.Game{}, time.Time{}, err
+	}
+
+	now := time.Now()
+	for _, game := range games {
+		start, err := GameStartTime(game, defaultLocation())
+		if err == nil && start.After(now) {
+			return game, start, nil
+		}
+	}
+	return models.Game{}, time.Time{}, gorm.ErrRecordNotFound
+}
+
+func (s *WhatsAppService) reply(team models.Team, msg WhapiMessage, body string) {
+	token, err := teamWhapiToken(team)
+	if err != nil {
+		log.Printf("WhatsApp Inbound: Can't reply in team %s's group: %v", team.Name, err)
+		return
+	}
+	_, err = s.SendGroupReply(token, msg.ChatID, msg.ID, body)
+	recordWhatsAppResult(team.ID, err)
+	if err != nil {
+		log.Printf("WhatsApp Inbound Error: Failed to reply to message %s: %v", msg.ID, err)
+	}
+}
@@ -3,7 +3,9 @@ package utils
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
 	"errors"
 	"fmt"
@@ -94,3 +96,35 @@ func Decrypt(ciphertextStr string) (string, error) {
 
 	return string(plaintext), nil
 }
+
+// signingKey derives a separate HMAC key from the master key so signatures
+// never reuse the encryption key directly.
+func signingKey() ([]byte, error) {
+	key, err := getEncryptionKey()
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("screaming-toller/signing"))
+	return mac.Sum(nil), nil
+}
+
+// Sign returns a URL-safe HMAC-SHA256 signature of message.
+func Sign(message string) (string, error) {
+	key, err := signingKey()
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(message))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// VerifySignature reports whether signature is a valid signature of message.
+func VerifySignature(message, signature string) bool {
+	expected, err := Sign(message)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
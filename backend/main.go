@@ -1,11 +1,16 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -35,21 +40,34 @@ func main() {
 	// Initialize Auth0 JWKS Validator
 	auth.InitAuth0()
 
-	// Initialize Email and Reminder Services
-	emailService, err := services.NewEmailService()
+	// Background workers stop on SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	var workers sync.WaitGroup
+
+	// Initialize Notification and Reminder Services
+	// Built once and shared by the handlers and every worker
+	notifications := services.NewNotificationService()
+	defer notifications.Close()
+	handlers.SetNotificationService(notifications)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		notifications.RunOutbox(ctx)
+	}()
+	if !notifications.HasChannel(services.ChannelEmail) {
+		log.Printf("Warning: Email channel not configured, set EMAIL_TRANSPORT or RESEND_API_KEY")
+	}
+	reminderService, err := services.NewReminderService(notifications)
 	if err != nil {
-		log.Printf("Warning: Email service not initialized: %v", err)
+		log.Printf("Warning: Reminder service not initialized: %v", err)
 	} else {
-		// WhatsApp service is optional — nil if WHAPI_TOKEN is not set
-		whatsAppService := services.NewWhatsAppService()
-
-		reminderService, err := services.NewReminderService(emailService, whatsAppService)
-		if err != nil {
-			log.Printf("Warning: Reminder service not initialized: %v", err)
-		} else {
-			reminderService.StartScheduler()
-			log.Println("ReminderService started")
-		}
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			reminderService.Run(ctx)
+		}()
+		log.Println("ReminderService started")
 	}
 
 	r := chi.NewRouter()
@@ -73,9 +91,21 @@ func main() {
 		MaxAge:           300,
 	}))
 
-	// Public Routes (None currently needed, but keeping group for future)
+	// Public Routes (no login; the link token is the credential)
 	r.Group(func(r chi.Router) {
-		// e.g. webhooks
+		r.Use(middleware.RateLimitMiddleware(middleware.NewIPRateLimiter(1, 10)))
+		r.Get("/api/spare-invites/{token}", handlers.GetSpareInvite)
+		r.Post("/api/spare-invites/{token}/accept", handlers.AcceptSpareInvite)
+		r.Post("/api/spare-invites/{token}/decline", handlers.DeclineSpareInvite)
+		r.Get("/api/rsvp/{token}", handlers.ShowRSVPLink)
+		r.Post("/api/rsvp/{token}", handlers.RespondToRSVPLink)
+	})
+
+	// Provider webhooks, authenticated by their signature. They aren't rate
+	// limited per IP, since providers deliver bursts from a few shared addresses.
+	r.Group(func(r chi.Router) {
+		r.Post("/api/webhooks/whapi", handlers.WhapiWebhook)
+		r.Post("/api/webhooks/sms", handlers.SMSWebhook)
 	})
 
 	// Protected Routes
@@ -84,6 +114,16 @@ func main() {
 		r.Post("/api/auth/sync", handlers.SyncUser) // Auto-provisions or syncs local DB user from Auth0 Token
 		r.Get("/api/auth/me", handlers.GetMe)
 		r.Put("/api/auth/me", handlers.UpdateMe)
+		r.Post("/api/auth/me/phone/verification", handlers.SendPhoneVerification)
+		r.Post("/api/auth/me/phone/verification/confirm", handlers.ConfirmPhoneVerification)
+		r.Get("/api/auth/me/notifications", handlers.GetMyNotificationPreferences)
+		r.Put("/api/auth/me/notifications", handlers.UpdateMyNotificationPreferences)
+		r.Get("/api/auth/me/digest", handlers.GetMyDigest)
+		r.Put("/api/auth/me/digest", handlers.UpdateMyDigest)
+		r.Get("/api/auth/me/push/key", handlers.GetPushKey)
+		r.Get("/api/auth/me/push-subscriptions", handlers.GetMyPushSubscriptions)
+		r.Post("/api/auth/me/push-subscriptions", handlers.CreateMyPushSubscription)
+		r.Delete("/api/auth/me/push-subscriptions/{subscriptionID}", handlers.DeleteMyPushSubscription)
 		r.Post("/api/teams", handlers.CreateTeam)
 		r.Get("/api/teams", handlers.GetTeams)
 
@@ -95,6 +135,38 @@ func main() {
 			r.Post("/teams/{teamID}/reject", handlers.RejectTeam)
 		})
 
+		// Leagues
+		r.Post("/api/leagues", handlers.CreateLeague)
+		r.Get("/api/leagues", handlers.GetLeagues)
+		r.Route("/api/leagues/{leagueID}", func(r chi.Router) {
+			r.Use(middleware.RequireLeagueAccess)
+			r.Get("/", handlers.GetLeague)
+			r.Get("/standings", handlers.GetLeagueStandings)
+			r.Get("/venues", handlers.GetLeagueVenues)
+			r.Get("/tournaments", handlers.GetLeagueTournaments)
+			r.Get("/tournaments/{tournamentID}", handlers.GetTournament)
+
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.RequireLeagueAdmin)
+				r.Put("/", handlers.UpdateLeague)
+				r.Post("/teams", handlers.AddLeagueTeam)
+				r.Delete("/teams/{teamID}", handlers.RemoveLeagueTeam)
+				r.Post("/admins", handlers.AddLeagueAdmin)
+				r.Delete("/admins/{userID}", handlers.RemoveLeagueAdmin)
+				r.Post("/schedule", handlers.GenerateLeagueSchedule)
+				r.Post("/venues", handlers.CreateLeagueVenue)
+				r.Put("/venues/{venueID}", handlers.UpdateLeagueVenue)
+				r.Delete("/venues/{venueID}", handlers.DeleteLeagueVenue)
+				r.Get("/spares", handlers.GetLeagueSpares)
+				r.Post("/spares", handlers.CreateLeagueSpare)
+				r.Put("/spares/{spareID}", handlers.UpdateLeagueSpare)
+				r.Delete("/spares/{spareID}", handlers.DeleteLeagueSpare)
+				r.Post("/tournaments", handlers.CreateTournament)
+				r.Post("/tournaments/{tournamentID}/bracket", handlers.GenerateTournamentBracket)
+				r.Put("/tournaments/{tournamentID}/games/{tournamentGameID}", handlers.UpdateTournamentGame)
+			})
+		})
+
 		// Invitations
 		r.Get("/api/invitations/{token}", handlers.GetInvitation)
 		r.Post("/api/invitations/{token}/accept", handlers.AcceptInvitation)
@@ -106,6 +178,7 @@ func main() {
 			r.Get("/games", handlers.GetTeamGames)
 			r.Get("/games/{gameID}", handlers.GetGame)
 			r.Get("/members", handlers.GetTeamMembers)
+			r.Get("/venues", handlers.GetTeamVenues)
 
 			// Player preference routes
 			r.Get("/members/me/preferences", handlers.GetMyPreferences)
@@ -113,12 +186,16 @@ func main() {
 			r.Get("/members/me", handlers.GetMyTeamMemberInfo)
 			r.Put("/members/me/pitcher", handlers.UpdateMyPitcherStatus)
 			r.Put("/members/me/gender", handlers.UpdateMyGender)
+			r.Get("/members/me/blackouts", handlers.GetMyBlackouts)
+			r.Post("/members/me/blackouts", handlers.CreateMyBlackout)
+			r.Delete("/members/me/blackouts/{blackoutID}", handlers.DeleteMyBlackout)
 
 			// Game-specific routes
 			r.Get("/games/{gameID}/attendance", handlers.GetAttendance)
 			r.Put("/games/{gameID}/attendance", handlers.UpdateAttendance)
 			r.Get("/games/{gameID}/batting-order", handlers.GetBattingOrder)
 			r.Get("/games/{gameID}/fielding", handlers.GetFieldingLineup)
+			r.Get("/games/{gameID}/forfeit-risk", handlers.GetForfeitRisk)
 
 			// Admin-only routes
 			r.Group(func(r chi.Router) {
@@ -127,6 +204,31 @@ func main() {
 				r.Post("/games", handlers.CreateGame)
 				r.Put("/games/{gameID}", handlers.UpdateGame)
 				r.Delete("/games/{gameID}", handlers.DeleteGame)
+				r.Post("/games/{gameID}/reschedule", handlers.RescheduleGame)
+				r.Post("/venues", handlers.CreateVenue)
+				r.Put("/venues/{venueID}", handlers.UpdateVenue)
+				r.Delete("/venues/{venueID}", handlers.DeleteVenue)
+				r.Get("/reminder-rules", handlers.GetReminderRules)
+				r.Post("/reminder-rules", handlers.CreateReminderRule)
+				r.Put("/reminder-rules/{ruleID}", handlers.UpdateReminderRule)
+				r.Delete("/reminder-rules/{ruleID}", handlers.DeleteReminderRule)
+				r.Get("/message-templates", handlers.GetMessageTemplates)
+				r.Get("/message-templates/defaults", handlers.GetDefaultMessageTemplates)
+				r.Put("/message-templates", handlers.SaveMessageTemplate)
+				r.Delete("/message-templates/{templateID}", handlers.DeleteMessageTemplate)
+				r.Get("/league-invitations", handlers.GetTeamLeagueInvitations)
+				r.Post("/league-invitations/{leagueID}/accept", handlers.AcceptLeagueInvitation)
+				r.Delete("/league-invitations/{leagueID}", handlers.DeclineLeagueInvitation)
+				r.Get("/integrations/whatsapp", handlers.GetWhatsAppIntegration)
+				r.Put("/integrations/whatsapp", handlers.UpdateWhatsAppIntegration)
+				r.Post("/integrations/whatsapp/test", handlers.TestWhatsAppIntegration)
+				r.Get("/spares", handlers.GetTeamSpares)
+				r.Post("/spares", handlers.CreateSpare)
+				r.Put("/spares/{spareID}", handlers.UpdateSpare)
+				r.Delete("/spares/{spareID}", handlers.DeleteSpare)
+				r.Get("/games/{gameID}/spare-requests", handlers.GetSpareRequests)
+				r.Post("/games/{gameID}/spare-requests", handlers.CreateSpareRequest)
+				r.Delete("/games/{gameID}/spare-requests/{requestID}", handlers.CancelSpareRequest)
 				r.Put("/games/{gameID}/score", handlers.UpdateGameScore)
 				r.Put("/games/{gameID}/innings", handlers.UpdateInningScores)
 				r.Put("/games/{gameID}/attendance/admin", handlers.AdminUpdateAttendance)
@@ -141,10 +243,15 @@ func main() {
 				r.Post("/games/{gameID}/fielding/generate-complete", handlers.GenerateCompleteFieldingLineup)
 				r.Put("/games/{gameID}/fielding", handlers.UpdateFieldingLineup)
 				r.Delete("/games/{gameID}/fielding", handlers.DeleteFieldingLineup)
+				r.Post("/games/{gameID}/lineup/publish", handlers.PublishLineup)
+				r.Delete("/games/{gameID}/lineup/publish", handlers.UnpublishLineup)
+				r.Get("/games/{gameID}/notifications", handlers.GetGameNotifications)
+				r.Post("/games/{gameID}/notifications/{notificationID}/retry", handlers.RetryGameNotification)
 				
 				r.Post("/invitations", handlers.InviteMember)
 				r.Delete("/members/{memberID}", handlers.RemoveMember)
 				r.Get("/members/preferences", handlers.GetAllTeamMemberPreferences)
+				r.Get("/members/blackouts", handlers.GetTeamBlackouts)
 				r.Put("/members/{memberID}/preferences", handlers.UpdateMemberPreferences)
 				r.Put("/members/{memberID}/pitcher", handlers.UpdateMemberPitcherStatus)
 
@@ -169,8 +276,23 @@ func main() {
 		w.Write([]byte("Screaming Toller API"))
 	})
 
-	log.Println("Server starting on :8080...")
-	if err := http.ListenAndServe(":8080", r); err != nil {
-		log.Fatal(err)
+	server := &http.Server{Addr: ":8080", Handler: r}
+	go func() {
+		log.Println("Server starting on :8080...")
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Println("Shutting down...")
+
+	// Let in-flight requests and the job being run finish
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Warning: Server shutdown: %v", err)
 	}
+	workers.Wait()
+	log.Println("Shutdown complete")
 }